@@ -5,43 +5,51 @@ import (
 	"recap/internal/app"
 	"recap/internal/db"
 	"recap/internal/llm"
+	"recap/internal/models"
 	"recap/internal/schedule"
 )
 
 //go:embed all:frontend/build
 var assets embed.FS
 
-func addBindings() *app.AppMethods {
+func addBindings(store db.Store) *app.AppMethods {
 	methods := app.NewAppMethods()
 	methods.CCheckTimers = schedule.AreTimersRunning
 	methods.CSetLLMTimer = schedule.SetLLMScheduleState
 	methods.CSetScrTimer = schedule.SetScrScheduleState
 
-	methods.CGetScreenshots = db.GetScreenshots
-	methods.CGetScreenshotById = db.GetScreenshotById
-	methods.CGetScreenshotsNewerThan = db.GetScreenshotsNewerThan
-	methods.CGetScreenshotsOlderThan = db.GetScreenshotsOlderThan
-	methods.CDeleteScreenshotsById = db.DeleteScreenshotsById
+	methods.CGetScreenshots = store.GetScreenshots
+	methods.CGetScreenshotById = store.GetScreenshotById
+	methods.CGetScreenshotsNewerThan = store.GetScreenshotsNewerThan
+	methods.CGetScreenshotsOlderThan = store.GetScreenshotsOlderThan
+	methods.CGetScreenshotsForReport = store.GetScreenshotsForReport
+	methods.CDeleteScreenshotsById = store.DeleteScreenshotsById
 
 	methods.CGenerateReportWithSelectScr = llm.GenerateReportWithSelectScr
-	methods.CGetReports = db.GetReports
-	methods.CGetReportById = db.GetReportById
-	methods.CGetReportsNewerThan = db.GetReportsNewerThan
-	methods.CGetReportsOlderThan = db.GetReportsOlderThan
-	methods.CDeleteReportsById = db.DeleteReportsById
-
-	methods.CGetConfig = db.LoadConfig
+	methods.CGetReports = store.GetReports
+	methods.CGetReportById = store.GetReportById
+	methods.CGetReportsNewerThan = store.GetReportsNewerThan
+	methods.CGetReportsOlderThan = store.GetReportsOlderThan
+	methods.CGetReportsForScreenshot = store.GetReportsForScreenshot
+	methods.CDeleteReportsById = store.DeleteReportsById
+	methods.CAskHistory = llm.AskHistory
+	methods.CSemanticSearch = llm.SemanticSearch
+	methods.CSearch = store.Search
+	methods.CTestProvider = llm.TestProvider
+	methods.CListModels = models.ListAPIModels
+
+	methods.CGetConfig = store.GetMaskedConfig
 	methods.CGetDisplayValues = db.GetDisplayValues
-	methods.CUpdateSettings = db.UpdateSettings
+	methods.CUpdateSettings = store.UpdateSettings
 
-	methods.CUpdateInfo = db.UpdateInfo
-	methods.CWriteInfo = db.WriteInfo
-	methods.CReadInfo = db.ReadInfo
-	methods.CReadAllInfo = db.ReadAllInfo
+	methods.CUpdateInfo = store.UpdateInfo
+	methods.CWriteInfo = store.WriteInfo
+	methods.CReadInfo = store.ReadInfo
+	methods.CReadAllInfo = store.ReadAllInfo
 	return methods
 }
 
-func createApp() {
-	methods := addBindings()
+func createApp(store db.Store) {
+	methods := addBindings(store)
 	app.LaunchAppInstance(assets, methods, &iconBytes)
 }
@@ -1,5 +1,11 @@
 import "@fontsource/inter"
+import { loadDaySettings } from "../utils/timeSince.ts"
 
 export const prerender = true
 export const ssr = false
-export const trailingSlash = 'always'
\ No newline at end of file
+export const trailingSlash = 'always'
+
+// Dates are grouped by day in the configured time zone, so it has to be known before pages load
+export const load = async () => {
+    await loadDaySettings()
+}
@@ -5,6 +5,8 @@ import { getReportById } from "../../../utils/report.ts";
 import { reportStore } from "$lib/stores/ReportStore.ts";
 import { ConvertToHtmlTree } from "$lib/markdown/Markdown.ts";
 import type { MdNode } from "$lib/markdown/Markdown.interface.ts";
+import type { ExtendedScreenshot } from "../../../types/ExtendedScreenshot.interface.ts";
+import { getScreenshotsForReport } from "../../../utils/screenshot.ts";
 
 // Pulls report from database
 async function pullFromDb(id: number): Promise<ExtendedReport | null> {
@@ -17,6 +19,16 @@ async function pullFromDb(id: number): Promise<ExtendedReport | null> {
   }
 }
 
+// Pulls the screenshots the report was generated from
+async function pullScreenshotsFromDb(id: number): Promise<ExtendedScreenshot[]> {
+  try {
+    return await getScreenshotsForReport(id);
+  } catch (err) {
+    console.error(`Failed to fetch screenshots of report with ID ${id}`, err);
+    return [];
+  }
+}
+
 // Tries to find the report in the store
 function pullFromStore(id: number, store: ExtendedReport[]): ExtendedReport | null {
   return store?.find((r) => r.ReportID === id) || null;
@@ -50,7 +62,8 @@ export const load = async ({ params }) => {
           } 
           resolve(result);
         }
-      })
+      }),
+      screenshots: pullScreenshotsFromDb(scrId),
     }
   };
 };
@@ -4,6 +4,8 @@ import { screenshotStore } from "$lib/stores/ScreenshotStore.ts";
 import { GetScreenshotById } from "$lib/wailsjs/go/app/AppMethods.js";
 import type { ExtendedScreenshot } from "../../../types/ExtendedScreenshot.interface.ts";
 import { getScreenshotById } from "../../../utils/screenshot.ts";
+import type { ExtendedReport } from "../../../types/ExtendedReport.interface.ts";
+import { getReportsForScreenshot } from "../../../utils/report.ts";
 
 // Pulls screenshot from database
 async function pullFromDb(id: number): Promise<ExtendedScreenshot | null> {
@@ -16,6 +18,16 @@ async function pullFromDb(id: number): Promise<ExtendedScreenshot | null> {
   }
 }
 
+// Pulls the reports the screenshot was included in
+async function pullReportsFromDb(id: number): Promise<ExtendedReport[]> {
+  try {
+    return await getReportsForScreenshot(id);
+  } catch (err) {
+    console.error(`Failed to fetch reports of screenshot with ID ${id}`, err);
+    return [];
+  }
+}
+
 // Tries to find the screenshot in the store
 function pullFromStore(id: number, store: ExtendedScreenshot[]): ExtendedScreenshot | null {
   return store?.find((s) => s.CaptureID === id) || null;
@@ -40,7 +52,8 @@ export const load = async ({ params }) => {
         } else {
           resolve(result); // Pass the screenshot result
         }
-      })
+      }),
+      reports: pullReportsFromDb(scrId),
     }
   };
 };
@@ -36,10 +36,6 @@ export const load = async () => {
 
         const result = joinDisplaySettings(basicSettings, displayVals!);
 
-        //! Temporarily remove these keys. Automatic reporting is not implemented yet.
-        delete result["Reports"]["ReportAutoEnabled"]
-        delete result["Reports"]["ReportAutoAt"]
-
         // Handle cases where result is not found
         if (!result) {
           reject(0);
@@ -0,0 +1,44 @@
+import { TestProvider } from "$lib/wailsjs/go/app/AppMethods.js";
+import type { models } from "$lib/wailsjs/go/models.ts";
+import { addNewDialog } from "./dialog.ts";
+
+// Model settings that get a "Test connection" button, and the API setting each one is used with
+export const testableModelSettings: { [modelKey: string]: string } = {
+  DescGenModel: "DescGenAPI",
+  ReportModel: "ReportAPI",
+};
+
+const statusLabels: { [status: string]: string } = {
+  passed: "✓",
+  failed: "✗",
+  skipped: "–",
+  unknown: "?",
+};
+
+const describeResult = (result: models.HealthCheckResult): string => {
+  const lines = (result.Checks ?? []).map((check) => `${statusLabels[check.Status] ?? check.Status} ${check.Message}`);
+  lines.push(`Took ${result.LatencyMs} ms`);
+  return lines.join("\n");
+}
+
+/**
+ * Checks the API and model selected in the model setting modelKey, with the unsaved settings in pending applied
+ * for the check only, and shows the result in a dialog. Masked API keys in pending keep their saved value
+ */
+export const testConnection = async (modelKey: string, pending: { [key: string]: string }) => {
+  const api = pending[testableModelSettings[modelKey]];
+  const model = pending[modelKey];
+
+  try {
+    const result = await TestProvider(api, model, pending);
+    addNewDialog({
+      title: result.Healthy ? `${api} is working` : `${api} isn't working`,
+      description: describeResult(result),
+    });
+  } catch (error: any) {
+    addNewDialog({
+      title: "Error",
+      description: `Could not test the connection. The following error was received: ${error}`,
+    });
+  }
+}
@@ -1,12 +1,13 @@
 import { reportStore } from "$lib/stores/ReportStore.ts";
-import { GetReports, GetReportById, GetReportsNewerThan, GetReportsOlderThan } from "$lib/wailsjs/go/app/AppMethods.js";
+import { GetReports, GetReportById, GetReportsNewerThan, GetReportsOlderThan, GetReportsForScreenshot } from "$lib/wailsjs/go/app/AppMethods.js";
 import type { db } from "$lib/wailsjs/go/models.ts";
 import type { ExtendedReport } from "../types/ExtendedReport.interface.ts";
+import { formatTime } from "./timeSince.ts";
 
 const processReports = (reports: db.Report[] | null): ExtendedReport[] => {
     if (!reports) return []; // Return an empty array if reports is null
     return reports.map<ExtendedReport>((v: any) => {
-        v.Time = new Date(v.Timestamp * 1000).toLocaleTimeString();
+        v.Time = formatTime(v.Timestamp);
         return v;
     });
 };
@@ -54,3 +55,9 @@ export const addReportToStore = async (
 
     return newReport;
 };
+
+// Reports the screenshot with the given capture ID was included in, newest first
+export const getReportsForScreenshot = async (captureId: number) => {
+    const res = await GetReportsForScreenshot(captureId);
+    return processReports(res);
+}
@@ -1,7 +1,8 @@
 import { screenshotStore } from "$lib/stores/ScreenshotStore.ts";
-import { GetScreenshotById, GetScreenshots, GetScreenshotsOlderThan, GetScreenshotsNewerThan } from "$lib/wailsjs/go/app/AppMethods.js";
+import { GetScreenshotById, GetScreenshots, GetScreenshotsOlderThan, GetScreenshotsNewerThan, GetScreenshotsForReport } from "$lib/wailsjs/go/app/AppMethods.js";
 import type { ExtendedScreenshot } from "../types/ExtendedScreenshot.interface.ts";
 import { db } from "$lib/wailsjs/go/models.ts";
+import { formatTime } from "./timeSince.ts";
 
 const processScreenshots = (
     screenshots: db.CaptureScreenshotImage[] | null
@@ -9,7 +10,7 @@ const processScreenshots = (
     if (!screenshots) return [];
     const cast = screenshots.map<ExtendedScreenshot>(
         (v: any) => {
-            v.Time = new Date(v.Timestamp * 1000).toLocaleTimeString();
+            v.Time = formatTime(v.Timestamp);
             return v;
         }
     );
@@ -53,3 +54,9 @@ export const addScreenshotToStore = async (id: number): Promise<ExtendedScreensh
 
     return newScreenshot;
 }
+
+// Screenshots the report with the given ID was generated from, oldest first
+export const getScreenshotsForReport = async (reportId: number) => {
+    const res = await GetScreenshotsForReport(reportId);
+    return processScreenshots(res);
+}
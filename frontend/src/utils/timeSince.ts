@@ -1,3 +1,5 @@
+import { GetConfig } from "$lib/wailsjs/go/app/AppMethods.js";
+
 export function timeSinceUNIXSeconds(date: number) {
     const seconds = Math.floor((Date.now() - date * 1000) / 1000); // date * 1000 converts a UNIX second timestamp to millis
     let interval = seconds / 31536000;
@@ -28,9 +30,60 @@ function formatTimeAgoText(since: number, text: string) {
     return since + ` ${text}${since !== 1 ? "s" : ""} ago`;
 }
 
+// Time zone and start of day used to work out which day a timestamp belongs to. Loaded from settings by loadDaySettings
+const daySettings = {
+    timeZone: undefined as string | undefined, // undefined uses the system's time zone
+    dayStartMinutes: 0,
+};
+
+/**
+ * Reads the time zone and start of day from settings. Ran on startup and after settings are saved
+ */
+export async function loadDaySettings() {
+    try {
+        const conf = await GetConfig();
+        daySettings.timeZone = !conf.Timezone || conf.Timezone === "Local" ? undefined : conf.Timezone;
+
+        const [hours, minutes] = (conf.DayStartsAt || "00:00").split(":").map(Number);
+        daySettings.dayStartMinutes = (hours || 0) * 60 + (minutes || 0);
+    } catch (err) {
+        console.error("Could not read the time zone and start of day from settings", err);
+    }
+}
+
+/**
+ * Returns the date of the day a timestamp belongs to, in the configured time zone. Times before the configured
+ * start of the day belong to the previous day. The returned Date holds the date in the system's time zone
+ */
+function dayOf(date: Date): Date {
+    const parts = new Intl.DateTimeFormat("en-US", {
+        timeZone: daySettings.timeZone,
+        year: "numeric",
+        month: "numeric",
+        day: "numeric",
+        hour: "numeric",
+        minute: "numeric",
+        hourCycle: "h23",
+    }).formatToParts(date);
+    const part = (type: string) => Number(parts.find((p) => p.type === type)?.value);
+
+    const day = new Date(part("year"), part("month") - 1, part("day"));
+    if (part("hour") * 60 + part("minute") < daySettings.dayStartMinutes) {
+        day.setDate(day.getDate() - 1);
+    }
+    return day;
+}
+
+/**
+ * Formats the time of day of a UNIX second timestamp in the configured time zone
+ */
+export function formatTime(unixSeconds: number): string {
+    return new Date(unixSeconds * 1000).toLocaleTimeString(undefined, { timeZone: daySettings.timeZone });
+}
+
 export function formatDate(unixSeconds: number): string {
-    const date = new Date(unixSeconds * 1000);
-    const now = new Date();
+    const date = dayOf(new Date(unixSeconds * 1000));
+    const now = dayOf(new Date());
     const yesterday = new Date(now);
     yesterday.setDate(yesterday.getDate() - 1);
 
@@ -5,6 +5,8 @@ import (
 	"embed"
 	"recap/internal/config"
 	"recap/internal/db"
+	"recap/internal/llm"
+	"recap/internal/models"
 	"runtime"
 
 	"github.com/wailsapp/wails/v2"
@@ -30,13 +32,20 @@ type AppMethods struct {
 	CGetScreenshotById           func(id int) (*db.CaptureScreenshotImage, error)
 	CGetScreenshotsNewerThan     func(timestamp int) ([]db.CaptureScreenshotImage, error)
 	CGetScreenshotsOlderThan     func(timestamp int, limit int) ([]db.CaptureScreenshotImage, error)
+	CGetScreenshotsForReport     func(reportId int) ([]db.CaptureScreenshotImage, error)
 	CDeleteScreenshotsById       func(ids []int) error
-	CGenerateReportWithSelectScr func(ids []int) (*int64, error)
+	CGenerateReportWithSelectScr func(ids []int, bypassCache bool) (*int64, error)
 	CGetReports                  func(limit int) ([]db.Report, error)
 	CGetReportById               func(id int) (*db.Report, error)
 	CGetReportsNewerThan         func(id int) ([]db.Report, error)
 	CGetReportsOlderThan         func(timestamp int, limit int) ([]db.Report, error)
+	CGetReportsForScreenshot     func(captureId int) ([]db.Report, error)
 	CDeleteReportsById           func(ids []int) error
+	CAskHistory                  func(question string) (*llm.HistoryAnswer, error)
+	CSemanticSearch              func(query string, from int64, to int64, k int) ([]db.SemanticMatch, error)
+	CSearch                      func(query string, filters db.SearchFilters) ([]db.SearchResult, error)
+	CTestProvider                func(api string, model string, pending map[string]string) (*models.HealthCheckResult, error)
+	CListModels                  func(api string) ([]models.ModelInfo, error)
 	CGetConfig                   func() (*config.AppConfig, error)
 	CGetDisplayValues            func() map[string]db.SettingDisplayProps
 	CUpdateSettings              func(map[string]string) error
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"recap/internal/config"
 	"recap/internal/db"
+	"recap/internal/llm"
+	"recap/internal/models"
 
 	"github.com/sqweek/dialog"
 )
@@ -81,6 +83,20 @@ func (a *AppMethods) GetScreenshotsNewerThan(id int) ([]db.CaptureScreenshotImag
 	return []db.CaptureScreenshotImage{}, fmt.Errorf("callback functions were not passed to AppMethods")
 }
 
+func (a *AppMethods) GetScreenshotsForReport(reportId int) ([]db.CaptureScreenshotImage, error) {
+	if a.CGetScreenshotsForReport != nil {
+		results, err := a.CGetScreenshotsForReport(reportId)
+		if err != nil {
+			fmt.Printf("Received error from GetScreenshotsForReport: %v\n", err)
+			return []db.CaptureScreenshotImage{}, err
+		}
+
+		return results, nil
+	}
+
+	return []db.CaptureScreenshotImage{}, fmt.Errorf("callback functions were not passed to AppMethods")
+}
+
 func (a *AppMethods) GetReports(limit int) []db.Report {
 	if a.CGetReports != nil {
 		results, err := a.CGetReports(limit)
@@ -123,6 +139,20 @@ func (a *AppMethods) GetReportsNewerThan(id int) []db.Report {
 	return nil
 }
 
+func (a *AppMethods) GetReportsForScreenshot(captureId int) []db.Report {
+	if a.CGetReportsForScreenshot != nil {
+		result, err := a.CGetReportsForScreenshot(captureId)
+		if err != nil {
+			fmt.Println(err)
+			return nil
+		}
+
+		return result
+	}
+
+	return nil
+}
+
 func (a *AppMethods) GetReportsOlderThan(id int, limit int) []db.Report {
 	if a.CGetReportsOlderThan != nil {
 		result, err := a.CGetReportsOlderThan(id, limit)
@@ -179,9 +209,9 @@ func (a *AppMethods) DeleteReportsById(ids []int) error {
 	return fmt.Errorf("callback functions not passed")
 }
 
-func (a *AppMethods) GenerateReportFromScreenshotIds(ids []int) (*int64, error) {
+func (a *AppMethods) GenerateReportFromScreenshotIds(ids []int, bypassCache bool) (*int64, error) {
 	if a.CGenerateReportWithSelectScr != nil {
-		result, err := a.CGenerateReportWithSelectScr(ids)
+		result, err := a.CGenerateReportWithSelectScr(ids, bypassCache)
 		if err != nil {
 			fmt.Println(err)
 			return nil, nil
@@ -193,6 +223,76 @@ func (a *AppMethods) GenerateReportFromScreenshotIds(ids []int) (*int64, error)
 	return nil, nil
 }
 
+func (a *AppMethods) AskHistory(question string) (*llm.HistoryAnswer, error) {
+	if a.CAskHistory != nil {
+		result, err := a.CAskHistory(question)
+		if err != nil {
+			fmt.Printf("Received error from AskHistory: %v\n", err)
+			return nil, err
+		}
+
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("missing function AskHistory")
+}
+
+func (a *AppMethods) TestProvider(api string, model string, pending map[string]string) (*models.HealthCheckResult, error) {
+	if a.CTestProvider != nil {
+		result, err := a.CTestProvider(api, model, pending)
+		if err != nil {
+			fmt.Printf("Received error from TestProvider: %v\n", err)
+			return nil, err
+		}
+
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("missing function TestProvider")
+}
+
+func (a *AppMethods) ListModels(api string) ([]models.ModelInfo, error) {
+	if a.CListModels != nil {
+		list, err := a.CListModels(api)
+		if err != nil {
+			fmt.Printf("Received error from ListModels: %v\n", err)
+			return []models.ModelInfo{}, err
+		}
+
+		return list, nil
+	}
+
+	return []models.ModelInfo{}, fmt.Errorf("missing function ListModels")
+}
+
+func (a *AppMethods) Search(query string, filters db.SearchFilters) ([]db.SearchResult, error) {
+	if a.CSearch != nil {
+		results, err := a.CSearch(query, filters)
+		if err != nil {
+			fmt.Printf("Received error from Search: %v\n", err)
+			return []db.SearchResult{}, err
+		}
+
+		return results, nil
+	}
+
+	return []db.SearchResult{}, fmt.Errorf("missing function Search")
+}
+
+func (a *AppMethods) SemanticSearch(query string, from int64, to int64, k int) ([]db.SemanticMatch, error) {
+	if a.CSemanticSearch != nil {
+		results, err := a.CSemanticSearch(query, from, to, k)
+		if err != nil {
+			fmt.Printf("Received error from SemanticSearch: %v\n", err)
+			return []db.SemanticMatch{}, err
+		}
+
+		return results, nil
+	}
+
+	return []db.SemanticMatch{}, fmt.Errorf("missing function SemanticSearch")
+}
+
 func (a *AppMethods) GetConfig() *config.AppConfig {
 	if a.CGetConfig != nil {
 		result, err := a.CGetConfig()
@@ -12,28 +12,69 @@ var Config AppConfig
 var Info AppInfo
 
 type AppConfig struct {
-	ScrPath                   string `json:"ScrPath"`
-	DescGenAPI                string `json:"DescGenAPI"`
-	DescGenModel              string `json:"DescGenModel"`
-	DescGenPrompt             string `json:"DescGenPrompt"`
-	DescGenIntervalMins       int    `json:"DescGenIntervalMins"`
-	DescGenIntervalEnabled    int    `json:"DescGenIntervalEnabled"`
-	ScreenshotIntervalMins    int    `json:"ScreenshotIntervalMins"`
-	ScreenshotIntervalEnabled int    `json:"ScreenshotIntervalEnabled"`
-	ReportAPI                 string `json:"ReportAPI"`
-	ReportModel               string `json:"ReportModel"`
-	ReportAutoEnabled         int    `json:"ReportAutoEnabled"`
-	ReportAutoAt              string `json:"ReportAutoAt"`
-	ReportPrompt              string `json:"ReportPrompt"`
-	OllamaURL                 string `json:"OllamaURL"`
-	GeminiAPIKey              string `json:"GeminiAPIKey"`
-	OpenAIAPIKey              string `json:"OpenAIAPIKey"`
-	OpenRouterAPIKey          string `json:"OpenRouterAPIKey"`
+	ScrPath                   string  `json:"ScrPath"`
+	DescGenAPI                string  `json:"DescGenAPI"`
+	DescGenModel              string  `json:"DescGenModel"`
+	DescGenFallback           string  `json:"DescGenFallback"`
+	DescGenPrompt             string  `json:"DescGenPrompt"`
+	DescGenIntervalMins       int     `json:"DescGenIntervalMins"`
+	DescGenIntervalEnabled    int     `json:"DescGenIntervalEnabled"`
+	DescGenBatchSize          int     `json:"DescGenBatchSize"`
+	DescGenContextCount       int     `json:"DescGenContextCount"`
+	DescGenTemperature        float64 `json:"DescGenTemperature"`
+	DescGenTopP               float64 `json:"DescGenTopP"`
+	DescGenMaxTokens          int     `json:"DescGenMaxTokens"`
+	ImageMaxEdge              int     `json:"ImageMaxEdge"`
+	ImageFormat               string  `json:"ImageFormat"`
+	ImageQuality              int     `json:"ImageQuality"`
+	ImageGrayscale            int     `json:"ImageGrayscale"`
+	ImageCropToActiveDisplay  int     `json:"ImageCropToActiveDisplay"`
+	ImageOverrides            string  `json:"ImageOverrides"`
+	ScreenshotIntervalMins    int     `json:"ScreenshotIntervalMins"`
+	ScreenshotIntervalEnabled int     `json:"ScreenshotIntervalEnabled"`
+	ReportAPI                 string  `json:"ReportAPI"`
+	ReportModel               string  `json:"ReportModel"`
+	ReportFallback            string  `json:"ReportFallback"`
+	ReportAutoEnabled         int     `json:"ReportAutoEnabled"`
+	ReportAutoAt              string  `json:"ReportAutoAt"`
+	ReportPrompt              string  `json:"ReportPrompt"`
+	ReportTemperature         float64 `json:"ReportTemperature"`
+	ReportTopP                float64 `json:"ReportTopP"`
+	ReportMaxTokens           int     `json:"ReportMaxTokens"`
+	Timezone                  string  `json:"Timezone"`
+	DayStartsAt               string  `json:"DayStartsAt"`
+	EmbeddingEnabled          int     `json:"EmbeddingEnabled"`
+	EmbeddingAPI              string  `json:"EmbeddingAPI"`
+	EmbeddingModel            string  `json:"EmbeddingModel"`
+	ScrubEnabled              int     `json:"ScrubEnabled"`
+	ScrubDetectors            string  `json:"ScrubDetectors"`
+	ScrubWords                string  `json:"ScrubWords"`
+	OllamaURL                 string  `json:"OllamaURL"`
+	OllamaKeepAlive           string  `json:"OllamaKeepAlive"`
+	OllamaNumCtx              int     `json:"OllamaNumCtx"`
+	GeminiAPIKey              string  `json:"GeminiAPIKey"`
+	GeminiInlineImages        int     `json:"GeminiInlineImages"`
+	OpenAIAPIKey              string  `json:"OpenAIAPIKey"`
+	OpenRouterAPIKey          string  `json:"OpenRouterAPIKey"`
+	AnthropicAPIKey           string  `json:"AnthropicAPIKey"`
+	CustomOpenAIURL           string  `json:"CustomOpenAIURL"`
+	CustomOpenAIAPIKey        string  `json:"CustomOpenAIAPIKey"`
+	CustomOpenAIHeaders       string  `json:"CustomOpenAIHeaders"`
+	CustomOpenAIVision        int     `json:"CustomOpenAIVision"`
+	HTTPProxy                 string  `json:"HTTPProxy"`
+	HTTPCABundle              string  `json:"HTTPCABundle"`
+	HTTPConnectTimeoutSecs    int     `json:"HTTPConnectTimeoutSecs"`
+	HTTPResponseTimeoutSecs   int     `json:"HTTPResponseTimeoutSecs"`
+	HTTPOverrides             string  `json:"HTTPOverrides"`
+	ResponseCacheEnabled      int     `json:"ResponseCacheEnabled"`
+	ResponseCacheTTLHours     int     `json:"ResponseCacheTTLHours"`
+	ResponseCacheMaxMB        int     `json:"ResponseCacheMaxMB"`
 }
 
 type AppInfo struct {
 	Version                string `json:"Version"`
 	FirstTimeTutorialShown string `json:"FirstTimeTutorialShown"`
+	RedactedItemCount      string `json:"RedactedItemCount"`
 }
 
 // CreateFolderIfNotExists checks if a folder exists at the given path, and if not, creates it with permissions set to 0700.
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// Returns the time zone with the given IANA name, such as Europe/Berlin. Local, or an empty name,
+// is the system's time zone
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return time.Local, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// Parses a time of day written as HH:MM, returning the hour and minute
+func ParseClock(value string) (hour int, minute int, err error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%q is not a time of day in HH:MM format", value)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// Returns the time zone dates are worked out in, falling back to the system's if the setting is invalid
+func Location() *time.Location {
+	loc, err := LoadLocation(Config.Timezone)
+	if err != nil {
+		log.Printf("%v, using the system's time zone", err)
+		return time.Local
+	}
+	return loc
+}
+
+// Returns when the day dated y-m-d starts, in the configured time zone and at the configured start of day
+func DayStart(y int, m time.Month, d int) time.Time {
+	hour, minute, err := ParseClock(Config.DayStartsAt)
+	if err != nil {
+		hour, minute = 0, 0
+	}
+	return time.Date(y, m, d, hour, minute, 0, 0, Location())
+}
+
+// Returns when the day t falls in started. Days start at DayStartsAt in the configured time zone,
+// so times before it belong to the previous day
+func StartOfDay(t time.Time) time.Time {
+	y, m, d := t.In(Location()).Date()
+	start := DayStart(y, m, d)
+	if start.After(t) {
+		start = DayStart(y, m, d-1)
+	}
+	return start
+}
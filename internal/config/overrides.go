@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Checks one key=value pair of an API's overrides, returning an error for unknown keys and invalid values
+type OverrideValidator func(api string, key string, value string) error
+
+// Parses per-API overrides of a group of settings, given as semicolon-separated "API: key=value, key=value" entries,
+// e.g. "Ollama: max_edge=1344, format=jpeg; Gemini: max_edge=3072". Each pair is checked with validate.
+// Returns the values by API and then by key
+func ParseOverrides(list string, validate OverrideValidator) (map[string]map[string]string, error) {
+	overrides := make(map[string]map[string]string)
+
+	for _, entry := range strings.Split(list, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		api, values, ok := strings.Cut(entry, ":")
+		api = strings.TrimSpace(api)
+		if !ok || api == "" {
+			return nil, fmt.Errorf("invalid override %q, expected API: key=value", entry)
+		}
+
+		if overrides[api] == nil {
+			overrides[api] = make(map[string]string)
+		}
+
+		for _, pair := range strings.Split(values, ",") {
+			key, value, ok := strings.Cut(pair, "=")
+			key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+			if !ok {
+				return nil, fmt.Errorf("invalid override %q for %s, expected key=value", strings.TrimSpace(pair), api)
+			}
+
+			if err := validate(api, key, value); err != nil {
+				return nil, err
+			}
+
+			overrides[api][key] = value
+		}
+	}
+
+	return overrides, nil
+}
@@ -2,16 +2,33 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"path"
 	"recap/internal/config"
+	"recap/internal/db/migrations"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 var Initializers InitializerCallbacks
 
+const (
+	// How long a connection waits for another one to release a lock before giving up
+	busyTimeout = 5 * time.Second
+
+	// Writes are serialized by SQLite anyway, a few connections are enough for reads to run alongside them
+	maxOpenConns    = 4
+	connMaxIdleTime = 5 * time.Minute
+)
+
+// Implements Store with a SQLite database, through a pool of connections to it
+type SQLiteStore struct {
+	db *sql.DB
+}
+
 // A list of callbacks that are used to initialize parts of the application,
 // called directly during the database initialization process. This avoids
 // circular imports
@@ -25,119 +42,116 @@ func NewInitializers() *InitializerCallbacks {
 	return &InitializerCallbacks{}
 }
 
-func createTable(db *sql.DB) {
-	capturesStmt := `
-	CREATE TABLE IF NOT EXISTS captures (
-		capture_id INTEGER NOT NULL PRIMARY KEY, 
-		r_id INTEGER, 
-		timestamp INTEGER NOT NULL
-	);
-	`
-	_, err := db.Exec(capturesStmt)
+// Brings the schema up to date by applying pending migrations, then sets up the full-text search tables,
+// which depend on how SQLite was built. Exits if the schema is newer than this version supports, rather
+// than risk writing to tables it doesn't understand
+func createTable(db *sql.DB, dbPath string) {
+	err := migrations.Run(db, dbPath)
+	if errors.Is(err, migrations.ErrSchemaTooNew) {
+		log.Fatalf("%v. Update Recap, or restore a backup of recap.db made before the newer version ran\n", err)
+	}
 	if err != nil {
-		log.Printf("Error executing query: %q: %s\n", err, capturesStmt)
+		log.Fatalf("Error migrating database: %v\n", err)
 	}
 
-	screenshotsStmt := `
-	CREATE TABLE IF NOT EXISTS screenshots (
-		screenshot_id INTEGER NOT NULL PRIMARY KEY, 
-		capt_id INTEGER NOT NULL,
-		filename TEXT, 
-		thumbname TEXT, 
-		description TEXT, 
-		gen_with_api TEXT,
-		gen_with_model TEXT,
-		FOREIGN KEY(capt_id) REFERENCES captures(capture_id)
-	);
-	`
-	_, err = db.Exec(screenshotsStmt)
+	createSearchTables(db)
+}
+
+// Returns the path of the SQLite database file in the project root
+func databasePath() string {
+	return path.Join(config.GetProjectRoot(), "recap.db")
+}
+
+// Opens the SQLite database at dbPath. Connections use WAL journaling, so the UI can read while
+// descriptions are being written, and wait up to busyTimeout for a lock instead of failing with
+// "database is locked". Transactions take the write lock when they begin, so two of them can't deadlock
+// trying to upgrade from a read lock
+func Open(dbPath string) (*SQLiteStore, error) {
+	dsn := fmt.Sprintf("file:%s?_journal_mode=WAL&_busy_timeout=%d&_foreign_keys=on&_txlock=immediate",
+		dbPath, busyTimeout.Milliseconds())
+
+	dbCl, err := sql.Open("sqlite3", dsn)
 	if err != nil {
-		log.Printf("Error executing query: %q: %s\n", err, screenshotsStmt)
+		return nil, err
 	}
 
-	dailyReportsStmt := `
-	CREATE TABLE IF NOT EXISTS dailyreports (
-		report_id INTEGER NOT NULL PRIMARY KEY,
-		timestamp INTEGER NOT NULL,
-		content TEXT,
-		gen_with_api TEXT,
-		gen_with_model TEXT
-	);
-	`
-	_, err = db.Exec(dailyReportsStmt)
-	if err != nil {
-		log.Printf("Error executing query: %q: %s\n", err, dailyReportsStmt)
+	dbCl.SetMaxOpenConns(maxOpenConns)
+	dbCl.SetMaxIdleConns(maxOpenConns)
+	dbCl.SetConnMaxIdleTime(connMaxIdleTime)
+
+	if err := dbCl.Ping(); err != nil {
+		dbCl.Close()
+		return nil, err
 	}
 
-	settingsStmt := `
-	CREATE TABLE IF NOT EXISTS settings (
-		key TEXT PRIMARY KEY UNIQUE NOT NULL,
-		value TEXT NOT NULL
-	);
-	`
-	_, err = db.Exec(settingsStmt)
+	return &SQLiteStore{db: dbCl}, nil
+}
+
+// Opens a new, empty SQLite database held in memory, with its tables and default settings set up
+// like the application's database. The database is discarded when the store is closed.
+// Meant for tests, which shouldn't touch the user's data
+func OpenMemory() (*SQLiteStore, error) {
+	dbCl, err := sql.Open("sqlite3", "file::memory:?_foreign_keys=on")
 	if err != nil {
-		log.Printf("Error executing query: %q: %s\n", err, settingsStmt)
+		return nil, err
 	}
 
-	infoStmt := `
-	CREATE TABLE IF NOT EXISTS info (
-		key TEXT PRIMARY KEY UNIQUE NOT NULL,
-		value TEXT NOT NULL
-	);
-	`
-	_, err = db.Exec(infoStmt)
-	if err != nil {
-		log.Printf("Error executing query: %q: %s\n", err, infoStmt)
+	// Every connection to an in-memory database gets a database of its own, so a single one is kept open
+	dbCl.SetMaxOpenConns(1)
+	dbCl.SetMaxIdleConns(1)
+	dbCl.SetConnMaxIdleTime(0)
+
+	if err := dbCl.Ping(); err != nil {
+		dbCl.Close()
+		return nil, err
 	}
+
+	store := &SQLiteStore{db: dbCl}
+	store.setup("")
+	return store, nil
 }
 
-// Establishes a connection to the SQLite database located
-// at the project root directory and returns the database handle.
-//
-// Returns:
-//   - *sql.DB: A handle to the SQLite database
-//   - error: An error object if there was an issue opening the database
-func CreateConnection() (*sql.DB, error) {
-	proot := config.GetProjectRoot()
-	return sql.Open("sqlite3", path.Join(proot, "recap.db"))
+// Closes the store's connections. The store can't be used afterwards
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
 }
 
-// Sets up the database connection, creates necessary tables,
-// initializes settings with default values, and loads the configuration.
-// If keepConnectionOpen is true, the database connection remains open and
-// is returned; otherwise, the connection is closed before returning.
-//
-// Parameters:
-//   - keepConnectionOpen: Whether to keep the database
-//     connection open
-func Initialize(keepConnectionOpen bool) (*sql.DB, error) {
-	dbCl, err := CreateConnection()
+// Opens the database, creates necessary tables, initializes settings
+// with default values, and loads the configuration. The returned store
+// is meant to live as long as the application and be shared by every
+// part of it that reads or writes the database
+func Initialize() (*SQLiteStore, error) {
+	store, err := Open(databasePath())
 	if err != nil {
-		log.Fatalf("Error creating DB connection: %v\n", err.Error())
+		return nil, fmt.Errorf("could not open database: %w", err)
 	}
 
-	createTable(dbCl)
+	store.setup(databasePath())
+	return store, nil
+}
+
+// Creates the tables, fills in default settings and info, and loads the configuration.
+// dbPath is where backups are made before migrations; it's empty for in-memory databases, which start empty
+func (s *SQLiteStore) setup(dbPath string) {
+	createTable(s.db, dbPath)
 
-	err = initializeSettings(dbCl, defaultSettings)
+	err := initializeSettings(s.db, defaultSettings)
 	if err != nil {
 		fmt.Printf("Error when inserting setting defaults: %v\n", err.Error())
 	}
 
-	err = InitializeInfo(dbCl)
+	err = migrateSecrets(s.db)
 	if err != nil {
-		fmt.Printf("Error when inserting info defaults: %v\n", err.Error())
+		fmt.Printf("Error when moving API keys to the secret store: %v\n", err.Error())
 	}
 
-	_, err = LoadConfig()
+	err = s.InitializeInfo()
 	if err != nil {
-		log.Fatalf("Failed to load config: %v\n", err.Error())
+		fmt.Printf("Error when inserting info defaults: %v\n", err.Error())
 	}
 
-	if keepConnectionOpen {
-		return dbCl, nil
+	_, err = s.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v\n", err.Error())
 	}
-
-	dbCl.Close()
-	return nil, nil
 }
@@ -0,0 +1,214 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Source types of rows in the embeddings table
+const (
+	EmbeddingSourceCapture = "capture"
+	EmbeddingSourceReport  = "report"
+)
+
+// An embedding vector of a screenshot description or report, along with its source's timestamp
+type Embedding struct {
+	SourceType string
+	SourceID   int
+	Timestamp  int64
+	Vector     []float32
+}
+
+// Encodes a vector into a little-endian float32 byte slice for storage
+func encodeVector(vec []float32) []byte {
+	buf := make([]byte, len(vec)*4)
+	for i, v := range vec {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(v))
+	}
+	return buf
+}
+
+// Decodes a vector stored by encodeVector
+func decodeVector(buf []byte) []float32 {
+	vec := make([]float32, len(buf)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[i*4:]))
+	}
+	return vec
+}
+
+// Inserts or replaces the embedding of a description or report.
+//
+// Parameters:
+//   - sourceType: EmbeddingSourceCapture or EmbeddingSourceReport
+//   - sourceId: The capture or report ID
+//   - model: Identifies the API and model that generated the vector. Vectors from different models can't be compared
+//   - vector: The embedding vector
+func (s *SQLiteStore) SaveEmbedding(sourceType string, sourceId int, model string, vector []float32) error {
+	_, err := s.db.Exec(`
+		INSERT OR REPLACE INTO embeddings (source_type, source_id, model, vector)
+		VALUES (?, ?, ?, ?)`, sourceType, sourceId, model, encodeVector(vector))
+	if err != nil {
+		return fmt.Errorf("error saving embedding: %v", err)
+	}
+	return nil
+}
+
+// Retrieves up to limit described captures that have no embedding generated with the given model, oldest first
+func (s *SQLiteStore) GetUnembeddedDescriptions(model string, limit int) ([]CaptureDescription, error) {
+	rows, err := s.db.Query(`
+		SELECT
+			c.capture_id,
+			c.timestamp,
+			s.description
+		FROM
+			captures c
+		INNER JOIN
+			screenshots s ON c.capture_id = s.capt_id
+		LEFT JOIN
+			embeddings e ON e.source_type = ? AND e.source_id = c.capture_id
+		WHERE
+			s.description IS NOT NULL AND (e.model IS NULL OR e.model != ?)
+		ORDER BY
+			c.timestamp ASC
+		LIMIT ?
+	`, EmbeddingSourceCapture, model, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	var results []CaptureDescription
+
+	for rows.Next() {
+		var cd CaptureDescription
+		err := rows.Scan(
+			&cd.CaptureID,
+			&cd.Timestamp,
+			&cd.Description,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		results = append(results, cd)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during row iteration: %v", err)
+	}
+
+	return results, nil
+}
+
+// Retrieves up to limit reports that have no embedding generated with the given model, oldest first
+func (s *SQLiteStore) GetUnembeddedReports(model string, limit int) ([]Report, error) {
+	rows, err := s.db.Query(`
+		SELECT
+			r.report_id,
+			r.timestamp,
+			r.content,
+			r.gen_with_api,
+			r.gen_with_model
+		FROM
+			dailyreports r
+		LEFT JOIN
+			embeddings e ON e.source_type = ? AND e.source_id = r.report_id
+		WHERE
+			r.content IS NOT NULL AND (e.model IS NULL OR e.model != ?)
+		ORDER BY
+			r.timestamp ASC
+		LIMIT ?
+	`, EmbeddingSourceReport, model, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	var results []Report
+
+	for rows.Next() {
+		var r Report
+		err := rows.Scan(
+			&r.ReportID,
+			&r.Timestamp,
+			&r.Content,
+			&r.GenWithApi,
+			&r.GenWithModel,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		results = append(results, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during row iteration: %v", err)
+	}
+
+	return results, nil
+}
+
+// Retrieves all embeddings of the given source type generated with the given model, whose source's
+// timestamp falls between the from and to UNIX second timestamps. A from or to value of 0 leaves that
+// end of the range open
+func (s *SQLiteStore) GetEmbeddings(sourceType string, model string, from int64, to int64) ([]Embedding, error) {
+	var query string
+	if sourceType == EmbeddingSourceReport {
+		query = `
+		SELECT e.source_id, r.timestamp, e.vector
+		FROM embeddings e
+		INNER JOIN dailyreports r ON r.report_id = e.source_id
+		WHERE e.source_type = ? AND e.model = ? AND (? = 0 OR r.timestamp >= ?) AND (? = 0 OR r.timestamp < ?)`
+	} else {
+		query = `
+		SELECT e.source_id, c.timestamp, e.vector
+		FROM embeddings e
+		INNER JOIN captures c ON c.capture_id = e.source_id
+		WHERE e.source_type = ? AND e.model = ? AND (? = 0 OR c.timestamp >= ?) AND (? = 0 OR c.timestamp < ?)`
+	}
+
+	rows, err := s.db.Query(query, sourceType, model, from, from, to, to)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	var results []Embedding
+
+	for rows.Next() {
+		emb := Embedding{SourceType: sourceType}
+		var buf []byte
+		if err := rows.Scan(&emb.SourceID, &emb.Timestamp, &buf); err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		emb.Vector = decodeVector(buf)
+		results = append(results, emb)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during row iteration: %v", err)
+	}
+
+	return results, nil
+}
+
+// Deletes the embeddings of the given sources
+func deleteEmbeddings(db *sql.DB, sourceType string, ids []int) error {
+	args := make([]interface{}, len(ids)+1)
+	args[0] = sourceType
+	for i, id := range ids {
+		args[i+1] = id
+	}
+
+	_, err := db.Exec(fmt.Sprintf(`
+		DELETE FROM embeddings
+		WHERE source_type = ? AND source_id IN (%s)
+	`, generateNumOfQuestionMarks(len(ids))), args...)
+	if err != nil {
+		return fmt.Errorf("error deleting embeddings: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,428 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Whether SQLite was built with FTS5 and the full-text tables are in use. FTS5 requires building
+// with the sqlite_fts5 tag; without it, searches fall back to LIKE queries
+var ftsAvailable bool
+
+// Marks the matching words in search snippets. Snippets are rendered as Markdown, so matches show up in bold
+const (
+	snippetHighlightStart = "**"
+	snippetHighlightEnd   = "**"
+)
+
+// Number of tokens FTS5 includes in a snippet, and the number of characters around the first match the
+// LIKE fallback includes
+const (
+	snippetTokens      = 24
+	snippetRadiusChars = 120
+)
+
+// Full-text tables mirror screenshots.description and dailyreports.content through triggers
+var ftsTriggerNames = []string{
+	"screenshots_fts_ai", "screenshots_fts_ad", "screenshots_fts_au",
+	"dailyreports_fts_ai", "dailyreports_fts_ad", "dailyreports_fts_au",
+}
+
+var ftsTriggerStmts = []string{
+	`CREATE TRIGGER IF NOT EXISTS screenshots_fts_ai AFTER INSERT ON screenshots BEGIN
+		INSERT INTO screenshots_fts(rowid, description) VALUES (new.screenshot_id, new.description);
+	END;`,
+	`CREATE TRIGGER IF NOT EXISTS screenshots_fts_ad AFTER DELETE ON screenshots BEGIN
+		INSERT INTO screenshots_fts(screenshots_fts, rowid, description) VALUES ('delete', old.screenshot_id, old.description);
+	END;`,
+	`CREATE TRIGGER IF NOT EXISTS screenshots_fts_au AFTER UPDATE OF description ON screenshots BEGIN
+		INSERT INTO screenshots_fts(screenshots_fts, rowid, description) VALUES ('delete', old.screenshot_id, old.description);
+		INSERT INTO screenshots_fts(rowid, description) VALUES (new.screenshot_id, new.description);
+	END;`,
+	`CREATE TRIGGER IF NOT EXISTS dailyreports_fts_ai AFTER INSERT ON dailyreports BEGIN
+		INSERT INTO dailyreports_fts(rowid, content) VALUES (new.report_id, new.content);
+	END;`,
+	`CREATE TRIGGER IF NOT EXISTS dailyreports_fts_ad AFTER DELETE ON dailyreports BEGIN
+		INSERT INTO dailyreports_fts(dailyreports_fts, rowid, content) VALUES ('delete', old.report_id, old.content);
+	END;`,
+	`CREATE TRIGGER IF NOT EXISTS dailyreports_fts_au AFTER UPDATE OF content ON dailyreports BEGIN
+		INSERT INTO dailyreports_fts(dailyreports_fts, rowid, content) VALUES ('delete', old.report_id, old.content);
+		INSERT INTO dailyreports_fts(rowid, content) VALUES (new.report_id, new.content);
+	END;`,
+}
+
+// Creates the FTS5 tables and the triggers that keep them in sync with screenshot descriptions and
+// report contents. If the triggers didn't exist yet, the tables are rebuilt from the existing rows.
+//
+// If SQLite was built without FTS5, the triggers are dropped instead, since writes to the
+// source tables would fail while they reference a module that isn't available. They're recreated,
+// and the tables rebuilt, the next time a build with FTS5 starts
+func createSearchTables(db *sql.DB) {
+	var enabled int
+	err := db.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled)
+	if err != nil || enabled != 1 {
+		log.Println("SQLite was built without FTS5, full-text search will use LIKE queries")
+		for _, name := range ftsTriggerNames {
+			if _, err := db.Exec("DROP TRIGGER IF EXISTS " + name); err != nil {
+				log.Printf("Error dropping trigger %s: %v\n", name, err)
+			}
+		}
+		ftsAvailable = false
+		return
+	}
+
+	var triggerCount int
+	err = db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name IN (%s)", generateNumOfQuestionMarks(len(ftsTriggerNames))),
+		stringsToArgs(ftsTriggerNames)...).Scan(&triggerCount)
+	if err != nil {
+		log.Printf("Error checking full-text triggers: %v\n", err)
+		return
+	}
+
+	tableStmts := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS screenshots_fts USING fts5(description, content='screenshots', content_rowid='screenshot_id');`,
+		`CREATE VIRTUAL TABLE IF NOT EXISTS dailyreports_fts USING fts5(content, content='dailyreports', content_rowid='report_id');`,
+	}
+
+	for _, stmt := range append(tableStmts, ftsTriggerStmts...) {
+		if _, err := db.Exec(stmt); err != nil {
+			log.Printf("Error executing query: %q: %s\n", err, stmt)
+			return
+		}
+	}
+
+	if triggerCount < len(ftsTriggerNames) {
+		log.Println("Rebuilding full-text search tables")
+		for _, table := range []string{"screenshots_fts", "dailyreports_fts"} {
+			if _, err := db.Exec(fmt.Sprintf("INSERT INTO %s(%s) VALUES ('rebuild')", table, table)); err != nil {
+				log.Printf("Error rebuilding %s: %v\n", table, err)
+				return
+			}
+		}
+	}
+
+	ftsAvailable = true
+}
+
+// Converts a slice of strings to query arguments
+func stringsToArgs(vals []string) []interface{} {
+	args := make([]interface{}, len(vals))
+	for i, v := range vals {
+		args[i] = v
+	}
+	return args
+}
+
+// Matches quoted phrases and single words in a search query. A trailing asterisk marks a prefix query
+var queryTokenRegex = regexp.MustCompile(`"([^"]*)"(\*?)|([^\s"]+)`)
+
+// Strips characters with special meaning in FTS5 queries from a word, keeping letters, digits and joiners
+var queryWordRegex = regexp.MustCompile(`[^\p{L}\p{N}_\-.@']+`)
+
+// A phrase or word of a search query
+type queryToken struct {
+	Text   string
+	Prefix bool
+}
+
+// Splits a user's search query into quoted phrases and words. Words and phrases ending with an
+// asterisk are prefix queries
+func parseSearchQuery(query string) []queryToken {
+	var tokens []queryToken
+
+	for _, m := range queryTokenRegex.FindAllStringSubmatch(query, -1) {
+		if m[1] != "" || m[0] == `""` {
+			if text := strings.TrimSpace(m[1]); text != "" {
+				tokens = append(tokens, queryToken{Text: text, Prefix: m[2] == "*"})
+			}
+			continue
+		}
+
+		word := m[3]
+		prefix := strings.HasSuffix(word, "*")
+		word = strings.Trim(queryWordRegex.ReplaceAllString(word, " "), " ")
+		for _, part := range strings.Fields(word) {
+			tokens = append(tokens, queryToken{Text: part})
+		}
+		if prefix && len(tokens) > 0 {
+			tokens[len(tokens)-1].Prefix = true
+		}
+	}
+
+	return tokens
+}
+
+// Builds an FTS5 MATCH expression from query tokens, joining them with the given operator ("AND" or "OR").
+// Every token is quoted so characters in user input can't change the query's meaning
+func buildFTSQuery(tokens []queryToken, operator string) string {
+	parts := make([]string, len(tokens))
+
+	for i, tok := range tokens {
+		parts[i] = `"` + strings.ReplaceAll(tok.Text, `"`, `""`) + `"`
+		if tok.Prefix {
+			parts[i] += "*"
+		}
+	}
+
+	return strings.Join(parts, " "+operator+" ")
+}
+
+// Builds a snippet of text around the first occurrence of any of the tokens, highlighting every occurrence.
+// Used when FTS5 is unavailable
+func makeSnippet(text string, tokens []queryToken) string {
+	lower := strings.ToLower(text)
+	first := -1
+
+	for _, tok := range tokens {
+		if idx := strings.Index(lower, strings.ToLower(tok.Text)); idx >= 0 && (first < 0 || idx < first) {
+			first = idx
+		}
+	}
+
+	start, end := 0, len(text)
+	if first >= 0 {
+		start = max(first-snippetRadiusChars, 0)
+		end = min(first+snippetRadiusChars, len(text))
+	} else {
+		end = min(2*snippetRadiusChars, len(text))
+	}
+
+	// Don't cut multi-byte characters in half
+	for start > 0 && !isRuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !isRuneStart(text[end]) {
+		end++
+	}
+
+	snippet := text[start:end]
+	for _, tok := range tokens {
+		re, err := regexp.Compile(`(?i)` + regexp.QuoteMeta(tok.Text))
+		if err != nil {
+			continue
+		}
+		snippet = re.ReplaceAllString(snippet, snippetHighlightStart+"$0"+snippetHighlightEnd)
+	}
+
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(text) {
+		snippet += "…"
+	}
+
+	return snippet
+}
+
+// Reports whether b is the first byte of a UTF-8 encoded character
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// Returns true if kinds is empty or contains kind
+func includesKind(kinds []string, kind string) bool {
+	if len(kinds) == 0 {
+		return true
+	}
+
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Runs a full-text query against screenshot descriptions, returning highlighted snippets ranked by relevance
+func searchDescriptionsFTS(db *sql.DB, match string, from int64, to int64, limit int) ([]SearchResult, error) {
+	rows, err := db.Query(`
+		SELECT
+			c.capture_id,
+			c.timestamp,
+			snippet(screenshots_fts, 0, ?, ?, '…', ?),
+			bm25(screenshots_fts)
+		FROM
+			screenshots_fts
+		INNER JOIN
+			screenshots s ON s.screenshot_id = screenshots_fts.rowid
+		INNER JOIN
+			captures c ON c.capture_id = s.capt_id
+		WHERE
+			screenshots_fts MATCH ?
+			AND (? = 0 OR c.timestamp >= ?) AND (? = 0 OR c.timestamp < ?)
+		ORDER BY
+			bm25(screenshots_fts)
+		LIMIT ?
+	`, snippetHighlightStart, snippetHighlightEnd, snippetTokens, match, from, from, to, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+
+	for rows.Next() {
+		r := SearchResult{Kind: SearchKindCapture}
+		if err := rows.Scan(&r.ID, &r.Timestamp, &r.Snippet, &r.Rank); err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		results = append(results, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during row iteration: %v", err)
+	}
+
+	return results, nil
+}
+
+// Runs a full-text query against report contents, returning highlighted snippets ranked by relevance
+func searchReportsFTS(db *sql.DB, match string, from int64, to int64, limit int) ([]SearchResult, error) {
+	rows, err := db.Query(`
+		SELECT
+			r.report_id,
+			r.timestamp,
+			snippet(dailyreports_fts, 0, ?, ?, '…', ?),
+			bm25(dailyreports_fts)
+		FROM
+			dailyreports_fts
+		INNER JOIN
+			dailyreports r ON r.report_id = dailyreports_fts.rowid
+		WHERE
+			dailyreports_fts MATCH ?
+			AND (? = 0 OR r.timestamp >= ?) AND (? = 0 OR r.timestamp < ?)
+		ORDER BY
+			bm25(dailyreports_fts)
+		LIMIT ?
+	`, snippetHighlightStart, snippetHighlightEnd, snippetTokens, match, from, from, to, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+
+	for rows.Next() {
+		r := SearchResult{Kind: SearchKindReport}
+		if err := rows.Scan(&r.ID, &r.Timestamp, &r.Snippet, &r.Rank); err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		results = append(results, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during row iteration: %v", err)
+	}
+
+	return results, nil
+}
+
+// Searches screenshot descriptions and reports with LIKE queries, for when FTS5 is unavailable.
+// Phrases are matched as-is and prefix markers are ignored, since LIKE matches substrings anyway
+func (s *SQLiteStore) searchLike(tokens []queryToken, filters SearchFilters) ([]SearchResult, error) {
+	terms := make([]string, len(tokens))
+	for i, tok := range tokens {
+		terms[i] = tok.Text
+	}
+
+	var results []SearchResult
+
+	if includesKind(filters.Kinds, SearchKindCapture) {
+		descs, err := s.SearchDescriptions(terms, filters.From, filters.To, filters.Limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, desc := range descs {
+			if countMatchingTerms(desc.Description, terms) < len(terms) {
+				continue // Every term must match, like an FTS5 AND query
+			}
+			results = append(results, SearchResult{Kind: SearchKindCapture, ID: desc.CaptureID, Timestamp: desc.Timestamp, Snippet: makeSnippet(desc.Description, tokens)})
+		}
+	}
+
+	if includesKind(filters.Kinds, SearchKindReport) {
+		reports, err := s.SearchReports(terms, filters.From, filters.To, filters.Limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, rep := range reports {
+			if countMatchingTerms(rep.Content, terms) < len(terms) {
+				continue
+			}
+			results = append(results, SearchResult{Kind: SearchKindReport, ID: rep.ReportID, Timestamp: int64(rep.Timestamp), Snippet: makeSnippet(rep.Content, tokens)})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Timestamp > results[j].Timestamp
+	})
+
+	if results == nil {
+		results = []SearchResult{}
+	}
+
+	return results, nil
+}
+
+// Searches screenshot descriptions and reports for a query, returning highlighted snippets along with
+// the matching capture or report IDs and timestamps, most relevant first.
+//
+// Words in the query must all match. "Quoted phrases" match words in that exact order, and words or
+// phrases ending with an asterisk match as prefixes, e.g. migrat* matches "migration" and "migrating".
+//
+// Parameters:
+//   - query: The search query
+//   - filters: Restricts results to a time range and to captures, reports or both
+func (s *SQLiteStore) Search(query string, filters SearchFilters) ([]SearchResult, error) {
+	tokens := parseSearchQuery(query)
+	if len(tokens) == 0 {
+		return []SearchResult{}, nil
+	}
+
+	if filters.Limit <= 0 {
+		filters.Limit = 50
+	}
+
+	if !ftsAvailable {
+		results, err := s.searchLike(tokens, filters)
+		if err != nil {
+			return nil, err
+		}
+		return results[:min(filters.Limit, len(results))], nil
+	}
+
+	match := buildFTSQuery(tokens, "AND")
+	var results []SearchResult
+
+	if includesKind(filters.Kinds, SearchKindCapture) {
+		descs, err := searchDescriptionsFTS(s.db, match, filters.From, filters.To, filters.Limit)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, descs...)
+	}
+
+	if includesKind(filters.Kinds, SearchKindReport) {
+		reports, err := searchReportsFTS(s.db, match, filters.From, filters.To, filters.Limit)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, reports...)
+	}
+
+	// bm25 scores are lower for better matches
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Rank < results[j].Rank
+	})
+
+	if results == nil {
+		results = []SearchResult{}
+	}
+
+	return results[:min(filters.Limit, len(results))], nil
+}
@@ -24,15 +24,8 @@ var defaultInfo = map[string]string{
 // ! This is unused
 // Parameters:
 //   - value: A string representing the value to be inserted
-func WriteInfo(key, value string) error {
-	dbCl, err := CreateConnection()
-	if err != nil {
-		fmt.Printf("Could not create DB connection: %v\n", err.Error())
-		return err
-	}
-	defer dbCl.Close()
-
-	_, err = dbCl.Exec("INSERT INTO info (key, value) VALUES (?, ?)", key, value)
+func (s *SQLiteStore) WriteInfo(key, value string) error {
+	_, err := s.db.Exec("INSERT INTO info (key, value) VALUES (?, ?)", key, value)
 	if err != nil {
 		return fmt.Errorf("error inserting info: %v", err)
 	}
@@ -48,16 +41,9 @@ func updateInfo(db *sql.DB, key, newValue string) error {
 
 // Updates info in both the database and the in-memory configuration (config.Info) using reflection.
 // It ensures that changes to info are saved persistently and reflected immediately in the running application.
-func UpdateInfo(newInfo map[string]string) error {
-	dbCl, err := CreateConnection()
-	if err != nil {
-		fmt.Printf("Could not create DB connection: %v\n", err.Error())
-		return err
-	}
-	defer dbCl.Close()
-
+func (s *SQLiteStore) UpdateInfo(newInfo map[string]string) error {
 	for key, val := range newInfo {
-		err = updateInfo(dbCl, key, val)
+		err := updateInfo(s.db, key, val)
 		if err != nil {
 			fmt.Printf("Error when updating %s with %s: %v\n", key, val, err.Error())
 			return err
@@ -87,19 +73,30 @@ func UpdateInfo(newInfo map[string]string) error {
 	return nil
 }
 
+// Adds count to the RedactedItemCount info value, the total number of private information items
+// removed from descriptions, creating it if it doesn't exist yet
+func (s *SQLiteStore) AddRedactedItemCount(count int) error {
+	_, err := s.db.Exec(`
+		INSERT INTO info (key, value) VALUES ('RedactedItemCount', ?)
+		ON CONFLICT(key) DO UPDATE SET value = CAST(value AS INTEGER) + CAST(excluded.value AS INTEGER)
+	`, strconv.Itoa(count))
+	if err != nil {
+		return fmt.Errorf("error updating redacted item count: %v", err)
+	}
+
+	err = s.db.QueryRow("SELECT value FROM info WHERE key = 'RedactedItemCount'").Scan(&config.Info.RedactedItemCount)
+	if err != nil {
+		return fmt.Errorf("error reading redacted item count: %v", err)
+	}
+	return nil
+}
+
 // Retrieves an Info record from the database based on the provided key.
 //
 // Parameters:
 //   - key - A string representing the key to search for in the info table
-func ReadInfo(key string) (*Info, error) {
-	dbCl, err := CreateConnection()
-	if err != nil {
-		fmt.Printf("Could not create DB connection: %v\n", err.Error())
-		return nil, err
-	}
-	defer dbCl.Close()
-
-	row := dbCl.QueryRow("SELECT key, value FROM info WHERE key = ?", key)
+func (s *SQLiteStore) ReadInfo(key string) (*Info, error) {
+	row := s.db.QueryRow("SELECT key, value FROM info WHERE key = ?", key)
 
 	var info Info
 	if err := row.Scan(&info.Key, &info.Value); err != nil {
@@ -118,6 +115,8 @@ func ReadInfoToStruct(infoStruct *config.AppInfo, infoMap map[string]string) *co
 			infoStruct.Version = val
 		case "FirstTimeTutorialShown":
 			infoStruct.FirstTimeTutorialShown = val
+		case "RedactedItemCount":
+			infoStruct.RedactedItemCount = val
 		}
 	}
 
@@ -125,15 +124,8 @@ func ReadInfoToStruct(infoStruct *config.AppInfo, infoMap map[string]string) *co
 }
 
 // Retrieves all records from the "info" table in the database.
-func ReadAllInfo() (map[string]string, error) {
-	dbCl, err := CreateConnection()
-	if err != nil {
-		fmt.Printf("Could not create DB connection: %v\n", err.Error())
-		return nil, err
-	}
-	defer dbCl.Close()
-
-	rows, err := dbCl.Query("SELECT * FROM info")
+func (s *SQLiteStore) ReadAllInfo() (map[string]string, error) {
+	rows, err := s.db.Query("SELECT * FROM info")
 	if err != nil {
 		return nil, err
 	}
@@ -162,8 +154,8 @@ func ReadAllInfo() (map[string]string, error) {
 // and commits the transaction. If any error occurs during the process, the transaction
 // is rolled back and an error is returned.
 // If
-func InitializeInfo(dbCl *sql.DB) error {
-	tx, err := dbCl.BeginTx(context.Background(), &sql.TxOptions{})
+func (s *SQLiteStore) InitializeInfo() error {
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{})
 	if err != nil {
 		return fmt.Errorf("could not start database transaction: %v", err)
 	}
@@ -177,7 +169,7 @@ func InitializeInfo(dbCl *sql.DB) error {
 			// Check if the failed key is "Version" and attempt to update it
 			if k == "Version" {
 				updateData := map[string]string{"Version": v}
-				if updateErr := UpdateInfo(updateData); updateErr != nil {
+				if updateErr := s.UpdateInfo(updateData); updateErr != nil {
 					return fmt.Errorf("error updating info after failed insert (%s): %v", k, updateErr)
 				}
 			} else {
@@ -0,0 +1,90 @@
+package migrations
+
+// Every migration, in order. Tables use IF NOT EXISTS in migrations that predate this list, so databases
+// created before migrations were tracked can apply them too
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create captures, screenshots, reports, settings and info tables",
+		Up: execAll(
+			`CREATE TABLE IF NOT EXISTS captures (
+				capture_id INTEGER NOT NULL PRIMARY KEY,
+				r_id INTEGER,
+				timestamp INTEGER NOT NULL
+			);`,
+			`CREATE TABLE IF NOT EXISTS screenshots (
+				screenshot_id INTEGER NOT NULL PRIMARY KEY,
+				capt_id INTEGER NOT NULL,
+				filename TEXT,
+				thumbname TEXT,
+				description TEXT,
+				gen_with_api TEXT,
+				gen_with_model TEXT,
+				FOREIGN KEY(capt_id) REFERENCES captures(capture_id)
+			);`,
+			`CREATE TABLE IF NOT EXISTS dailyreports (
+				report_id INTEGER NOT NULL PRIMARY KEY,
+				timestamp INTEGER NOT NULL,
+				content TEXT,
+				gen_with_api TEXT,
+				gen_with_model TEXT
+			);`,
+			`CREATE TABLE IF NOT EXISTS settings (
+				key TEXT PRIMARY KEY UNIQUE NOT NULL,
+				value TEXT NOT NULL
+			);`,
+			`CREATE TABLE IF NOT EXISTS info (
+				key TEXT PRIMARY KEY UNIQUE NOT NULL,
+				value TEXT NOT NULL
+			);`,
+		),
+	},
+	{
+		Version: 2,
+		Name:    "create embeddings table",
+		Up: execAll(
+			`CREATE TABLE IF NOT EXISTS embeddings (
+				source_type TEXT NOT NULL,
+				source_id INTEGER NOT NULL,
+				model TEXT NOT NULL,
+				vector BLOB NOT NULL,
+				PRIMARY KEY (source_type, source_id)
+			);`,
+		),
+	},
+	{
+		Version: 3,
+		Name:    "create response_cache table",
+		Up: execAll(
+			`CREATE TABLE IF NOT EXISTS response_cache (
+				image_hash TEXT NOT NULL,
+				prompt_hash TEXT NOT NULL,
+				api TEXT NOT NULL,
+				model TEXT NOT NULL,
+				params TEXT NOT NULL,
+				response TEXT NOT NULL,
+				created_at INTEGER NOT NULL,
+				PRIMARY KEY (image_hash, prompt_hash, api, model, params)
+			);`,
+		),
+	},
+	{
+		Version: 4,
+		Name:    "link reports and captures through report_captures",
+		Up: execAll(
+			`CREATE TABLE report_captures (
+				report_id INTEGER NOT NULL,
+				capture_id INTEGER NOT NULL,
+				PRIMARY KEY (report_id, capture_id),
+				FOREIGN KEY(report_id) REFERENCES dailyreports(report_id) ON DELETE CASCADE,
+				FOREIGN KEY(capture_id) REFERENCES captures(capture_id) ON DELETE CASCADE
+			);`,
+			`CREATE INDEX report_captures_capture_id ON report_captures (capture_id);`,
+			// Captures could point to reports that were deleted since, which aren't carried over
+			`INSERT INTO report_captures (report_id, capture_id)
+				SELECT r_id, capture_id FROM captures
+				WHERE r_id IN (SELECT report_id FROM dailyreports);`,
+			`ALTER TABLE captures DROP COLUMN r_id;`,
+		),
+	},
+}
@@ -0,0 +1,144 @@
+package migrations
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+/**
+The database schema is changed by numbered migrations, applied in order by Run. Each migration runs in its own
+transaction and is recorded in the schema_migrations table along with the time it was applied, so it's never
+applied twice. The database is backed up before any migration runs.
+
+Released migrations must not be edited, since databases that already applied them won't run them again.
+Change the schema by appending a new migration to the list in list.go
+*/
+
+// A change to the schema, applied inside a transaction
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *sql.Tx) error
+}
+
+// Returned by Run when the database was migrated by a newer version of Recap than this one
+var ErrSchemaTooNew = errors.New("database schema is newer than this version of Recap supports")
+
+// Returns a migration step that executes the statements in order
+func execAll(stmts ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, stmt := range stmts {
+			if _, err := tx.Exec(stmt); err != nil {
+				return fmt.Errorf("error executing %q: %w", stmt, err)
+			}
+		}
+		return nil
+	}
+}
+
+// Returns the version of the newest migration this version of Recap has
+func Latest() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// Returns the version of the newest migration applied to the database, or 0 if none were
+func CurrentVersion(db *sql.DB) (int, error) {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER NOT NULL PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at INTEGER NOT NULL
+	);
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("error creating schema_migrations table: %w", err)
+	}
+
+	var version int
+	if err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, fmt.Errorf("error reading schema version: %w", err)
+	}
+	return version, nil
+}
+
+// Reports whether the database has any tables besides schema_migrations, i.e. whether there's data to back up
+func hasTables(db *sql.DB) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name != 'schema_migrations'").Scan(&count)
+	return count > 0, err
+}
+
+// Copies the database to a file next to it, named after the schema version it's at
+func backup(db *sql.DB, dbPath string, version int) (string, error) {
+	backupPath := fmt.Sprintf("%s.v%d-%s.bak", dbPath, version, time.Now().Format("20060102-150405"))
+
+	if _, err := db.Exec("VACUUM INTO ?", backupPath); err != nil {
+		return "", err
+	}
+	return backupPath, nil
+}
+
+// Applies a migration and records it in schema_migrations, in one transaction
+func apply(db *sql.DB, m Migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("could not start database transaction: %w", err)
+	}
+
+	if err := m.Up(tx); err != nil {
+		tx.Rollback() // nolint: all
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)", m.Version, m.Name, time.Now().Unix())
+	if err != nil {
+		tx.Rollback() // nolint: all
+		return fmt.Errorf("error recording migration: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// Applies the migrations the database at dbPath doesn't have yet, in order, backing it up first if it already has
+// tables. Returns ErrSchemaTooNew without changing anything if the database has migrations this version doesn't know
+func Run(db *sql.DB, dbPath string) error {
+	current, err := CurrentVersion(db)
+	if err != nil {
+		return err
+	}
+
+	if current > Latest() {
+		return fmt.Errorf("%w: database is at version %d, newest supported is %d", ErrSchemaTooNew, current, Latest())
+	}
+	if current == Latest() {
+		return nil
+	}
+
+	existing, err := hasTables(db)
+	if err != nil {
+		return fmt.Errorf("error reading database tables: %w", err)
+	}
+	if existing {
+		backupPath, err := backup(db, dbPath, current)
+		if err != nil {
+			return fmt.Errorf("could not back up database before migrating: %w", err)
+		}
+		log.Printf("Backed up database to %s before migrating", backupPath)
+	}
+
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+
+		if err := apply(db, m); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+		log.Printf("Applied database migration %d: %s", m.Version, m.Name)
+	}
+
+	return nil
+}
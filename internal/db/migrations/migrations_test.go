@@ -0,0 +1,109 @@
+package migrations
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Opens a database file in a temporary directory and applies every migration to it. Returns the database and its path
+func openMigratedDatabase(t *testing.T) (*sql.DB, string) {
+	t.Helper()
+
+	dbPath := filepath.Join(t.TempDir(), "recap.db")
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := Run(db, dbPath); err != nil {
+		t.Fatalf("could not migrate a new database: %v", err)
+	}
+	return db, dbPath
+}
+
+func backups(t *testing.T, dbPath string) []string {
+	t.Helper()
+
+	matches, err := filepath.Glob(dbPath + ".v*.bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func TestRunRefusesNewerSchema(t *testing.T) {
+	db, dbPath := openMigratedDatabase(t)
+
+	if _, err := db.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, 'from a newer version', 0)", Latest()+1); err != nil {
+		t.Fatal(err)
+	}
+
+	err := Run(db, dbPath)
+	if !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("expected ErrSchemaTooNew, got %v", err)
+	}
+	if found := backups(t, dbPath); len(found) != 0 {
+		t.Errorf("expected no backup to be made, found %v", found)
+	}
+}
+
+func TestRunRollsBackFailedMigration(t *testing.T) {
+	db, dbPath := openMigratedDatabase(t)
+
+	if _, err := db.Exec("INSERT INTO settings (key, value) VALUES ('kept', 'value')"); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := Latest()
+	original := migrations
+	t.Cleanup(func() { migrations = original })
+	migrations = append(append([]Migration{}, original...), Migration{
+		Version: previous + 1,
+		Name:    "fail halfway",
+		Up: execAll(
+			`CREATE TABLE half_done (id INTEGER);`,
+			`INSERT INTO missing_table (id) VALUES (1);`,
+		),
+	})
+
+	if err := Run(db, dbPath); err == nil {
+		t.Fatal("expected the failing migration to return an error")
+	}
+
+	version, err := CurrentVersion(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != previous {
+		t.Errorf("expected the schema to stay at version %d, got %d", previous, version)
+	}
+
+	var tables int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'half_done'").Scan(&tables); err != nil {
+		t.Fatal(err)
+	}
+	if tables != 0 {
+		t.Error("expected the failed migration's changes to be rolled back")
+	}
+
+	found := backups(t, dbPath)
+	if len(found) != 1 {
+		t.Fatalf("expected one backup, found %v", found)
+	}
+
+	backup, err := sql.Open("sqlite3", found[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backup.Close()
+
+	var value string
+	if err := backup.QueryRow("SELECT value FROM settings WHERE key = 'kept'").Scan(&value); err != nil || value != "value" {
+		t.Errorf("expected the backup to hold the data from before the migration, got %q, %v", value, err)
+	}
+}
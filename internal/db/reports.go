@@ -1,42 +1,28 @@
 package db
 
 import (
-	"database/sql"
 	"fmt"
-	"strings"
 	"time"
 )
 
-// Logs a daily report into the database and updates the associated captures with the report ID.
+// Logs a daily report into the database and links it to the captures it was generated from.
+// A capture can be linked to any number of reports, so generating another report from the same
+// captures doesn't take them away from earlier ones.
 //
 // Parameters:
-//   - db: A pointer to the sql.DB connection. If nil, a new connection will be created.
 //   - reportText: The content of the daily report.
 //   - caps: A slice of CaptureDescription containing the captures to be associated with the report.
 //   - genWithApi: A string indicating the API used to generate the report.
 //   - genWithModel: A string indicating the model used to generate the report.
-//
-// Note: The function uses dynamic SQL placeholders for the IN clause to update the captures.
-func LogDailyReport(db *sql.DB, reportText string, caps []CaptureDescription, genWithApi string, genWithModel string) (*int64, error) {
-	if db == nil {
-		db, err := CreateConnection()
-		if err != nil {
-			return nil, err
-		}
-		defer db.Close()
-	}
-	// Extract capture IDs into a slice
-	capIds := make([]int, len(caps))
-	for i, cap := range caps {
-		capIds[i] = cap.CaptureID
+func (s *SQLiteStore) LogDailyReport(reportText string, caps []CaptureDescription, genWithApi string, genWithModel string) (*int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction: %w", err)
 	}
-
-	// Create placeholders for the IN clause
-	questionMarks := strings.Repeat("?,", len(capIds))
-	questionMarks = strings.TrimSuffix(questionMarks, ",")
+	defer tx.Rollback() // nolint: errcheck
 
 	// Insert the daily report
-	res, err := db.Exec(`
+	res, err := tx.Exec(`
 		INSERT INTO dailyreports (timestamp, content, gen_with_api, gen_with_model)
 		VALUES (?, ?, ?, ?)`,
 		time.Now().UTC().Unix(), reportText, genWithApi, genWithModel)
@@ -53,42 +39,77 @@ func LogDailyReport(db *sql.DB, reportText string, caps []CaptureDescription, ge
 		return nil, err
 	}
 
-	// Update the captures with the report ID
-	query := fmt.Sprintf(`
-		UPDATE captures
-		SET r_id = ?
-		WHERE capture_id IN (%s)`, questionMarks)
-
-	// Prepare arguments for db.Exec (drId + capIds...)
-	// Passing drId, capIds... would have been better but this will do
-	args := make([]interface{}, len(capIds)+1)
-	args[0] = drId
-	for i, id := range capIds {
-		args[i+1] = id
+	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO report_captures (report_id, capture_id) VALUES (?, ?)`)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, cap := range caps {
+		if _, err := stmt.Exec(drId, cap.CaptureID); err != nil {
+			fmt.Printf("Error linking capture %d to report %d: %v\n", cap.CaptureID, drId, err)
+			return nil, err
+		}
 	}
 
-	// Execute the update query with dynamic placeholders
-	_, err = db.Exec(query, args...)
-	if err != nil {
-		fmt.Printf("Error updating captures: %v\n", err)
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing report: %w", err)
 	}
 
 	return &drId, nil
 }
 
-// Retrieves all reports from the database with a report_id greater than the specified id.
-// The results are ordered by timestamp in descending order.
+// Retrieves the reports that were generated from the capture with the given ID, newest first
 //
 // Parameters:
-//   - id: The report_id threshold
-func GetReportsNewerThan(id int) ([]Report, error) {
-	dbCl, err := CreateConnection()
+//   - captureId: The capture_id of the capture
+func (s *SQLiteStore) GetReportsForScreenshot(captureId int) ([]Report, error) {
+	rows, err := s.db.Query(`
+		SELECT r.* FROM dailyreports r
+		INNER JOIN
+			report_captures rc ON r.report_id = rc.report_id
+		WHERE
+			rc.capture_id = ?
+		ORDER BY
+			r.timestamp DESC
+	`, captureId)
+
 	if err != nil {
-		fmt.Printf("Could not create DB connection: %v\n", err.Error())
+		return nil, fmt.Errorf("error executing query: %v", err)
 	}
-	defer dbCl.Close()
+	defer rows.Close()
+
+	var results []Report
 
-	rows, err := dbCl.Query(`
+	for rows.Next() {
+		var r Report
+		err := rows.Scan(
+			&r.ReportID,
+			&r.Timestamp,
+			&r.Content,
+			&r.GenWithApi,
+			&r.GenWithModel,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		results = append(results, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during row iteration: %v", err)
+	}
+
+	return results, nil
+}
+
+// Retrieves all reports from the database with a report_id greater than the specified id.
+// The results are ordered by timestamp in descending order.
+//
+// Parameters:
+//   - id: The report_id threshold
+func (s *SQLiteStore) GetReportsNewerThan(id int) ([]Report, error) {
+	rows, err := s.db.Query(`
 		SELECT * FROM dailyreports
 		WHERE 
 			report_id > (?)
@@ -127,14 +148,8 @@ func GetReportsNewerThan(id int) ([]Report, error) {
 // Parameters:
 //   - id: The report_id threshold
 //   - limit: The maximum number of reports to retrieve
-func GetReportsOlderThan(id int, limit int) ([]Report, error) {
-	dbCl, err := CreateConnection()
-	if err != nil {
-		fmt.Printf("Could not create DB connection: %v\n", err.Error())
-	}
-	defer dbCl.Close()
-
-	rows, err := dbCl.Query(`
+func (s *SQLiteStore) GetReportsOlderThan(id int, limit int) ([]Report, error) {
+	rows, err := s.db.Query(`
 		SELECT * FROM dailyreports
 		WHERE 
 			report_id < (?)
@@ -172,14 +187,8 @@ func GetReportsOlderThan(id int, limit int) ([]Report, error) {
 //
 // Parameters:
 //   - limit: The maximum number of reports to retrieve
-func GetReports(limit int) ([]Report, error) {
-	dbCl, err := CreateConnection()
-	if err != nil {
-		return nil, err
-	}
-	defer dbCl.Close()
-
-	rows, err := dbCl.Query(`
+func (s *SQLiteStore) GetReports(limit int) ([]Report, error) {
+	rows, err := s.db.Query(`
 	SELECT * FROM dailyreports
 	ORDER BY 
 		timestamp DESC
@@ -188,6 +197,7 @@ func GetReports(limit int) ([]Report, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	var results []Report
 
@@ -213,14 +223,8 @@ func GetReports(limit int) ([]Report, error) {
 //
 // Parameters:
 //   - id: The ID of the report to retrieve
-func GetReportById(id int) (*Report, error) {
-	dbCl, err := CreateConnection()
-	if err != nil {
-		return nil, err
-	}
-	defer dbCl.Close()
-
-	rows, err := dbCl.Query(`
+func (s *SQLiteStore) GetReportById(id int) (*Report, error) {
+	rows, err := s.db.Query(`
 	SELECT * FROM dailyreports
 	WHERE report_id = ?`, id)
 
@@ -256,13 +260,7 @@ func GetReportById(id int) (*Report, error) {
 //
 // Parameters:
 //   - ids []int - A slice of report IDs to be deleted
-func DeleteReportsById(ids []int) error {
-	dbCl, err := CreateConnection()
-	if err != nil {
-		return fmt.Errorf("could not connect to DB: %v", err)
-	}
-	defer dbCl.Close()
-
+func (s *SQLiteStore) DeleteReportsById(ids []int) error {
 	questionMarks := generateNumOfQuestionMarks(len(ids))
 	deleteQuery := fmt.Sprintf(`
 		DELETE FROM dailyreports
@@ -275,10 +273,10 @@ func DeleteReportsById(ids []int) error {
 		args[i] = id
 	}
 
-	_, err = dbCl.Exec(deleteQuery, args...)
+	_, err := s.db.Exec(deleteQuery, args...)
 	if err != nil {
 		return fmt.Errorf("error deleting reports: %v", err)
 	}
 
-	return nil
+	return deleteEmbeddings(s.db, EmbeddingSourceReport, ids)
 }
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Identifies a request whose response is cached. A cached response is only reused for a request
+// with the same key
+type ResponseCacheKey struct {
+	ImageHash  string // SHA-256 of the screenshots sent, empty for text-only requests
+	PromptHash string // SHA-256 of the prompt
+	API        string
+	Model      string
+	Params     string // Generation parameters and other settings that change the response
+}
+
+// Returns the cached response for a request, if there is one younger than maxAge
+func (s *SQLiteStore) GetCachedResponse(key ResponseCacheKey, maxAge time.Duration) (string, bool, error) {
+	var response string
+	err := s.db.QueryRow(`
+		SELECT response FROM response_cache
+		WHERE image_hash = ? AND prompt_hash = ? AND api = ? AND model = ? AND params = ? AND created_at >= ?`,
+		key.ImageHash, key.PromptHash, key.API, key.Model, key.Params, time.Now().Add(-maxAge).Unix()).Scan(&response)
+
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("error reading cached response: %v", err)
+	}
+	return response, true, nil
+}
+
+// Saves the response to a request, replacing the one cached before
+func (s *SQLiteStore) SaveCachedResponse(key ResponseCacheKey, response string) error {
+	_, err := s.db.Exec(`
+		INSERT OR REPLACE INTO response_cache (image_hash, prompt_hash, api, model, params, response, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		key.ImageHash, key.PromptHash, key.API, key.Model, key.Params, response, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("error saving cached response: %v", err)
+	}
+	return nil
+}
+
+// Deletes cached responses older than maxAge, then the oldest ones until the responses left take up
+// no more than maxBytes. Returns the number of responses deleted
+func (s *SQLiteStore) PruneResponseCache(maxAge time.Duration, maxBytes int64) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback() // nolint: errcheck
+
+	res, err := tx.Exec("DELETE FROM response_cache WHERE created_at < ?", time.Now().Add(-maxAge).Unix())
+	if err != nil {
+		return 0, fmt.Errorf("error deleting expired responses: %v", err)
+	}
+	expired, _ := res.RowsAffected()
+
+	// Responses are added up from the newest, and the ones that take the total above maxBytes are deleted
+	res, err = tx.Exec(`
+		DELETE FROM response_cache WHERE rowid IN (
+			SELECT rowid FROM (
+				SELECT rowid, SUM(LENGTH(response)) OVER (ORDER BY created_at DESC, rowid DESC) AS total
+				FROM response_cache
+			)
+			WHERE total > ?
+		)`, maxBytes)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting cached responses above the size limit: %v", err)
+	}
+	overflow, _ := res.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing response cache pruning: %w", err)
+	}
+
+	return expired + overflow, nil
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path"
 	"recap/internal/config"
+	"recap/internal/preprocess"
 	"recap/internal/utils"
 	"time"
 )
@@ -18,8 +19,8 @@ type FullThumbScrPair struct {
 // Inserts a new capture record into the database and associates it with the provided screenshot filenames.
 // It returns the ID of the newly created capture or logs a fatal error if an operation fails.
 // Currently each capture equates to just one screenshot file, but this might change in the future
-func InsertCapture(db *sql.DB, scrFullThumbPairs []FullThumbScrPair) int64 {
-	stmt, err := db.Prepare(`
+func (s *SQLiteStore) InsertCapture(scrFullThumbPairs []FullThumbScrPair) int64 {
+	stmt, err := s.db.Prepare(`
 	INSERT INTO captures(timestamp)
 	VALUES (?)`)
 	if err != nil {
@@ -36,7 +37,7 @@ func InsertCapture(db *sql.DB, scrFullThumbPairs []FullThumbScrPair) int64 {
 	}
 	stmt.Close()
 
-	tx, err := db.Begin()
+	tx, err := s.db.Begin()
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -52,8 +53,8 @@ func InsertCapture(db *sql.DB, scrFullThumbPairs []FullThumbScrPair) int64 {
 
 // Updates the description of a specific screenshot identified by its ID.
 // Returns the result of the update operation or an error if the operation fails
-func UpdateScreenshotDescription(db *sql.DB, screenshot_id int, description string, genWithApi string, genWithModel string) (sql.Result, error) {
-	return db.Exec(`
+func (s *SQLiteStore) UpdateScreenshotDescription(screenshot_id int, description string, genWithApi string, genWithModel string) (sql.Result, error) {
+	return s.db.Exec(`
 	UPDATE screenshots
 	SET description = ?,
 	gen_with_api = ?,
@@ -88,16 +89,14 @@ func insertScreenshots(tx *sql.Tx, scrs []FullThumbScrPair, capt_id int64) {
 	}
 }
 
-// Retrieves all captures from today, including their associated descriptions.
+// Retrieves all captures from today that aren't part of a report yet, including their associated descriptions.
 // It returns a list of CaptureDescription objects or an error if the operation fails.
-// It does so by first getting the UNIX second timestamp equivalent of 12AM today, then filtering
-// rows' timestamp values to be higher than today's 12AM timestamp
-func GetCapturesToday(db *sql.DB) ([]CaptureDescription, error) {
-	now := time.Now().UTC()
-	y, m, d := now.Date()
-	startOfDay := time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix()
+// It does so by first getting the UNIX second timestamp of when today started, in the configured time zone
+// and at the configured start of day, then filtering rows' timestamp values to be at or after it
+func (s *SQLiteStore) GetCapturesToday() ([]CaptureDescription, error) {
+	startOfDay := config.StartOfDay(time.Now()).Unix()
 
-	rows, err := db.Query(`
+	rows, err := s.db.Query(`
 	SELECT 
 		c.capture_id,
 		c.timestamp, 
@@ -107,13 +106,15 @@ func GetCapturesToday(db *sql.DB) ([]CaptureDescription, error) {
 	INNER JOIN 
 		screenshots s ON c.capture_id = s.capt_id
 	WHERE 
-		c.r_id IS NULL & c.timestamp > (?)
+		NOT EXISTS (SELECT 1 FROM report_captures rc WHERE rc.capture_id = c.capture_id)
+		AND c.timestamp >= (?)
 	ORDER BY 
 		c.timestamp ASC
 	`, startOfDay)
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer rows.Close()
 
 	var results []CaptureDescription
 
@@ -133,17 +134,17 @@ func GetCapturesToday(db *sql.DB) ([]CaptureDescription, error) {
 	return results, nil
 }
 
-// Retrieves all screenshots that have not been processed by description generation via a vision model yet.
-// It returns a list of CaptureScreenshot objects or an error if the operation fails
-func GetUnprocessedCaptures(db *sql.DB) ([]CaptureScreenshot, error) {
-	rows, err := db.Query(`
+// Retrieves all screenshots that have not been processed by description generation via a vision model yet,
+// oldest first. It returns a list of CaptureScreenshot objects or an error if the operation fails
+func (s *SQLiteStore) GetUnprocessedCaptures() ([]CaptureScreenshot, error) {
+	rows, err := s.db.Query(`
 	SELECT 
 		c.capture_id, 
 		c.timestamp, 
 		s.screenshot_id, 
 		s.filename, 
 		s.description,
-		c.r_id
+		(SELECT MAX(rc.report_id) FROM report_captures rc WHERE rc.capture_id = c.capture_id)
 	FROM 
 		captures c
 	INNER JOIN 
@@ -151,11 +152,12 @@ func GetUnprocessedCaptures(db *sql.DB) ([]CaptureScreenshot, error) {
 	WHERE 
 		s.description IS NULL
 	ORDER BY 
-		c.timestamp DESC
+		c.timestamp ASC
 	`)
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer rows.Close()
 
 	var results []CaptureScreenshot
 
@@ -178,19 +180,61 @@ func GetUnprocessedCaptures(db *sql.DB) ([]CaptureScreenshot, error) {
 	return results, nil
 }
 
+// Retrieves the descriptions of up to limit described captures taken before the given UNIX second timestamp.
+// Results are ordered oldest first, so the last element is the capture closest to the timestamp
+func (s *SQLiteStore) GetDescriptionsBefore(timestamp int64, limit int) ([]CaptureDescription, error) {
+	rows, err := s.db.Query(`
+	SELECT * FROM (
+		SELECT 
+			c.capture_id,
+			c.timestamp, 
+			s.description
+		FROM 
+			captures c
+		INNER JOIN 
+			screenshots s ON c.capture_id = s.capt_id
+		WHERE 
+			s.description IS NOT NULL AND c.timestamp < (?)
+		ORDER BY 
+			c.timestamp DESC
+		LIMIT ?
+	)
+	ORDER BY timestamp ASC
+	`, timestamp, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	var results []CaptureDescription
+
+	for rows.Next() {
+		var cd CaptureDescription
+		err := rows.Scan(
+			&cd.CaptureID,
+			&cd.Timestamp,
+			&cd.Description,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		results = append(results, cd)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during row iteration: %v", err)
+	}
+
+	return results, nil
+}
+
 // Retrieves screenshots with capture IDs greater than the specified ID.
 // It returns a slice of CaptureScreenshotImage and an error if any occurs during the process.
 //
 // Parameters:
 //   - id: An integer representing the capture ID to compare against
-func GetScreenshotsNewerThan(id int) ([]CaptureScreenshotImage, error) {
-	dbCl, err := CreateConnection()
-	if err != nil {
-		fmt.Printf("Could not create DB connection: %v\n", err.Error())
-	}
-	defer dbCl.Close()
-
-	rows, err := dbCl.Query(`
+func (s *SQLiteStore) GetScreenshotsNewerThan(id int) ([]CaptureScreenshotImage, error) {
+	rows, err := s.db.Query(`
 		SELECT 
 			c.capture_id,
 			c.timestamp, 
@@ -199,7 +243,7 @@ func GetScreenshotsNewerThan(id int) ([]CaptureScreenshotImage, error) {
 			s.thumbname,
 			s.gen_with_api,
 			s.gen_with_model,
-			c.r_id
+			(SELECT MAX(rc.report_id) FROM report_captures rc WHERE rc.capture_id = c.capture_id)
 		FROM 
 			captures c
 		INNER JOIN 
@@ -247,20 +291,77 @@ func GetScreenshotsNewerThan(id int) ([]CaptureScreenshotImage, error) {
 	return results, nil
 }
 
+// Retrieves the screenshots a report was generated from, oldest first.
+// It returns a slice of CaptureScreenshotImage with thumbnails, and an error if any occurs during the process.
+//
+// Parameters:
+//   - reportId: The report_id of the report
+func (s *SQLiteStore) GetScreenshotsForReport(reportId int) ([]CaptureScreenshotImage, error) {
+	rows, err := s.db.Query(`
+		SELECT 
+			c.capture_id,
+			c.timestamp, 
+			s.description,
+			s.filename,
+			s.thumbname,
+			s.gen_with_api,
+			s.gen_with_model,
+			(SELECT MAX(rc.report_id) FROM report_captures rc WHERE rc.capture_id = c.capture_id)
+		FROM 
+			captures c
+		INNER JOIN 
+			screenshots s ON c.capture_id = s.capt_id
+		INNER JOIN
+			report_captures r ON c.capture_id = r.capture_id
+		WHERE 
+			r.report_id = ?
+		ORDER BY 
+			c.timestamp ASC
+	`, reportId)
+
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	var results []CaptureScreenshotImage
+
+	for rows.Next() {
+		var cs CaptureScreenshotImage
+		if err := rows.Scan(
+			&cs.CaptureID,
+			&cs.Timestamp,
+			&cs.Description,
+			&cs.Filename,
+			&cs.Thumbname,
+			&cs.GenWithApi,
+			&cs.GenWithModel,
+			&cs.ReportID,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		results = append(results, cs)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during row iteration: %v", err)
+	}
+
+	for i := range results {
+		results[i].Screenshot = utils.ReadImageToBase64PreferThumb(results[i].Filename, results[i].Thumbname)
+	}
+
+	return results, nil
+}
+
 // Retrieves screenshots with capture IDs less than the specified ID, limited by the specified number.
 // It returns a slice of CaptureScreenshotImage and an error if any occurs during the process.
 //
 // Parameters:
 //   - id: An integer representing the capture ID to compare against
 //   - limit: An integer representing the maximum number of results to return
-func GetScreenshotsOlderThan(id int, limit int) ([]CaptureScreenshotImage, error) {
-	dbCl, err := CreateConnection()
-	if err != nil {
-		return nil, fmt.Errorf("could not create DB connection: %v", err)
-	}
-	defer dbCl.Close()
-
-	rows, err := dbCl.Query(`
+func (s *SQLiteStore) GetScreenshotsOlderThan(id int, limit int) ([]CaptureScreenshotImage, error) {
+	rows, err := s.db.Query(`
         SELECT 
             c.capture_id,
             c.timestamp, 
@@ -269,7 +370,7 @@ func GetScreenshotsOlderThan(id int, limit int) ([]CaptureScreenshotImage, error
             s.thumbname,
             s.gen_with_api,
             s.gen_with_model,
-            c.r_id
+            (SELECT MAX(rc.report_id) FROM report_captures rc WHERE rc.capture_id = c.capture_id)
         FROM 
             captures c
         INNER JOIN 
@@ -329,7 +430,7 @@ func getLastScreenshots(db *sql.DB, limit int) ([]CaptureScreenshotImage, error)
 			s.thumbname,
 			s.gen_with_api,
 			s.gen_with_model,
-			c.r_id
+			(SELECT MAX(rc.report_id) FROM report_captures rc WHERE rc.capture_id = c.capture_id)
 		FROM 
 			captures c
 		INNER JOIN 
@@ -373,14 +474,8 @@ func getLastScreenshots(db *sql.DB, limit int) ([]CaptureScreenshotImage, error)
 }
 
 // Reads images' thumbnails
-func GetScreenshots(limit int) ([]CaptureScreenshotImage, error) {
-	dbCl, err := CreateConnection()
-	if err != nil {
-		return nil, fmt.Errorf("could not connect to DB: %v", err)
-	}
-	defer dbCl.Close()
-
-	screenshots, err := getLastScreenshots(dbCl, limit)
+func (s *SQLiteStore) GetScreenshots(limit int) ([]CaptureScreenshotImage, error) {
+	screenshots, err := getLastScreenshots(s.db, limit)
 	if err != nil {
 		return nil, fmt.Errorf("could not get screenshots: %v", err)
 	}
@@ -395,14 +490,8 @@ func GetScreenshots(limit int) ([]CaptureScreenshotImage, error) {
 }
 
 // Reads the full image
-func GetScreenshotById(id int) (*CaptureScreenshotImage, error) {
-	dbCl, err := CreateConnection()
-	if err != nil {
-		return nil, fmt.Errorf("could not connect to DB: %v", err)
-	}
-	defer dbCl.Close()
-
-	rows, err := dbCl.Query(`
+func (s *SQLiteStore) GetScreenshotById(id int) (*CaptureScreenshotImage, error) {
+	rows, err := s.db.Query(`
 		SELECT 
 			c.capture_id,
 			c.timestamp, 
@@ -411,7 +500,7 @@ func GetScreenshotById(id int) (*CaptureScreenshotImage, error) {
 			s.thumbname,
 			s.gen_with_api,
 			s.gen_with_model,
-			c.r_id
+			(SELECT MAX(rc.report_id) FROM report_captures rc WHERE rc.capture_id = c.capture_id)
 		FROM 
 			captures c
 		INNER JOIN 
@@ -455,15 +544,7 @@ func GetScreenshotById(id int) (*CaptureScreenshotImage, error) {
 	return &cs, nil
 }
 
-func GetScreenshotByIds(db *sql.DB, ids []int) ([]CaptureScreenshot, error) {
-	if db == nil {
-		db, err := CreateConnection()
-		if err != nil {
-			return nil, fmt.Errorf("could not connect to DB: %v", err)
-		}
-		defer db.Close()
-	}
-
+func (s *SQLiteStore) GetScreenshotByIds(ids []int) ([]CaptureScreenshot, error) {
 	questionMarks := generateNumOfQuestionMarks(len(ids))
 	preparedQuery := fmt.Sprintf(`
 		SELECT 
@@ -472,7 +553,7 @@ func GetScreenshotByIds(db *sql.DB, ids []int) ([]CaptureScreenshot, error) {
 			s.description,
 			s.filename,
 			s.thumbname,
-			c.r_id
+			(SELECT MAX(rc.report_id) FROM report_captures rc WHERE rc.capture_id = c.capture_id)
 		FROM 
 			captures c
 		INNER JOIN 
@@ -488,7 +569,7 @@ func GetScreenshotByIds(db *sql.DB, ids []int) ([]CaptureScreenshot, error) {
 		args[i] = id
 	}
 
-	rows, err := db.Query(preparedQuery, args...)
+	rows, err := s.db.Query(preparedQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("error executing query: %v", err)
 	}
@@ -520,14 +601,8 @@ func GetScreenshotByIds(db *sql.DB, ids []int) ([]CaptureScreenshot, error) {
 	return results, nil
 }
 
-func DeleteScreenshotsById(ids []int) error {
-	dbCl, err := CreateConnection()
-	if err != nil {
-		return fmt.Errorf("could not connect to DB: %v", err)
-	}
-	defer dbCl.Close()
-
-	scrs, err := GetScreenshotByIds(dbCl, ids)
+func (s *SQLiteStore) DeleteScreenshotsById(ids []int) error {
+	scrs, err := s.GetScreenshotByIds(ids)
 	if err != nil {
 		return err
 	}
@@ -535,6 +610,7 @@ func DeleteScreenshotsById(ids []int) error {
 	for _, scr := range scrs {
 		if scr.Filename != "" {
 			os.Remove(path.Join(config.Config.ScrPath, scr.Filename))
+			preprocess.RemoveCached(scr.Filename)
 		}
 
 		if scr.Thumbname != nil {
@@ -559,15 +635,15 @@ func DeleteScreenshotsById(ids []int) error {
 		args[i] = id
 	}
 
-	_, err = dbCl.Exec(screenshotsQuery, args...)
+	_, err = s.db.Exec(screenshotsQuery, args...)
 	if err != nil {
 		return fmt.Errorf("error deleting screenshots: %v", err)
 	}
 
-	_, err = dbCl.Exec(capturesQuery, args...)
+	_, err = s.db.Exec(capturesQuery, args...)
 	if err != nil {
 		return fmt.Errorf("error deleting captures: %v", err)
 	}
 
-	return nil
+	return deleteEmbeddings(s.db, EmbeddingSourceCapture, ids)
 }
@@ -0,0 +1,288 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Escapes LIKE wildcards in a search term so it's matched literally
+func escapeLikeTerm(term string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + replacer.Replace(term) + "%"
+}
+
+// Counts how many of the given terms appear in text, ignoring case
+func countMatchingTerms(text string, terms []string) int {
+	lower := strings.ToLower(text)
+	count := 0
+
+	for _, term := range terms {
+		if strings.Contains(lower, strings.ToLower(term)) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Builds the start of an ORDER BY clause putting rows that contain more of the terms in column first,
+// for ranking LIKE matches by relevance. Returns an empty clause if no terms are given
+func buildMatchOrder(column string, terms []string) (string, []interface{}) {
+	if len(terms) == 0 {
+		return "", nil
+	}
+
+	var cases []string
+	var args []interface{}
+
+	for _, term := range terms {
+		cases = append(cases, fmt.Sprintf(`(CASE WHEN %s LIKE ? ESCAPE '\' THEN 1 ELSE 0 END)`, column))
+		args = append(args, escapeLikeTerm(term))
+	}
+
+	return "(" + strings.Join(cases, " + ") + ") DESC,", args
+}
+
+// Builds the WHERE clause shared by the description and report searches. Rows must contain at least
+// one of the terms if any are given, and must fall within the from and to UNIX second timestamps.
+// A from or to value of 0 leaves that end of the range open
+func buildSearchFilter(column string, timestampColumn string, terms []string, from int64, to int64) (string, []interface{}) {
+	var clauses []string
+	var args []interface{}
+
+	if len(terms) > 0 {
+		var termClauses []string
+		for _, term := range terms {
+			termClauses = append(termClauses, fmt.Sprintf(`%s LIKE ? ESCAPE '\'`, column))
+			args = append(args, escapeLikeTerm(term))
+		}
+		clauses = append(clauses, "("+strings.Join(termClauses, " OR ")+")")
+	}
+
+	if from > 0 {
+		clauses = append(clauses, timestampColumn+" >= ?")
+		args = append(args, from)
+	}
+
+	if to > 0 {
+		clauses = append(clauses, timestampColumn+" < ?")
+		args = append(args, to)
+	}
+
+	if len(clauses) == 0 {
+		return "", args
+	}
+
+	return "AND " + strings.Join(clauses, " AND "), args
+}
+
+// Retrieves up to limit screenshot descriptions that contain any of the given terms and were captured
+// between the from and to UNIX second timestamps. A from or to value of 0 leaves that end of the range open.
+// Results are ordered by full-text relevance if FTS5 is available, or by the number of matching terms
+// and then by recency otherwise. If no terms are given, the most recent descriptions in the range are returned
+func (s *SQLiteStore) SearchDescriptions(terms []string, from int64, to int64, limit int) ([]CaptureDescription, error) {
+	if ftsAvailable && len(terms) > 0 {
+		return searchDescriptionsByTerms(s.db, terms, from, to, limit)
+	}
+
+	filter, args := buildSearchFilter("s.description", "c.timestamp", terms, from, to)
+	order, orderArgs := buildMatchOrder("s.description", terms)
+	args = append(append(args, orderArgs...), limit)
+
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT
+			c.capture_id,
+			c.timestamp,
+			s.description
+		FROM
+			captures c
+		INNER JOIN
+			screenshots s ON c.capture_id = s.capt_id
+		WHERE
+			s.description IS NOT NULL %s
+		ORDER BY
+			%s c.timestamp DESC
+		LIMIT ?
+	`, filter, order), args...)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	var results []CaptureDescription
+
+	for rows.Next() {
+		var cd CaptureDescription
+		err := rows.Scan(
+			&cd.CaptureID,
+			&cd.Timestamp,
+			&cd.Description,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		results = append(results, cd)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during row iteration: %v", err)
+	}
+
+	return results, nil
+}
+
+// Retrieves up to limit reports that contain any of the given terms and were generated between the
+// from and to UNIX second timestamps. A from or to value of 0 leaves that end of the range open.
+// Results are ordered by full-text relevance if FTS5 is available, or by the number of matching terms
+// and then by recency otherwise. If no terms are given, the most recent reports in the range are returned
+func (s *SQLiteStore) SearchReports(terms []string, from int64, to int64, limit int) ([]Report, error) {
+	if ftsAvailable && len(terms) > 0 {
+		return searchReportsByTerms(s.db, terms, from, to, limit)
+	}
+
+	filter, args := buildSearchFilter("content", "timestamp", terms, from, to)
+	order, orderArgs := buildMatchOrder("content", terms)
+	args = append(append(args, orderArgs...), limit)
+
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT * FROM dailyreports
+		WHERE
+			content IS NOT NULL %s
+		ORDER BY
+			%s timestamp DESC
+		LIMIT ?
+	`, filter, order), args...)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	var results []Report
+
+	for rows.Next() {
+		var r Report
+		err := rows.Scan(
+			&r.ReportID,
+			&r.Timestamp,
+			&r.Content,
+			&r.GenWithApi,
+			&r.GenWithModel,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		results = append(results, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during row iteration: %v", err)
+	}
+
+	return results, nil
+}
+
+// Converts search terms to tokens of an FTS5 OR query, matching any of the terms as prefixes
+func termsToTokens(terms []string) []queryToken {
+	tokens := make([]queryToken, len(terms))
+	for i, term := range terms {
+		tokens[i] = queryToken{Text: term, Prefix: true}
+	}
+	return tokens
+}
+
+// Full-text variant of SearchDescriptions. Descriptions matching any of the terms are ranked with bm25
+func searchDescriptionsByTerms(db *sql.DB, terms []string, from int64, to int64, limit int) ([]CaptureDescription, error) {
+	rows, err := db.Query(`
+		SELECT
+			c.capture_id,
+			c.timestamp,
+			s.description
+		FROM
+			screenshots_fts
+		INNER JOIN
+			screenshots s ON s.screenshot_id = screenshots_fts.rowid
+		INNER JOIN
+			captures c ON c.capture_id = s.capt_id
+		WHERE
+			screenshots_fts MATCH ?
+			AND (? = 0 OR c.timestamp >= ?) AND (? = 0 OR c.timestamp < ?)
+		ORDER BY
+			bm25(screenshots_fts)
+		LIMIT ?
+	`, buildFTSQuery(termsToTokens(terms), "OR"), from, from, to, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	var results []CaptureDescription
+
+	for rows.Next() {
+		var cd CaptureDescription
+		err := rows.Scan(
+			&cd.CaptureID,
+			&cd.Timestamp,
+			&cd.Description,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		results = append(results, cd)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during row iteration: %v", err)
+	}
+
+	return results, nil
+}
+
+// Full-text variant of SearchReports. Reports matching any of the terms are ranked with bm25
+func searchReportsByTerms(db *sql.DB, terms []string, from int64, to int64, limit int) ([]Report, error) {
+	rows, err := db.Query(`
+		SELECT
+			r.report_id,
+			r.timestamp,
+			r.content,
+			r.gen_with_api,
+			r.gen_with_model
+		FROM
+			dailyreports_fts
+		INNER JOIN
+			dailyreports r ON r.report_id = dailyreports_fts.rowid
+		WHERE
+			dailyreports_fts MATCH ?
+			AND (? = 0 OR r.timestamp >= ?) AND (? = 0 OR r.timestamp < ?)
+		ORDER BY
+			bm25(dailyreports_fts)
+		LIMIT ?
+	`, buildFTSQuery(termsToTokens(terms), "OR"), from, from, to, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	var results []Report
+
+	for rows.Next() {
+		var r Report
+		err := rows.Scan(
+			&r.ReportID,
+			&r.Timestamp,
+			&r.Content,
+			&r.GenWithApi,
+			&r.GenWithModel,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		results = append(results, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during row iteration: %v", err)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,272 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+// Saves a capture taken at timestamp with a screenshot described by description. Returns the
+// capture's ID, which is also its screenshot's ID
+func insertDescribedCapture(t *testing.T, store *SQLiteStore, timestamp int64, description string) int {
+	t.Helper()
+
+	res, err := store.db.Exec("INSERT INTO captures (timestamp) VALUES (?)", timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.db.Exec("INSERT INTO screenshots (screenshot_id, capt_id, filename, description) VALUES (?, ?, '', ?)", id, id, description); err != nil {
+		t.Fatal(err)
+	}
+	return int(id)
+}
+
+// Saves a report generated at timestamp. Returns its ID
+func insertReport(t *testing.T, store *SQLiteStore, timestamp int64, content string) int {
+	t.Helper()
+
+	res, err := store.db.Exec("INSERT INTO dailyreports (timestamp, content, gen_with_api, gen_with_model) VALUES (?, ?, 'Mock', 'mock-text')", timestamp, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return int(id)
+}
+
+// Makes searches use LIKE queries for the rest of the test, even if SQLite was built with FTS5
+func useLikeSearch(t *testing.T) {
+	t.Helper()
+
+	available := ftsAvailable
+	ftsAvailable = false
+	t.Cleanup(func() { ftsAvailable = available })
+}
+
+// Skips the test unless SQLite was built with FTS5, which requires the sqlite_fts5 tag
+func requireFTS(t *testing.T) {
+	t.Helper()
+
+	if !ftsAvailable {
+		t.Skip("SQLite was built without FTS5, run with -tags sqlite_fts5 to test full-text search")
+	}
+}
+
+func searchIDs(t *testing.T, store *SQLiteStore, query string, filters SearchFilters) []int {
+	t.Helper()
+
+	results, err := store.Search(query, filters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]int, len(results))
+	for i, r := range results {
+		ids[i] = r.ID
+	}
+	return ids
+}
+
+func equalIDs(a []int, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestLikeSearchRanksByMatchingTermsThenRecency(t *testing.T) {
+	store := openTestStore(t)
+	useLikeSearch(t)
+
+	insertDescribedCapture(t, store, 50, "Battery at 100 percent")
+	both := insertDescribedCapture(t, store, 100, "Writing the database migration in Vim")
+	older := insertDescribedCapture(t, store, 200, "Installing Vim plugins")
+	video := insertDescribedCapture(t, store, 300, "Watching a video")
+	newer := insertDescribedCapture(t, store, 400, "Back in vim")
+
+	tests := []struct {
+		terms    []string
+		from, to int64
+		limit    int
+		want     []int
+	}{
+		{[]string{"migration", "vim"}, 0, 0, 10, []int{both, newer, older}},
+		{[]string{"migration", "vim"}, 0, 0, 2, []int{both, newer}},
+		{[]string{"vim"}, 150, 400, 10, []int{older}},
+		{[]string{"100%"}, 0, 0, 10, nil},
+		{nil, 0, 0, 2, []int{newer, video}},
+	}
+
+	for _, test := range tests {
+		descs, err := store.SearchDescriptions(test.terms, test.from, test.to, test.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int
+		for _, desc := range descs {
+			got = append(got, desc.CaptureID)
+		}
+		if !equalIDs(got, test.want) {
+			t.Errorf("SearchDescriptions(%q, %d, %d, %d) = %v, expected %v", test.terms, test.from, test.to, test.limit, got, test.want)
+		}
+	}
+}
+
+func TestLikeSearchOfReports(t *testing.T) {
+	store := openTestStore(t)
+	useLikeSearch(t)
+
+	both := insertReport(t, store, 100, "Reviewed the release notes and the changelog")
+	insertReport(t, store, 200, "Meetings all day")
+	one := insertReport(t, store, 300, "Updated the changelog")
+
+	reports, err := store.SearchReports([]string{"release", "changelog"}, 0, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 || reports[0].ReportID != both {
+		t.Errorf("expected only the report matching both terms, got %+v", reports)
+	}
+
+	if got := searchIDs(t, store, "changelog", SearchFilters{Kinds: []string{SearchKindReport}}); !equalIDs(got, []int{one, both}) {
+		t.Errorf("expected the newest matching report first, got %v", got)
+	}
+}
+
+func TestLikeSearchRequiresEveryWord(t *testing.T) {
+	store := openTestStore(t)
+	useLikeSearch(t)
+
+	both := insertDescribedCapture(t, store, 100, "Writing the database migration in Vim")
+	insertDescribedCapture(t, store, 200, "Installing Vim plugins")
+
+	results, err := store.Search("migration vim", SearchFilters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ID != both {
+		t.Fatalf("expected only the capture matching both words, got %+v", results)
+	}
+	if !strings.Contains(results[0].Snippet, "**migration**") || !strings.Contains(results[0].Snippet, "**Vim**") {
+		t.Errorf("expected the matches to be highlighted, got %q", results[0].Snippet)
+	}
+}
+
+func TestFullTextSearchRanksWithBM25(t *testing.T) {
+	store := openTestStore(t)
+	requireFTS(t)
+
+	padding := strings.Repeat("browsing unrelated pages and reading email ", 20)
+	passing := insertDescribedCapture(t, store, 300, "Looked at a postgres dashboard while "+padding)
+	focused := insertDescribedCapture(t, store, 100, "Tuning postgres indexes, postgres query plans")
+	report := insertReport(t, store, 200, "Spent the afternoon on postgres")
+
+	results, err := store.Search("postgres", SearchFilters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %+v", results)
+	}
+	for i := 1; i < len(results); i++ {
+		if results[i].Rank < results[i-1].Rank {
+			t.Errorf("expected results ordered by bm25, got %+v", results)
+		}
+	}
+
+	captures, err := store.Search("postgres", SearchFilters{Kinds: []string{SearchKindCapture}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(captures) != 2 || captures[0].ID != focused || captures[1].ID != passing {
+		t.Fatalf("expected the capture mentioning postgres most to rank first, got %+v", captures)
+	}
+	for _, r := range captures {
+		if !strings.Contains(r.Snippet, "**postgres**") {
+			t.Errorf("expected the match to be highlighted in the snippet, got %q", r.Snippet)
+		}
+	}
+	if len(strings.Fields(captures[1].Snippet)) > snippetTokens+1 {
+		t.Errorf("expected the snippet of a long description to be shortened, got %q", captures[1].Snippet)
+	}
+
+	if got := searchIDs(t, store, "postgres", SearchFilters{Kinds: []string{SearchKindReport}}); !equalIDs(got, []int{report}) {
+		t.Errorf("expected only the report, got %v", got)
+	}
+	if got := searchIDs(t, store, "postgres", SearchFilters{From: 150, Kinds: []string{SearchKindCapture}}); !equalIDs(got, []int{passing}) {
+		t.Errorf("expected only the capture in the time range, got %v", got)
+	}
+
+	descs, err := store.SearchDescriptions([]string{"postgres", "index"}, 0, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(descs) != 1 || descs[0].CaptureID != focused {
+		t.Errorf("expected the best match from SearchDescriptions, got %+v", descs)
+	}
+}
+
+func TestFullTextTablesFollowTheirSourceTables(t *testing.T) {
+	store := openTestStore(t)
+	requireFTS(t)
+
+	capture := insertDescribedCapture(t, store, 100, "Editing a spreadsheet")
+	report := insertReport(t, store, 100, "Worked on the budget")
+	if got := searchIDs(t, store, "spreadsheet", SearchFilters{}); !equalIDs(got, []int{capture}) {
+		t.Errorf("expected an inserted description to be found, got %v", got)
+	}
+	if got := searchIDs(t, store, "budget", SearchFilters{}); !equalIDs(got, []int{report}) {
+		t.Errorf("expected an inserted report to be found, got %v", got)
+	}
+
+	if _, err := store.UpdateScreenshotDescription(capture, "Writing a presentation", "Mock", "mock-vision"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.db.Exec("UPDATE dailyreports SET content = ? WHERE report_id = ?", "Worked on the roadmap", report); err != nil {
+		t.Fatal(err)
+	}
+	for query, want := range map[string][]int{"spreadsheet": nil, "presentation": {capture}, "budget": nil, "roadmap": {report}} {
+		if got := searchIDs(t, store, query, SearchFilters{}); !equalIDs(got, want) {
+			t.Errorf("%s: expected %v after updating, got %v", query, want, got)
+		}
+	}
+
+	if err := store.DeleteScreenshotsById([]int{capture}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeleteReportsById([]int{report}); err != nil {
+		t.Fatal(err)
+	}
+	for _, query := range []string{"presentation", "roadmap"} {
+		if got := searchIDs(t, store, query, SearchFilters{}); len(got) != 0 {
+			t.Errorf("%s: expected nothing to be found after deleting, got %v", query, got)
+		}
+	}
+}
+
+func TestFullTextTablesAreRebuiltWithoutTriggers(t *testing.T) {
+	store := openTestStore(t)
+	requireFTS(t)
+
+	// Rows written by a build without FTS5 aren't copied to the full-text tables
+	for _, name := range ftsTriggerNames {
+		if _, err := store.db.Exec("DROP TRIGGER " + name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	capture := insertDescribedCapture(t, store, 100, "Sketching a logo")
+
+	createSearchTables(store.db)
+	if got := searchIDs(t, store, "logo", SearchFilters{}); !equalIDs(got, []int{capture}) {
+		t.Errorf("expected the rebuilt table to include rows written without triggers, got %v", got)
+	}
+}
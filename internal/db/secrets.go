@@ -0,0 +1,99 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"recap/internal/config"
+	"recap/internal/secrets"
+	"reflect"
+)
+
+// Placeholder API keys older versions filled in as defaults. They're dropped rather than migrated
+var oldKeyPlaceholders = map[string]string{
+	"GeminiAPIKey":     "your-gemini-api-key",
+	"OpenAIAPIKey":     "your-openai-api-key",
+	"OpenRouterAPIKey": "your-openrouter-api-key",
+	"AnthropicAPIKey":  "your-anthropic-api-key",
+}
+
+// Moves API keys stored in plain text in the settings table to the secret store, emptying their rows.
+// Keys that can't be saved in the secret store are left in the table, and retried the next time the app starts
+func migrateSecrets(db *sql.DB) error {
+	for _, key := range secrets.Keys {
+		var value string
+		err := db.QueryRow("SELECT value FROM settings WHERE key = ?", key).Scan(&value)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", key, err)
+		}
+		if value == "" {
+			continue
+		}
+
+		if value != oldKeyPlaceholders[key] {
+			if err := secrets.Set(key, value); err != nil {
+				log.Printf("Could not move %s to the secret store, leaving it in the settings table: %v", key, err)
+				continue
+			}
+			log.Printf("Moved %s to the secret store", key)
+		}
+
+		if err := updateSetting(db, key, ""); err != nil {
+			return fmt.Errorf("error clearing %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// Reads the secrets into conf. Values left in the settings table are kept if a secret can't be read
+func loadSecrets(conf *config.AppConfig) {
+	r := reflect.ValueOf(conf).Elem()
+
+	for _, key := range secrets.Keys {
+		value, err := secrets.Get(key)
+		if err != nil {
+			log.Printf("Could not read %s from the secret store: %v", key, err)
+			continue
+		}
+		if value != "" {
+			r.FieldByName(key).SetString(value)
+		}
+	}
+}
+
+// Saves a secret setting to the secret store and the in-memory configuration. The masked value the UI is given
+// is sent back unchanged when the user doesn't edit the key, in which case the secret is kept as it is
+func updateSecret(key string, value string) error {
+	field := reflect.ValueOf(&config.Config).Elem().FieldByName(key)
+	if value == secrets.Mask(field.String()) {
+		return nil
+	}
+
+	if err := secrets.Set(key, value); err != nil {
+		return err
+	}
+
+	field.SetString(value)
+	return nil
+}
+
+// Loads the configuration like LoadConfig, with secrets masked so they aren't sent to the UI
+func (s *SQLiteStore) GetMaskedConfig() (*config.AppConfig, error) {
+	conf, err := s.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	r := reflect.ValueOf(conf).Elem()
+	for _, key := range secrets.Keys {
+		field := r.FieldByName(key)
+		field.SetString(secrets.Mask(field.String()))
+	}
+
+	return conf, nil
+}
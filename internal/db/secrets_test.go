@@ -0,0 +1,122 @@
+package db
+
+import (
+	"recap/internal/config"
+	"recap/internal/secrets"
+	"testing"
+)
+
+// Opens an in-memory store with secrets kept in memory
+func openTestStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+
+	secrets.UseStore(secrets.NewMemoryStore())
+	store, err := OpenMemory()
+	if err != nil {
+		t.Fatalf("could not open in-memory store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return store
+}
+
+func TestMigrateSecretsMovesKeysOutOfSettings(t *testing.T) {
+	store := openTestStore(t)
+
+	plaintext := map[string]string{
+		"OpenAIAPIKey":        "sk-plaintext-openai-key",
+		"CustomOpenAIHeaders": "Authorization: Bearer plaintext-token",
+		"GeminiAPIKey":        oldKeyPlaceholders["GeminiAPIKey"],
+	}
+	for key, value := range plaintext {
+		if err := updateSetting(store.db, key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := migrateSecrets(store.db); err != nil {
+		t.Fatalf("migrateSecrets failed: %v", err)
+	}
+
+	for key, value := range plaintext {
+		var stored string
+		if err := store.db.QueryRow("SELECT value FROM settings WHERE key = ?", key).Scan(&stored); err != nil {
+			t.Fatal(err)
+		}
+		if stored != "" {
+			t.Errorf("%s: expected the settings row to be emptied, got %q", key, stored)
+		}
+
+		secret, err := secrets.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := value
+		if value == oldKeyPlaceholders[key] {
+			want = ""
+		}
+		if secret != want {
+			t.Errorf("%s: expected %q in the secret store, got %q", key, want, secret)
+		}
+	}
+}
+
+func TestMaskedSecretsAreKeptWhenSentBack(t *testing.T) {
+	store := openTestStore(t)
+
+	saved := map[string]string{
+		"OpenAIAPIKey":        "sk-saved-openai-key",
+		"CustomOpenAIHeaders": "Authorization: Bearer saved-token",
+	}
+	if err := store.UpdateSettings(saved); err != nil {
+		t.Fatal(err)
+	}
+
+	masked, err := store.GetMaskedConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if masked.OpenAIAPIKey == saved["OpenAIAPIKey"] || masked.CustomOpenAIHeaders == saved["CustomOpenAIHeaders"] {
+		t.Fatalf("expected secrets to be masked, got %q and %q", masked.OpenAIAPIKey, masked.CustomOpenAIHeaders)
+	}
+
+	// The UI sends the masked values back when the fields aren't edited
+	if err := store.UpdateSettings(map[string]string{
+		"OpenAIAPIKey":        masked.OpenAIAPIKey,
+		"CustomOpenAIHeaders": masked.CustomOpenAIHeaders,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range saved {
+		if secret, _ := secrets.Get(key); secret != value {
+			t.Errorf("%s: expected the saved secret to be kept, got %q", key, secret)
+		}
+	}
+	if config.Config.CustomOpenAIHeaders != saved["CustomOpenAIHeaders"] {
+		t.Errorf("expected the configuration to keep the saved headers, got %q", config.Config.CustomOpenAIHeaders)
+	}
+
+	if err := store.UpdateSettings(map[string]string{"OpenAIAPIKey": "sk-new-openai-key"}); err != nil {
+		t.Fatal(err)
+	}
+	if secret, _ := secrets.Get("OpenAIAPIKey"); secret != "sk-new-openai-key" {
+		t.Errorf("expected an edited key to replace the saved one, got %q", secret)
+	}
+
+	conf, err := store.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.OpenAIAPIKey != "sk-new-openai-key" || conf.CustomOpenAIHeaders != saved["CustomOpenAIHeaders"] {
+		t.Errorf("expected the secrets to be loaded from the secret store, got %q and %q", conf.OpenAIAPIKey, conf.CustomOpenAIHeaders)
+	}
+}
+
+func TestMigrateSecretsReturnsReadErrors(t *testing.T) {
+	store := openTestStore(t)
+	store.Close()
+
+	if err := migrateSecrets(store.db); err == nil {
+		t.Error("expected an error when the settings can't be read")
+	}
+}
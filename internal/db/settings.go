@@ -2,10 +2,13 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"recap/internal/config"
 	"recap/internal/models"
+	"recap/internal/preprocess"
+	"recap/internal/secrets"
 	"reflect"
 	"strconv"
 )
@@ -22,7 +25,7 @@ type SettingDisplayProps struct {
 	Category    string `json:"Category"`
 	InputType   string `json:"InputType"`
 
-	// Contains options for the APIPicker input type
+	// Contains options for the APIPicker input type, and suggestions for the APIModelPicker input type
 	Options *[]string `json:"Options"`
 }
 
@@ -30,43 +33,130 @@ var defaultSettings = map[string]string{
 	"ScrPath":                   "./screenshots",
 	"DescGenAPI":                "Gemini",
 	"DescGenModel":              "gemini-1.5-flash",
+	"DescGenFallback":           "", // Comma-separated API:model entries tried in order when DescGenAPI fails
 	"DescGenPrompt":             "This image was captured on a user's computer. Describe what the user was working on. Do not expose passwords, other people's names, emails, and other private and secure information.",
 	"DescGenIntervalMins":       "120", // Default interval in minutes
 	"DescGenIntervalEnabled":    "1",   // 1 for enabled, 0 for disabled
-	"ScreenshotIntervalMins":    "10",  // Default interval in minutes
-	"ScreenshotIntervalEnabled": "1",   // 1 for enabled, 0 for disabled
+	"DescGenBatchSize":          "1",   // Number of screenshots sent per vision request
+	"DescGenContextCount":       "0",   // Number of previous descriptions included as context, 0 to disable
+	"DescGenTemperature":        "0.2", // Low temperature keeps descriptions factual. Negative values use the provider's default
+	"DescGenTopP":               "-1",  // Negative values use the provider's default
+	"DescGenMaxTokens":          "0",   // 0 uses the provider's default
+	"ImageMaxEdge":              "0",   // Longest side screenshots are downscaled to before they're described, 0 to keep their size
+	"ImageFormat":               "original",
+	"ImageQuality":              "80",
+	"ImageGrayscale":            "0",
+	"ImageCropToActiveDisplay":  "0",
+	"ImageOverrides":            "",   // Per-API overrides, e.g. "Ollama: max_edge=1344, format=jpeg"
+	"ScreenshotIntervalMins":    "10", // Default interval in minutes
+	"ScreenshotIntervalEnabled": "1",  // 1 for enabled, 0 for disabled
 	"ReportAPI":                 "Gemini",
 	"ReportModel":               "gemini-1.5-flash",
+	"ReportFallback":            "", // Comma-separated API:model entries tried in order when ReportAPI fails
 	"ReportAutoEnabled":         "0",
 	"ReportAutoAt":              "17:00", // Default auto report time
 	"ReportPrompt":              "You are an AI assistant tasked with generating a daily activity report for a user based on a series of visual descriptions captured from their computer screen throughout the day. Your job is to summarize this data into brief items describing what the user worked on today.",
+	"ReportTemperature":         "-1",
+	"ReportTopP":                "-1",
+	"ReportMaxTokens":           "0",
+	"Timezone":                  "Local", // IANA time zone dates are worked out in, Local for the system's
+	"DayStartsAt":               "00:00", // Captures before this time count towards the previous day
+	"EmbeddingEnabled":          "0",
+	"EmbeddingAPI":              "Ollama",
+	"EmbeddingModel":            "nomic-embed-text",
+	"ScrubEnabled":              "1",
+	"ScrubDetectors":            "email,aws,github,jwt,creditcard,phone,ip",
+	"ScrubWords":                "", // Comma-separated words and names to redact
 	"OllamaURL":                 "http://localhost:11434",
-	"GeminiAPIKey":              "your-gemini-api-key",
-	"OpenAIAPIKey":              "your-openai-api-key",
-	"OpenRouterAPIKey":          "your-openrouter-api-key",
+	"OllamaKeepAlive":           "5m",   // How long Ollama keeps the model loaded after a request
+	"OllamaNumCtx":              "8192", // Context window in tokens, 0 to use the model's default
+	"GeminiAPIKey":              "",     // API keys are kept in the secret store. Their rows in the settings table stay empty
+	"GeminiInlineImages":        "1",    // 1 to send screenshots inside requests, 0 to upload them with the File API
+	"OpenAIAPIKey":              "",
+	"OpenRouterAPIKey":          "",
+	"AnthropicAPIKey":           "",
+	"CustomOpenAIURL":           "http://localhost:1234/v1", // Base URL of an OpenAI-compatible server, LM Studio's by default
+	"CustomOpenAIAPIKey":        "",                         // Left empty for servers that don't need a key
+	"CustomOpenAIHeaders":       "",                         // Semicolon-separated "Name: value" headers
+	"CustomOpenAIVision":        "1",
+	"HTTPProxy":                 "",    // Empty uses the HTTPS_PROXY and HTTP_PROXY environment variables
+	"HTTPCABundle":              "",    // PEM file with extra trusted certificates
+	"HTTPConnectTimeoutSecs":    "10",  // 0 disables the limit
+	"HTTPResponseTimeoutSecs":   "300", // Local models can take minutes to respond. 0 disables the limit
+	"HTTPOverrides":             "",    // Per-API overrides, e.g. "Ollama: proxy=direct, response_timeout=600"
+	"ResponseCacheEnabled":      "1",   // 1 to reuse responses to identical requests, 0 to always send them
+	"ResponseCacheTTLHours":     "168", // How long cached responses are reused
+	"ResponseCacheMaxMB":        "50",  // Oldest responses are deleted once the cache grows past this size
 }
 
 func GetDisplayValues() map[string]SettingDisplayProps {
 	apiList := models.ListRegisteredAPIs()
+	embeddingAPIList := models.ListRegisteredEmbeddingAPIs()
+
+	// Models offered by the selected APIs. Options are left empty if a provider can't be reached
+	descGenModelList := models.ModelNames(config.Config.DescGenAPI, true)
+	reportModelList := models.ModelNames(config.Config.ReportAPI, false)
+
+	imageFormats := preprocess.Formats
 
 	var settingKeyDisplayVals = map[string]SettingDisplayProps{
 		"ScrPath":                   {DisplayName: "Path", Description: "Specify the directory where screenshots will be saved on your device", Category: "Screenshots", InputType: "FolderPicker"},
 		"DescGenAPI":                {DisplayName: "API", Description: "Select the AI service to use for generating descriptions of your screenshots", Category: "Vision", InputType: "APIPicker", Options: &apiList},
-		"DescGenModel":              {DisplayName: "Model", Description: "Choose the specific AI model for analyzing screenshots and generating descriptions", Category: "Vision", InputType: "APIModelPicker"},
+		"DescGenModel":              {DisplayName: "Model", Description: "Choose the specific AI model for analyzing screenshots and generating descriptions", Category: "Vision", InputType: "APIModelPicker", Options: &descGenModelList},
+		"DescGenFallback":           {DisplayName: "Fallback", Description: "List APIs and models to try, in order, when the selected one is rate limited or unavailable, e.g. OpenRouter:google/gemini-flash-1.5, Ollama:llava. Leave empty to disable", Category: "Vision", InputType: "TextInput"},
 		"DescGenPrompt":             {DisplayName: "Prompt", Description: "Customize the instructions given to the AI when generating screenshot descriptions", Category: "Vision", InputType: "ExtendedTextInput"},
 		"DescGenIntervalEnabled":    {DisplayName: "Schedule", Description: "Toggle automatic description generation after Recap starts", Category: "Vision", InputType: "Boolean"},
 		"DescGenIntervalMins":       {DisplayName: "Interval", Description: "Set how often (in minutes) screenshots should be automatically sent for description generation", Category: "Vision", InputType: "NumberInput"},
+		"DescGenTemperature":        {DisplayName: "Temperature", Description: "Set how much the AI's descriptions vary. Lower values keep them factual and consistent. Gemini, OpenAI and Ollama accept 0 to 2, Anthropic 0 to 1. A negative value uses the provider's default", Category: "Vision", InputType: "NumberInput"},
+		"DescGenTopP":               {DisplayName: "Top P", Description: "Limit the words the AI picks from to the most likely ones making up this share of probability, from 0 to 1. A negative value uses the provider's default", Category: "Vision", InputType: "NumberInput"},
+		"DescGenMaxTokens":          {DisplayName: "Max tokens", Description: "Set the maximum length of each response in tokens. 0 uses the provider's default", Category: "Vision", InputType: "NumberInput"},
+		"ImageMaxEdge":              {DisplayName: "Image size", Description: "Downscale screenshots so their longest side is at most this many pixels before they're described, which makes requests smaller and uses fewer tokens. 0 sends them at full size", Category: "Vision", InputType: "NumberInput"},
+		"ImageFormat":               {DisplayName: "Image format", Description: "Convert screenshots to JPEG or WebP before they're described. Original sends them as they were saved", Category: "Vision", InputType: "APIPicker", Options: &imageFormats},
+		"ImageQuality":              {DisplayName: "Image quality", Description: "Set the quality of screenshots converted to JPEG or WebP, from 1 to 100", Category: "Vision", InputType: "NumberInput"},
+		"ImageGrayscale":            {DisplayName: "Grayscale images", Description: "Convert screenshots to grayscale before they're described", Category: "Vision", InputType: "Boolean"},
+		"ImageCropToActiveDisplay":  {DisplayName: "Crop to active display", Description: "Only send the display the mouse pointer was on when a screenshot was taken, if you use more than one. Not supported on macOS", Category: "Vision", InputType: "Boolean"},
+		"ImageOverrides":            {DisplayName: "Per-provider image settings", Description: "Override the image settings for specific APIs, as API: key=value entries separated by semicolons, e.g. Ollama: max_edge=1344, format=jpeg; Gemini: max_edge=3072. Keys are max_edge, format, quality, grayscale and crop", Category: "Vision", InputType: "TextInput"},
+		"DescGenBatchSize":          {DisplayName: "Batch size", Description: "Set how many consecutive screenshots are sent to the AI in a single request. Higher values use fewer requests per day; 1 sends each screenshot on its own", Category: "Vision", InputType: "NumberInput"},
+		"DescGenContextCount":       {DisplayName: "Context", Description: "Set how many previous descriptions are included when describing a new screenshot, helping the AI tell ongoing work apart from a switch to something new. 0 disables this", Category: "Vision", InputType: "NumberInput"},
 		"ScreenshotIntervalEnabled": {DisplayName: "Schedule", Description: "Toggle automatic screenshot capturing at regular intervals after Recap starts", Category: "Screenshots", InputType: "Boolean"},
 		"ScreenshotIntervalMins":    {DisplayName: "Interval", Description: "Define how frequently (in minutes) automatic screenshots should be taken", Category: "Screenshots", InputType: "NumberInput"},
 		"ReportAPI":                 {DisplayName: "API", Description: "Choose the AI service for generating reports based on your screenshot descriptions", Category: "Reports", InputType: "APIPicker", Options: &apiList},
-		"ReportModel":               {DisplayName: "Model", Description: "Select the specific AI model for creating reports from your screenshot descriptions", Category: "Reports", InputType: "APIModelPicker"},
+		"ReportModel":               {DisplayName: "Model", Description: "Select the specific AI model for creating reports from your screenshot descriptions", Category: "Reports", InputType: "APIModelPicker", Options: &reportModelList},
+		"ReportFallback":            {DisplayName: "Fallback", Description: "List APIs and models to try, in order, when the selected one is rate limited or unavailable, e.g. OpenRouter:google/gemini-flash-1.5, Ollama:llama3.1. Leave empty to disable", Category: "Reports", InputType: "TextInput"},
 		"ReportAutoEnabled":         {DisplayName: "Schedule", Description: "Enable or disable automatic daily report generation", Category: "Reports", InputType: "Boolean"},
 		"ReportAutoAt":              {DisplayName: "Time", Description: "Set the specific time each day when an automatic report should be generated", Category: "Reports", InputType: "TimePicker"},
 		"ReportPrompt":              {DisplayName: "Prompt", Description: "Customize the instructions given to the AI when generating reports from your screenshot descriptions", Category: "Reports", InputType: "ExtendedTextInput"},
+		"ReportTemperature":         {DisplayName: "Temperature", Description: "Set how much generated reports vary. Gemini, OpenAI and Ollama accept 0 to 2, Anthropic 0 to 1. A negative value uses the provider's default", Category: "Reports", InputType: "NumberInput"},
+		"ReportTopP":                {DisplayName: "Top P", Description: "Limit the words the AI picks from to the most likely ones making up this share of probability, from 0 to 1. A negative value uses the provider's default", Category: "Reports", InputType: "NumberInput"},
+		"ReportMaxTokens":           {DisplayName: "Max tokens", Description: "Set the maximum length of reports in tokens. Raise it if reports are cut off. 0 uses the provider's default", Category: "Reports", InputType: "NumberInput"},
+		"Timezone":                  {DisplayName: "Time zone", Description: "Set the time zone days are worked out in, as a name such as Europe/Berlin or America/New_York. Local uses your system's time zone", Category: "Reports", InputType: "TextInput"},
+		"DayStartsAt":               {DisplayName: "Day starts at", Description: "Set the time a new day starts. Screenshots taken before it count towards the previous day, so work past midnight ends up in that day's report", Category: "Reports", InputType: "TimePicker"},
+		"EmbeddingEnabled":          {DisplayName: "Semantic search", Description: "Generate embeddings of descriptions and reports in the background so they can be searched by meaning, not just by keywords", Category: "Search", InputType: "Boolean"},
+		"EmbeddingAPI":              {DisplayName: "API", Description: "Select the AI service to use for generating embeddings", Category: "Search", InputType: "APIPicker", Options: &embeddingAPIList},
+		"EmbeddingModel":            {DisplayName: "Model", Description: "Choose the embedding model. Changing it regenerates all embeddings in the background", Category: "Search", InputType: "APIModelPicker"},
+		"ScrubEnabled":              {DisplayName: "Redaction", Description: "Replace private information such as emails, API keys and phone numbers in screenshot descriptions with placeholders before they're saved or sent for report generation", Category: "Privacy", InputType: "Boolean"},
+		"ScrubDetectors":            {DisplayName: "Detectors", Description: "Comma-separated kinds of information to redact. Available: email, aws, github, jwt, creditcard, phone, ip", Category: "Privacy", InputType: "TextInput"},
+		"ScrubWords":                {DisplayName: "Words", Description: "Comma-separated words, such as names or project titles, to redact from descriptions", Category: "Privacy", InputType: "TextInput"},
 		"OllamaURL":                 {DisplayName: "Ollama URL", Description: "Enter the URL (including port) for your Ollama instance. The default is http://localhost:11434.", Category: "Models", InputType: "URLInput"},
+		"OllamaKeepAlive":           {DisplayName: "Ollama keep alive", Description: "Set how long Ollama keeps a model loaded after a request, e.g. 5m or 1h. Use 0 to unload it right away, or -1 to keep it loaded", Category: "Models", InputType: "TextInput"},
+		"OllamaNumCtx":              {DisplayName: "Ollama context size", Description: "Set the context window in tokens used with Ollama. Prompts longer than this are truncated, so raise it if reports leave out parts of the day. 0 uses the model's default", Category: "Models", InputType: "NumberInput"},
 		"GeminiAPIKey":              {DisplayName: "Gemini API key", Description: "Enter your Gemini API key. You can obtain a free API key from Google.", Category: "Models", InputType: "TextInput"},
+		"GeminiInlineImages":        {DisplayName: "Gemini inline images", Description: "Send screenshots to Gemini inside requests instead of uploading them first. Screenshots too large to send inline are still uploaded, then deleted. Downscale them with a Gemini: max_edge=... entry in the image overrides", Category: "Models", InputType: "Boolean"},
+		"HTTPProxy":                 {DisplayName: "Proxy", Description: "Set the URL of a proxy used to reach AI providers, e.g. http://proxy.example.com:8080. Leave empty to use the HTTPS_PROXY and HTTP_PROXY environment variables, or enter direct to not use a proxy", Category: "Network", InputType: "TextInput"},
+		"HTTPCABundle":              {DisplayName: "CA bundle", Description: "Set the path to a PEM file with certificates to trust in addition to the system's, e.g. your organization's private CA", Category: "Network", InputType: "TextInput"},
+		"HTTPConnectTimeoutSecs":    {DisplayName: "Connect timeout", Description: "Set how many seconds to wait when connecting to an AI provider. 0 waits indefinitely", Category: "Network", InputType: "NumberInput"},
+		"HTTPResponseTimeoutSecs":   {DisplayName: "Response timeout", Description: "Set how many seconds to wait for an AI provider to respond, so a provider that stops responding doesn't hold up the queue. 0 waits indefinitely", Category: "Network", InputType: "NumberInput"},
+		"HTTPOverrides":             {DisplayName: "Per-provider overrides", Description: "Override the network settings for specific APIs, as API: key=value entries separated by semicolons, e.g. Ollama: proxy=direct, response_timeout=600; OpenAI: ca_bundle=/etc/ssl/corp.pem. Keys are proxy, ca_bundle, connect_timeout and response_timeout", Category: "Network", InputType: "TextInput"},
+		"ResponseCacheEnabled":      {DisplayName: "Response cache", Description: "Reuse the response to a request that was already sent with the same screenshots, prompt, model and settings, instead of paying for it again. Regenerating a report skips the cache", Category: "Models", InputType: "Boolean"},
+		"ResponseCacheTTLHours":     {DisplayName: "Response cache duration", Description: "Set how many hours cached responses are reused for", Category: "Models", InputType: "NumberInput"},
+		"ResponseCacheMaxMB":        {DisplayName: "Response cache size", Description: "Set the most space in megabytes cached responses can take up. The oldest responses are deleted first", Category: "Models", InputType: "NumberInput"},
 		"OpenAIAPIKey":              {DisplayName: "OpenAI API key", Description: "Enter your OpenAI API key. You can obtain an API key from https://platform.openai.com/api-keys.", Category: "Models", InputType: "TextInput"},
 		"OpenRouterAPIKey":          {DisplayName: "OpenRouter API key", Description: "Enter your OpenRouter API key. You can obtain an API key from https://openrouter.ai/settings/keys.", Category: "Models", InputType: "TextInput"},
+		"AnthropicAPIKey":           {DisplayName: "Anthropic API key", Description: "Enter your Anthropic API key. You can obtain an API key from https://console.anthropic.com/settings/keys.", Category: "Models", InputType: "TextInput"},
+		"CustomOpenAIURL":           {DisplayName: "Custom API URL", Description: "Set the base URL of an OpenAI-compatible server used by the Custom OpenAI-compatible API, such as LM Studio, llama.cpp's server, vLLM or LocalAI, e.g. http://localhost:8080/v1", Category: "Models", InputType: "TextInput"},
+		"CustomOpenAIAPIKey":        {DisplayName: "Custom API key", Description: "Enter the API key of the OpenAI-compatible server, if it requires one", Category: "Models", InputType: "TextInput"},
+		"CustomOpenAIHeaders":       {DisplayName: "Custom API headers", Description: "Set extra headers sent to the OpenAI-compatible server, as Name: value entries separated by semicolons. They're stored with your API keys and aren't shown again once saved, as they can hold credentials", Category: "Models", InputType: "TextInput"},
+		"CustomOpenAIVision":        {DisplayName: "Custom API supports images", Description: "Enable if the models served by the OpenAI-compatible server accept images and can be used to describe screenshots", Category: "Models", InputType: "Boolean"},
 	}
 
 	return settingKeyDisplayVals
@@ -74,8 +164,8 @@ func GetDisplayValues() map[string]SettingDisplayProps {
 
 // Retrieves all settings from the database and returns them as a map where each key is the setting name
 // and each value is a Setting struct. Returns an error if the query fails.
-func LoadSettings(db *sql.DB) (map[string]Setting, error) {
-	rows, err := db.Query("SELECT * FROM settings")
+func (s *SQLiteStore) LoadSettings() (map[string]Setting, error) {
+	rows, err := s.db.Query("SELECT * FROM settings")
 	if err != nil {
 		return nil, err
 	}
@@ -83,53 +173,109 @@ func LoadSettings(db *sql.DB) (map[string]Setting, error) {
 
 	settings := make(map[string]Setting)
 	for rows.Next() {
-		var s Setting
-		if err := rows.Scan(&s.Key, &s.Value); err != nil {
+		var setting Setting
+		if err := rows.Scan(&setting.Key, &setting.Value); err != nil {
 			return nil, err
 		}
-		settings[s.Key] = s
+		settings[setting.Key] = setting
 	}
 	return settings, nil
 }
 
 // Initializes the application configuration by loading settings from the database and merging them with default values.
 // It updates the config object with the final values and returns the populated AppConfig struct or an error if the operation fails.
-func LoadConfig() (*config.AppConfig, error) {
-	dbCl, err := CreateConnection()
-	if err != nil {
-		return nil, err
-	}
-	defer dbCl.Close()
-
-	settingsMap, err := LoadSettings(dbCl) // Load settings from database
+func (s *SQLiteStore) LoadConfig() (*config.AppConfig, error) {
+	settingsMap, err := s.LoadSettings() // Load settings from database
 	if err != nil {
 		return nil, err
 	}
 
 	defaultDescIntervalMins, _ := strconv.Atoi(defaultSettings["DescGenIntervalMins"])
 	defaultDescIntervalEnabled, _ := strconv.Atoi(defaultSettings["DescGenIntervalEnabled"])
+	defaultDescBatchSize, _ := strconv.Atoi(defaultSettings["DescGenBatchSize"])
+	defaultDescContextCount, _ := strconv.Atoi(defaultSettings["DescGenContextCount"])
+	defaultDescTemperature, _ := strconv.ParseFloat(defaultSettings["DescGenTemperature"], 64)
+	defaultDescTopP, _ := strconv.ParseFloat(defaultSettings["DescGenTopP"], 64)
+	defaultDescMaxTokens, _ := strconv.Atoi(defaultSettings["DescGenMaxTokens"])
+	defaultImageMaxEdge, _ := strconv.Atoi(defaultSettings["ImageMaxEdge"])
+	defaultImageQuality, _ := strconv.Atoi(defaultSettings["ImageQuality"])
+	defaultImageGrayscale, _ := strconv.Atoi(defaultSettings["ImageGrayscale"])
+	defaultImageCropToActiveDisplay, _ := strconv.Atoi(defaultSettings["ImageCropToActiveDisplay"])
+	defaultReportTemperature, _ := strconv.ParseFloat(defaultSettings["ReportTemperature"], 64)
+	defaultReportTopP, _ := strconv.ParseFloat(defaultSettings["ReportTopP"], 64)
+	defaultReportMaxTokens, _ := strconv.Atoi(defaultSettings["ReportMaxTokens"])
 	defaultScrIntervalMins, _ := strconv.Atoi(defaultSettings["ScreenshotIntervalMins"])
 	defaultScrIntervalEnabled, _ := strconv.Atoi(defaultSettings["ScreenshotIntervalEnabled"])
 	defaultReportAutoEnabled, _ := strconv.Atoi(defaultSettings["ReportAutoEnabled"])
+	defaultEmbeddingEnabled, _ := strconv.Atoi(defaultSettings["EmbeddingEnabled"])
+	defaultScrubEnabled, _ := strconv.Atoi(defaultSettings["ScrubEnabled"])
+	defaultOllamaNumCtx, _ := strconv.Atoi(defaultSettings["OllamaNumCtx"])
+	defaultGeminiInlineImages, _ := strconv.Atoi(defaultSettings["GeminiInlineImages"])
+	defaultCustomOpenAIVision, _ := strconv.Atoi(defaultSettings["CustomOpenAIVision"])
+	defaultHTTPConnectTimeoutSecs, _ := strconv.Atoi(defaultSettings["HTTPConnectTimeoutSecs"])
+	defaultHTTPResponseTimeoutSecs, _ := strconv.Atoi(defaultSettings["HTTPResponseTimeoutSecs"])
+	defaultResponseCacheEnabled, _ := strconv.Atoi(defaultSettings["ResponseCacheEnabled"])
+	defaultResponseCacheTTLHours, _ := strconv.Atoi(defaultSettings["ResponseCacheTTLHours"])
+	defaultResponseCacheMaxMB, _ := strconv.Atoi(defaultSettings["ResponseCacheMaxMB"])
 
 	loadedConf := &config.AppConfig{
 		ScrPath:                   defaultSettings["ScrPath"],
 		DescGenAPI:                defaultSettings["DescGenAPI"],
 		DescGenModel:              defaultSettings["DescGenModel"],
+		DescGenFallback:           defaultSettings["DescGenFallback"],
 		DescGenPrompt:             defaultSettings["DescGenPrompt"],
 		DescGenIntervalMins:       defaultDescIntervalMins,
 		DescGenIntervalEnabled:    defaultDescIntervalEnabled,
+		DescGenBatchSize:          defaultDescBatchSize,
+		DescGenContextCount:       defaultDescContextCount,
+		DescGenTemperature:        defaultDescTemperature,
+		DescGenTopP:               defaultDescTopP,
+		DescGenMaxTokens:          defaultDescMaxTokens,
+		ImageMaxEdge:              defaultImageMaxEdge,
+		ImageFormat:               defaultSettings["ImageFormat"],
+		ImageQuality:              defaultImageQuality,
+		ImageGrayscale:            defaultImageGrayscale,
+		ImageCropToActiveDisplay:  defaultImageCropToActiveDisplay,
+		ImageOverrides:            defaultSettings["ImageOverrides"],
 		ScreenshotIntervalMins:    defaultScrIntervalMins,
 		ScreenshotIntervalEnabled: defaultScrIntervalEnabled,
 		ReportAPI:                 defaultSettings["ReportAPI"],
 		ReportModel:               defaultSettings["ReportModel"],
+		ReportFallback:            defaultSettings["ReportFallback"],
 		ReportAutoEnabled:         defaultReportAutoEnabled,
 		ReportAutoAt:              defaultSettings["ReportAutoAt"],
 		ReportPrompt:              defaultSettings["ReportPrompt"],
+		ReportTemperature:         defaultReportTemperature,
+		ReportTopP:                defaultReportTopP,
+		ReportMaxTokens:           defaultReportMaxTokens,
+		Timezone:                  defaultSettings["Timezone"],
+		DayStartsAt:               defaultSettings["DayStartsAt"],
+		EmbeddingEnabled:          defaultEmbeddingEnabled,
+		EmbeddingAPI:              defaultSettings["EmbeddingAPI"],
+		EmbeddingModel:            defaultSettings["EmbeddingModel"],
+		ScrubEnabled:              defaultScrubEnabled,
+		ScrubDetectors:            defaultSettings["ScrubDetectors"],
+		ScrubWords:                defaultSettings["ScrubWords"],
 		OllamaURL:                 defaultSettings["OllamaURL"],
+		OllamaKeepAlive:           defaultSettings["OllamaKeepAlive"],
+		OllamaNumCtx:              defaultOllamaNumCtx,
 		GeminiAPIKey:              defaultSettings["GeminiAPIKey"],
+		GeminiInlineImages:        defaultGeminiInlineImages,
 		OpenAIAPIKey:              defaultSettings["OpenAIAPIKey"],
 		OpenRouterAPIKey:          defaultSettings["OpenRouterAPIKey"],
+		AnthropicAPIKey:           defaultSettings["AnthropicAPIKey"],
+		CustomOpenAIURL:           defaultSettings["CustomOpenAIURL"],
+		CustomOpenAIAPIKey:        defaultSettings["CustomOpenAIAPIKey"],
+		CustomOpenAIHeaders:       defaultSettings["CustomOpenAIHeaders"],
+		CustomOpenAIVision:        defaultCustomOpenAIVision,
+		HTTPProxy:                 defaultSettings["HTTPProxy"],
+		HTTPCABundle:              defaultSettings["HTTPCABundle"],
+		HTTPConnectTimeoutSecs:    defaultHTTPConnectTimeoutSecs,
+		HTTPResponseTimeoutSecs:   defaultHTTPResponseTimeoutSecs,
+		HTTPOverrides:             defaultSettings["HTTPOverrides"],
+		ResponseCacheEnabled:      defaultResponseCacheEnabled,
+		ResponseCacheTTLHours:     defaultResponseCacheTTLHours,
+		ResponseCacheMaxMB:        defaultResponseCacheMaxMB,
 	}
 
 	// Update config with values from the database, validating them
@@ -141,12 +287,36 @@ func LoadConfig() (*config.AppConfig, error) {
 			loadedConf.DescGenAPI = setting.Value
 		case "DescGenModel":
 			loadedConf.DescGenModel = setting.Value
+		case "DescGenFallback":
+			loadedConf.DescGenFallback = setting.Value
 		case "DescGenPrompt":
 			loadedConf.DescGenPrompt = setting.Value
 		case "DescGenIntervalMins":
 			loadedConf.DescGenIntervalMins, _ = strconv.Atoi(setting.Value)
 		case "DescGenIntervalEnabled":
 			loadedConf.DescGenIntervalEnabled, _ = strconv.Atoi(setting.Value)
+		case "DescGenBatchSize":
+			loadedConf.DescGenBatchSize, _ = strconv.Atoi(setting.Value)
+		case "DescGenContextCount":
+			loadedConf.DescGenContextCount, _ = strconv.Atoi(setting.Value)
+		case "DescGenTemperature":
+			loadedConf.DescGenTemperature, _ = strconv.ParseFloat(setting.Value, 64)
+		case "DescGenTopP":
+			loadedConf.DescGenTopP, _ = strconv.ParseFloat(setting.Value, 64)
+		case "DescGenMaxTokens":
+			loadedConf.DescGenMaxTokens, _ = strconv.Atoi(setting.Value)
+		case "ImageMaxEdge":
+			loadedConf.ImageMaxEdge, _ = strconv.Atoi(setting.Value)
+		case "ImageFormat":
+			loadedConf.ImageFormat = setting.Value
+		case "ImageQuality":
+			loadedConf.ImageQuality, _ = strconv.Atoi(setting.Value)
+		case "ImageGrayscale":
+			loadedConf.ImageGrayscale, _ = strconv.Atoi(setting.Value)
+		case "ImageCropToActiveDisplay":
+			loadedConf.ImageCropToActiveDisplay, _ = strconv.Atoi(setting.Value)
+		case "ImageOverrides":
+			loadedConf.ImageOverrides = setting.Value
 		case "ScreenshotIntervalMins":
 			loadedConf.ScreenshotIntervalMins, _ = strconv.Atoi(setting.Value)
 		case "ScreenshotIntervalEnabled":
@@ -155,23 +325,81 @@ func LoadConfig() (*config.AppConfig, error) {
 			loadedConf.ReportAPI = setting.Value
 		case "ReportModel":
 			loadedConf.ReportModel = setting.Value
+		case "ReportFallback":
+			loadedConf.ReportFallback = setting.Value
 		case "ReportAutoEnabled":
 			loadedConf.ReportAutoEnabled, _ = strconv.Atoi(setting.Value)
 		case "ReportAutoAt":
 			loadedConf.ReportAutoAt = setting.Value
 		case "ReportPrompt":
 			loadedConf.ReportPrompt = setting.Value
+		case "ReportTemperature":
+			loadedConf.ReportTemperature, _ = strconv.ParseFloat(setting.Value, 64)
+		case "ReportTopP":
+			loadedConf.ReportTopP, _ = strconv.ParseFloat(setting.Value, 64)
+		case "ReportMaxTokens":
+			loadedConf.ReportMaxTokens, _ = strconv.Atoi(setting.Value)
+		case "Timezone":
+			loadedConf.Timezone = setting.Value
+		case "DayStartsAt":
+			loadedConf.DayStartsAt = setting.Value
+		case "EmbeddingEnabled":
+			loadedConf.EmbeddingEnabled, _ = strconv.Atoi(setting.Value)
+		case "EmbeddingAPI":
+			loadedConf.EmbeddingAPI = setting.Value
+		case "EmbeddingModel":
+			loadedConf.EmbeddingModel = setting.Value
+		case "ScrubEnabled":
+			loadedConf.ScrubEnabled, _ = strconv.Atoi(setting.Value)
+		case "ScrubDetectors":
+			loadedConf.ScrubDetectors = setting.Value
+		case "ScrubWords":
+			loadedConf.ScrubWords = setting.Value
 		case "OllamaURL":
 			loadedConf.OllamaURL = setting.Value
+		case "OllamaKeepAlive":
+			loadedConf.OllamaKeepAlive = setting.Value
+		case "OllamaNumCtx":
+			loadedConf.OllamaNumCtx, _ = strconv.Atoi(setting.Value)
 		case "GeminiAPIKey":
 			loadedConf.GeminiAPIKey = setting.Value
+		case "GeminiInlineImages":
+			loadedConf.GeminiInlineImages, _ = strconv.Atoi(setting.Value)
 		case "OpenAIAPIKey":
 			loadedConf.OpenAIAPIKey = setting.Value
 		case "OpenRouterAPIKey":
 			loadedConf.OpenRouterAPIKey = setting.Value
+		case "AnthropicAPIKey":
+			loadedConf.AnthropicAPIKey = setting.Value
+		case "CustomOpenAIURL":
+			loadedConf.CustomOpenAIURL = setting.Value
+		case "CustomOpenAIAPIKey":
+			loadedConf.CustomOpenAIAPIKey = setting.Value
+		case "CustomOpenAIHeaders":
+			loadedConf.CustomOpenAIHeaders = setting.Value
+		case "CustomOpenAIVision":
+			loadedConf.CustomOpenAIVision, _ = strconv.Atoi(setting.Value)
+		case "HTTPProxy":
+			loadedConf.HTTPProxy = setting.Value
+		case "HTTPCABundle":
+			loadedConf.HTTPCABundle = setting.Value
+		case "HTTPConnectTimeoutSecs":
+			loadedConf.HTTPConnectTimeoutSecs, _ = strconv.Atoi(setting.Value)
+		case "HTTPResponseTimeoutSecs":
+			loadedConf.HTTPResponseTimeoutSecs, _ = strconv.Atoi(setting.Value)
+		case "HTTPOverrides":
+			loadedConf.HTTPOverrides = setting.Value
+		case "ResponseCacheEnabled":
+			loadedConf.ResponseCacheEnabled, _ = strconv.Atoi(setting.Value)
+		case "ResponseCacheTTLHours":
+			loadedConf.ResponseCacheTTLHours, _ = strconv.Atoi(setting.Value)
+		case "ResponseCacheMaxMB":
+			loadedConf.ResponseCacheMaxMB, _ = strconv.Atoi(setting.Value)
 		}
 	}
 
+	loadSecrets(loadedConf)
+
 	config.Config = *loadedConf
 	return loadedConf, nil
 }
@@ -179,62 +407,262 @@ func LoadConfig() (*config.AppConfig, error) {
 // Triggers re-initialization of specific app components (like scheduling or LLM) based on updated settings.
 // It checks which settings have changed and only reinitializes components if required by those changes.
 func RefreshInit(newSettings map[string]string) {
+	// Connectors create their HTTP clients with the network settings when they're created
+	httpChanged := false
+	for _, key := range []string{"HTTPProxy", "HTTPCABundle", "HTTPConnectTimeoutSecs", "HTTPResponseTimeoutSecs", "HTTPOverrides"} {
+		if _, ok := newSettings[key]; ok {
+			httpChanged = true
+		}
+	}
+
+	for _, key := range []string{"OllamaURL", "GeminiAPIKey", "OpenAIAPIKey", "OpenRouterAPIKey", "AnthropicAPIKey", "CustomOpenAIURL", "CustomOpenAIAPIKey", "CustomOpenAIHeaders", "CustomOpenAIVision"} {
+		if _, ok := newSettings[key]; ok || httpChanged {
+			models.InvalidateModelLists()
+			break
+		}
+	}
+
 	_, ok1 := newSettings["ScreenshotIntervalMins"]
 	_, ok2 := newSettings["DescGenIntervalMins"]
+	scheduleChanged := false
+	for _, key := range []string{"ReportAutoEnabled", "ReportAutoAt", "Timezone"} {
+		if _, ok := newSettings[key]; ok {
+			scheduleChanged = true
+		}
+	}
 
-	if (ok1 || ok2) && Initializers.FunctionsGiven {
+	if (ok1 || ok2 || scheduleChanged) && Initializers.FunctionsGiven {
 		Initializers.InitSchedule()
 	}
 
 	_, ok1 = newSettings["DescGenAPI"]
 	_, ok2 = newSettings["ReportAPI"]
+	_, ok3 := newSettings["EmbeddingEnabled"]
+	_, ok4 := newSettings["EmbeddingAPI"]
+	_, ok5 := newSettings["EmbeddingModel"]
+	_, ok6 := newSettings["DescGenFallback"]
+	_, ok7 := newSettings["ReportFallback"]
+
+	paramsChanged := false
+	for _, key := range generationSettings {
+		if _, ok := newSettings[key]; ok {
+			paramsChanged = true
+		}
+	}
+
+	// The custom API's connectors read their URL, headers and vision setting when they're created
+	customChanged := false
+	for _, key := range []string{"CustomOpenAIURL", "CustomOpenAIHeaders", "CustomOpenAIVision"} {
+		if _, ok := newSettings[key]; ok {
+			customChanged = true
+		}
+	}
 
-	if (ok1 || ok2) && Initializers.FunctionsGiven {
+	if (ok1 || ok2 || ok3 || ok4 || ok5 || ok6 || ok7 || customChanged || paramsChanged || httpChanged) && Initializers.FunctionsGiven {
 		Initializers.InitLLM()
 	}
 }
 
+// Settings holding generation parameters. Changing them, or the API they're used with, has them validated
+var generationSettings = []string{"DescGenTemperature", "DescGenTopP", "DescGenMaxTokens", "ReportTemperature", "ReportTopP", "ReportMaxTokens"}
+
+// Returns the new value of a setting if it's being changed, or its current value otherwise
+func newOrCurrent(newSettings map[string]string, key string, current string) string {
+	if val, ok := newSettings[key]; ok {
+		return val
+	}
+	return current
+}
+
+// Parses the generation parameters of a task from new and current setting values
+func parseGenerationParams(newSettings map[string]string, prefix string, temperature float64, topP float64, maxTokens int) (models.GenerationParams, error) {
+	temp, err := strconv.ParseFloat(newOrCurrent(newSettings, prefix+"Temperature", strconv.FormatFloat(temperature, 'f', -1, 64)), 64)
+	if err != nil {
+		return models.GenerationParams{}, fmt.Errorf("temperature must be a number")
+	}
+	p, err := strconv.ParseFloat(newOrCurrent(newSettings, prefix+"TopP", strconv.FormatFloat(topP, 'f', -1, 64)), 64)
+	if err != nil {
+		return models.GenerationParams{}, fmt.Errorf("top P must be a number")
+	}
+	tokens, err := strconv.Atoi(newOrCurrent(newSettings, prefix+"MaxTokens", strconv.Itoa(maxTokens)))
+	if err != nil {
+		return models.GenerationParams{}, fmt.Errorf("max tokens must be a whole number")
+	}
+
+	return models.NewGenerationParams(temp, p, tokens), nil
+}
+
+// Checks that the generation parameters of the vision and report tasks are accepted by the APIs selected for them,
+// if either is being changed. APIs that aren't registered are left for InitLLM to replace
+func validateGenerationSettings(newSettings map[string]string) error {
+	tasks := []struct {
+		prefix, apiKey, api string
+		temperature, topP   float64
+		maxTokens           int
+	}{
+		{"DescGen", "DescGenAPI", config.Config.DescGenAPI, config.Config.DescGenTemperature, config.Config.DescGenTopP, config.Config.DescGenMaxTokens},
+		{"Report", "ReportAPI", config.Config.ReportAPI, config.Config.ReportTemperature, config.Config.ReportTopP, config.Config.ReportMaxTokens},
+	}
+
+	for _, task := range tasks {
+		changed := false
+		for _, key := range []string{task.apiKey, task.prefix + "Temperature", task.prefix + "TopP", task.prefix + "MaxTokens"} {
+			if _, ok := newSettings[key]; ok {
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+
+		params, err := parseGenerationParams(newSettings, task.prefix, task.temperature, task.topP, task.maxTokens)
+		if err != nil {
+			return err
+		}
+
+		api := newOrCurrent(newSettings, task.apiKey, task.api)
+		if _, err := models.GetAPI(api); err != nil {
+			continue
+		}
+		if err := models.ValidateGenerationParams(api, params); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Updates settings in both the database and the in-memory configuration (config.Config) using reflection.
 // It ensures that changes to settings are saved persistently and reflected immediately in the running application.
-func UpdateSettings(newSettings map[string]string) error {
-	dbCl, err := CreateConnection()
-	if err != nil {
-		fmt.Printf("Could not create DB connection: %v\n", err.Error())
+func (s *SQLiteStore) UpdateSettings(newSettings map[string]string) error {
+	if err := validateGenerationSettings(newSettings); err != nil {
+		fmt.Printf("Invalid generation settings: %v\n", err)
 		return err
 	}
-	defer dbCl.Close()
+	if overrides, ok := newSettings["HTTPOverrides"]; ok {
+		if err := models.CheckHTTPOverrides(overrides); err != nil {
+			fmt.Printf("Invalid HTTP overrides: %v\n", err)
+			return err
+		}
+	}
+	if proxy, ok := newSettings["HTTPProxy"]; ok {
+		if err := models.CheckProxy(proxy); err != nil {
+			fmt.Printf("Invalid HTTP proxy: %v\n", err)
+			return err
+		}
+	}
+	if bundle, ok := newSettings["HTTPCABundle"]; ok {
+		if err := models.CheckCABundle(bundle); err != nil {
+			fmt.Printf("Invalid CA bundle: %v\n", err)
+			return err
+		}
+	}
+	if overrides, ok := newSettings["ImageOverrides"]; ok {
+		if _, err := preprocess.ParseOverrides(overrides); err != nil {
+			fmt.Printf("Invalid image overrides: %v\n", err)
+			return err
+		}
+	}
+	if name, ok := newSettings["Timezone"]; ok {
+		if _, err := config.LoadLocation(name); err != nil {
+			fmt.Printf("Invalid time zone: %v\n", err)
+			return err
+		}
+	}
+	for _, key := range []string{"DayStartsAt", "ReportAutoAt"} {
+		if val, ok := newSettings[key]; ok {
+			if _, _, err := config.ParseClock(val); err != nil {
+				fmt.Printf("Invalid %s: %v\n", key, err)
+				return err
+			}
+		}
+	}
 
+	// Every value is parsed on a copy of the configuration first, so nothing is saved if one of them is invalid
+	checked := config.Config
 	for key, val := range newSettings {
-		err = updateSetting(dbCl, key, val)
+		if err := setConfigField(&checked, key, val); err != nil && !errors.Is(err, errUnknownSetting) {
+			fmt.Printf("Invalid %s: %v\n", key, err)
+			return err
+		}
+	}
+
+	for key, val := range newSettings {
+		if secrets.IsSecret(key) {
+			if err := updateSecret(key, val); err != nil {
+				fmt.Printf("Error when updating %s: %v\n", key, err.Error())
+				return err
+			}
+			continue
+		}
+
+		err := updateSetting(s.db, key, val)
 		if err != nil {
 			fmt.Printf("Error when updating %s with %s: %v\n", key, val, err.Error())
 			return err
 		}
 
 		// Update the config.Config struct via reflection. Find field with 'key', then update the field with 'val'
-		r := reflect.ValueOf(&config.Config).Elem()
-		field := r.FieldByName(key)
-		if field.IsValid() && field.CanSet() {
-			switch field.Kind() {
-			case reflect.Int:
-				intVal, err := strconv.Atoi(val)
-				if err != nil {
-					log.Panic("Could not parse int to string during setting reflection")
-				}
-				field.SetInt(int64(intVal))
-
-			default:
-				field.SetString(val)
+		if err := setConfigField(&config.Config, key, val); err != nil {
+			if errors.Is(err, errUnknownSetting) {
+				fmt.Printf("Field %s not found or not settable\n", key)
+				continue
 			}
+			return err
+		}
+	}
 
-		} else {
-			fmt.Printf("Field %s not found or not settable\n", key)
+	return nil
+}
+
+var errUnknownSetting = errors.New("unknown setting")
+
+// Sets the field of conf named key to val, parsing val for number fields
+func setConfigField(conf *config.AppConfig, key string, val string) error {
+	field := reflect.ValueOf(conf).Elem().FieldByName(key)
+	if !field.IsValid() || !field.CanSet() {
+		return fmt.Errorf("%w %s", errUnknownSetting, key)
+	}
+
+	switch field.Kind() {
+	case reflect.Int:
+		intVal, err := strconv.Atoi(val)
+		if err != nil {
+			return fmt.Errorf("%s must be a whole number", key)
 		}
+		field.SetInt(int64(intVal))
+
+	case reflect.Float64:
+		floatVal, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return fmt.Errorf("%s must be a number", key)
+		}
+		field.SetFloat(floatVal)
+
+	default:
+		field.SetString(val)
 	}
 
 	return nil
 }
 
+// Returns a copy of the current configuration with the pending, unsaved settings applied, so they can be tried
+// without changing config.Config. Secrets sent back as the masked value the UI was given keep their current value
+func PendingConfig(pending map[string]string) (*config.AppConfig, error) {
+	conf := config.Config
+
+	for key, val := range pending {
+		if secrets.IsSecret(key) && val == secrets.Mask(reflect.ValueOf(conf).FieldByName(key).String()) {
+			continue
+		}
+		if err := setConfigField(&conf, key, val); err != nil {
+			return nil, err
+		}
+	}
+
+	return &conf, nil
+}
+
 // Updates a specific setting in the database using the provided key and new value.
 // It performs a SQL UPDATE operation and returns an error if the update fails.
 func updateSetting(db *sql.DB, key, newValue string) error {
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"recap/internal/config"
+	"testing"
+)
+
+func TestUpdateSettingsRejectsInvalidNumbersWithoutSaving(t *testing.T) {
+	store := openTestStore(t)
+	interval := config.Config.DescGenIntervalMins
+
+	err := store.UpdateSettings(map[string]string{"DescGenIntervalMins": "15", "ImageQuality": "high"})
+	if err == nil {
+		t.Fatal("expected a non-numeric image quality to be rejected")
+	}
+
+	if config.Config.DescGenIntervalMins != interval {
+		t.Errorf("expected the configuration to be unchanged, got an interval of %d", config.Config.DescGenIntervalMins)
+	}
+	conf, err := store.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.DescGenIntervalMins != interval {
+		t.Errorf("expected nothing to be saved, got an interval of %d", conf.DescGenIntervalMins)
+	}
+
+	if err := store.UpdateSettings(map[string]string{"DescGenIntervalMins": "15", "ImageQuality": "70"}); err != nil {
+		t.Fatal(err)
+	}
+	if config.Config.DescGenIntervalMins != 15 || config.Config.ImageQuality != 70 {
+		t.Errorf("expected valid settings to be saved, got %d and %d", config.Config.DescGenIntervalMins, config.Config.ImageQuality)
+	}
+}
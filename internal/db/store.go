@@ -0,0 +1,70 @@
+package db
+
+import (
+	"database/sql"
+	"recap/internal/config"
+	"time"
+)
+
+// Reads and writes everything Recap keeps in its database. The application uses a SQLiteStore opened on
+// recap.db, and tests use one opened in memory with OpenMemory. Packages are given a Store when they're
+// initialized rather than opening the database themselves
+type Store interface {
+	// Captures and their descriptions
+	InsertCapture(scrFullThumbPairs []FullThumbScrPair) int64
+	UpdateScreenshotDescription(screenshot_id int, description string, genWithApi string, genWithModel string) (sql.Result, error)
+	GetCapturesToday() ([]CaptureDescription, error)
+	GetUnprocessedCaptures() ([]CaptureScreenshot, error)
+	GetDescriptionsBefore(timestamp int64, limit int) ([]CaptureDescription, error)
+
+	// Screenshots
+	GetScreenshots(limit int) ([]CaptureScreenshotImage, error)
+	GetScreenshotById(id int) (*CaptureScreenshotImage, error)
+	GetScreenshotByIds(ids []int) ([]CaptureScreenshot, error)
+	GetScreenshotsNewerThan(id int) ([]CaptureScreenshotImage, error)
+	GetScreenshotsOlderThan(id int, limit int) ([]CaptureScreenshotImage, error)
+	GetScreenshotsForReport(reportId int) ([]CaptureScreenshotImage, error)
+	DeleteScreenshotsById(ids []int) error
+
+	// Reports
+	LogDailyReport(reportText string, caps []CaptureDescription, genWithApi string, genWithModel string) (*int64, error)
+	GetReports(limit int) ([]Report, error)
+	GetReportById(id int) (*Report, error)
+	GetReportsNewerThan(id int) ([]Report, error)
+	GetReportsOlderThan(id int, limit int) ([]Report, error)
+	GetReportsForScreenshot(captureId int) ([]Report, error)
+	DeleteReportsById(ids []int) error
+
+	// Search
+	Search(query string, filters SearchFilters) ([]SearchResult, error)
+	SearchDescriptions(terms []string, from int64, to int64, limit int) ([]CaptureDescription, error)
+	SearchReports(terms []string, from int64, to int64, limit int) ([]Report, error)
+
+	// Embeddings
+	SaveEmbedding(sourceType string, sourceId int, model string, vector []float32) error
+	GetUnembeddedDescriptions(model string, limit int) ([]CaptureDescription, error)
+	GetUnembeddedReports(model string, limit int) ([]Report, error)
+	GetEmbeddings(sourceType string, model string, from int64, to int64) ([]Embedding, error)
+
+	// Response cache
+	GetCachedResponse(key ResponseCacheKey, maxAge time.Duration) (string, bool, error)
+	SaveCachedResponse(key ResponseCacheKey, response string) error
+	PruneResponseCache(maxAge time.Duration, maxBytes int64) (int64, error)
+
+	// Settings
+	LoadSettings() (map[string]Setting, error)
+	LoadConfig() (*config.AppConfig, error)
+	GetMaskedConfig() (*config.AppConfig, error)
+	UpdateSettings(newSettings map[string]string) error
+
+	// Info
+	WriteInfo(key, value string) error
+	UpdateInfo(newInfo map[string]string) error
+	ReadInfo(key string) (*Info, error)
+	ReadAllInfo() (map[string]string, error)
+	AddRedactedItemCount(count int) error
+
+	Close() error
+}
+
+var _ Store = (*SQLiteStore)(nil)
@@ -11,7 +11,7 @@ type CaptureScreenshotImage struct {
 	Screenshot   string  `json:"Screenshot"`
 	GenWithApi   *string `json:"GenWithApi"`
 	GenWithModel *string `json:"GenWithModel"`
-	ReportID     *int    `json:"ReportID"`
+	ReportID     *int    `json:"ReportID"` // Newest report the capture was included in, if any
 }
 
 // Contains description of screen capture along with other properties. Thumbname contains the thumbnail's filename
@@ -24,7 +24,7 @@ type CaptureScreenshot struct {
 	Description  *string
 	GenWithApi   *string
 	GenWithModel *string
-	ReportID     *int
+	ReportID     *int // Newest report the capture was included in, if any
 }
 
 // Basic properties of a screen capture
@@ -42,3 +42,35 @@ type Report struct {
 	GenWithApi   string `json:"GenWithApi"`
 	GenWithModel string `json:"GenWithModel"`
 }
+
+// A capture found by semantic search, with the cosine similarity between its description and the query
+type SemanticMatch struct {
+	CaptureID   int     `json:"CaptureID"`
+	Timestamp   int64   `json:"Timestamp"`
+	Description string  `json:"Description"`
+	Score       float64 `json:"Score"`
+}
+
+// Kinds of search results
+const (
+	SearchKindCapture = "capture"
+	SearchKindReport  = "report"
+)
+
+// Narrows down full-text search results
+type SearchFilters struct {
+	From  int64    `json:"From"`  // UNIX second timestamp. 0 leaves the start of the range open
+	To    int64    `json:"To"`    // UNIX second timestamp, exclusive. 0 leaves the end of the range open
+	Kinds []string `json:"Kinds"` // SearchKindCapture, SearchKindReport, or both if empty
+	Limit int      `json:"Limit"` // Maximum number of results. Defaults to 50
+}
+
+// A capture or report matching a full-text search. Snippet contains the matching part of the
+// description or report, with matches highlighted in Markdown bold
+type SearchResult struct {
+	Kind      string  `json:"Kind"`
+	ID        int     `json:"ID"`
+	Timestamp int64   `json:"Timestamp"`
+	Snippet   string  `json:"Snippet"`
+	Rank      float64 `json:"-"`
+}
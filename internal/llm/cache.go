@@ -0,0 +1,191 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"recap/internal/config"
+	"recap/internal/db"
+	"recap/internal/models"
+	"recap/internal/preprocess"
+	"strings"
+	"time"
+)
+
+/**
+Responses are cached by the content of the screenshots and prompt sent, along with the API, model and
+parameters used, so regenerating a report or re-running the queue after a crash doesn't pay for the same
+request twice. A chain's APIs are checked in order, and the first cached response found is used
+*/
+
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Returns the SHA-256 of a screenshot's content, or of the hashes of several screenshots in order
+func hashImages(fileNames []string) (string, error) {
+	hashes := make([]string, len(fileNames))
+	for i, fileName := range fileNames {
+		data, err := os.ReadFile(filepath.Join(config.Config.ScrPath, fileName))
+		if err != nil {
+			return "", fmt.Errorf("failed to read image file %s: %w", fileName, err)
+		}
+		hashes[i] = hashBytes(data)
+	}
+
+	if len(hashes) == 1 {
+		return hashes[0], nil
+	}
+	return hashBytes([]byte(strings.Join(hashes, ","))), nil
+}
+
+// Base URLs of the APIs whose server is a setting. They're part of the cache key, so responses from
+// one server aren't reused after switching to another
+var baseURLs = map[string]func() string{
+	"Ollama":                   func() string { return config.Config.OllamaURL },
+	"Custom OpenAI-compatible": func() string { return config.Config.CustomOpenAIURL },
+}
+
+// Returns the cache key of a request to an API. Screenshots are preprocessed differently for each API,
+// so the image settings are part of the key of vision requests
+func cacheKey(api models.TextVisionAPI, params models.GenerationParams, imageHash string, prompt string) db.ResponseCacheKey {
+	paramText := params.String()
+	if baseURL, ok := baseURLs[api.GetAPIName()]; ok {
+		paramText += " url=" + strings.TrimSuffix(baseURL(), "/")
+	}
+	if imageHash != "" {
+		paramText += fmt.Sprintf(" image=%+v", preprocess.GetOptions(api.GetAPIName()))
+	}
+
+	return db.ResponseCacheKey{
+		ImageHash:  imageHash,
+		PromptHash: hashBytes([]byte(prompt)),
+		API:        api.GetAPIName(),
+		Model:      api.GetAPIModelName(),
+		Params:     paramText,
+	}
+}
+
+func cacheTTL() time.Duration {
+	return time.Duration(max(config.Config.ResponseCacheTTLHours, 0)) * time.Hour
+}
+
+// Returns the cached responses to a request from the first API in the chain that has them, along with that API.
+// Responses are stored as a JSON array, holding one response per screenshot for bulk requests
+func lookupResponses(chain *models.FallbackChain, params models.GenerationParams, imageHash string, prompt string, count int) ([]string, models.TextVisionAPI, bool) {
+	for _, api := range chain.APIs() {
+		cached, ok, err := store.GetCachedResponse(cacheKey(api, params, imageHash, prompt), cacheTTL())
+		if err != nil {
+			log.Printf("Could not read response cache: %v", err)
+			return nil, nil, false
+		}
+		if !ok {
+			continue
+		}
+
+		var responses []string
+		if err := json.Unmarshal([]byte(cached), &responses); err != nil || len(responses) != count {
+			continue
+		}
+		return responses, api, true
+	}
+
+	return nil, nil, false
+}
+
+// Caches the responses an API gave to a request
+func saveResponses(producer models.TextVisionAPI, params models.GenerationParams, imageHash string, prompt string, responses []string) {
+	encoded, err := json.Marshal(responses)
+	if err != nil {
+		return
+	}
+
+	if err := store.SaveCachedResponse(cacheKey(producer, params, imageHash, prompt), string(encoded)); err != nil {
+		log.Printf("Could not cache response: %v", err)
+	}
+}
+
+// Deletes expired cached responses and the oldest ones above the size limit. It's run once the queue
+// or a report is done rather than after every response
+func pruneResponses() {
+	maxBytes := int64(max(config.Config.ResponseCacheMaxMB, 0)) * 1024 * 1024
+	deleted, err := store.PruneResponseCache(cacheTTL(), maxBytes)
+	if err != nil {
+		log.Printf("Could not prune response cache: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Removed %d responses from the cache", deleted)
+	}
+}
+
+// Generates text with the chain, reusing a cached response if there is one. If bypassCache is true,
+// the cache isn't checked, but the new response still replaces the cached one
+func generateTextCached(chain *models.FallbackChain, params models.GenerationParams, prompt string, bypassCache bool) (string, models.TextVisionAPI, error) {
+	enabled := config.Config.ResponseCacheEnabled == 1
+
+	if enabled && !bypassCache {
+		if cached, api, ok := lookupResponses(chain, params, "", prompt, 1); ok {
+			log.Printf("Using cached response from %s %s", api.GetAPIName(), api.GetAPIModelName())
+			return cached[0], api, nil
+		}
+	}
+
+	res, producer, err := chain.GenerateTextWithProducer(prompt)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if enabled {
+		saveResponses(producer, params, "", prompt, []string{res})
+	}
+	return res, producer, nil
+}
+
+// Describes one or more screenshots with the chain, reusing cached descriptions if there are any.
+// If bypassCache is true, the cache isn't checked, but the new descriptions still replace the cached ones
+func describeScreenshotsCached(chain *models.FallbackChain, params models.GenerationParams, fileNames []string, prompt string, bypassCache bool) ([]string, models.TextVisionAPI, error) {
+	enabled := config.Config.ResponseCacheEnabled == 1
+
+	imageHash := ""
+	if enabled {
+		hash, err := hashImages(fileNames)
+		if err != nil {
+			log.Printf("Not caching description: %v", err)
+			enabled = false
+		}
+		imageHash = hash
+	}
+
+	if enabled && !bypassCache {
+		if cached, api, ok := lookupResponses(chain, params, imageHash, prompt, len(fileNames)); ok {
+			log.Printf("Using cached description from %s %s", api.GetAPIName(), api.GetAPIModelName())
+			return cached, api, nil
+		}
+	}
+
+	var results []string
+	var producer models.TextVisionAPI
+	var err error
+
+	if len(fileNames) == 1 {
+		var res string
+		res, producer, err = chain.DescribeScreenshotWithProducer(fileNames[0], prompt)
+		results = []string{res}
+	} else {
+		results, producer, err = chain.DescribeBulkScreenshotsWithProducer(fileNames, prompt)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if enabled {
+		saveResponses(producer, params, imageHash, prompt, results)
+	}
+	return results, producer, nil
+}
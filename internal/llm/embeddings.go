@@ -0,0 +1,235 @@
+package llm
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"recap/internal/config"
+	"recap/internal/db"
+	"recap/internal/models"
+	"sort"
+	"sync/atomic"
+)
+
+// Number of descriptions or reports sent to the embedding API per request during backfill
+const embeddingBatchSize = 32
+
+var embeddingAPI models.EmbeddingAPI
+var backfillRunning atomic.Bool
+
+// Sets up the embedding API from configuration settings. Embeddings are disabled if EmbeddingEnabled
+// is off or the configured API isn't registered
+func initializeEmbeddings() {
+	embeddingAPI = nil
+
+	if config.Config.EmbeddingEnabled != 1 {
+		return
+	}
+
+	factory, err := models.GetEmbeddingAPI(config.Config.EmbeddingAPI)
+	if err != nil {
+		fmt.Printf("Could not find embedding API %s, semantic search is disabled\n", config.Config.EmbeddingAPI)
+		return
+	}
+
+	embeddingAPI = factory(config.Config.EmbeddingModel)
+	fmt.Printf("Using %s %s for embeddings\n", config.Config.EmbeddingAPI, config.Config.EmbeddingModel)
+}
+
+// Identifies the API and model embeddings are generated with. Stored alongside each vector,
+// as vectors from different models can't be compared
+func embeddingModelKey(api models.EmbeddingAPI) string {
+	return api.GetAPIName() + "/" + api.GetAPIModelName()
+}
+
+// Starts generating embeddings for all descriptions and reports that don't have one yet, in the background.
+// Does nothing if embeddings are disabled or a backfill is already running
+func BackfillEmbeddings() {
+	api := embeddingAPI
+	if api == nil || !backfillRunning.CompareAndSwap(false, true) {
+		return
+	}
+
+	go func() {
+		defer backfillRunning.Store(false)
+
+		count, err := backfillEmbeddings(api)
+		if err != nil {
+			log.Printf("Embedding backfill stopped: %v", err)
+		}
+		if count > 0 {
+			log.Printf("Embedding backfill generated %d embeddings", count)
+		}
+	}()
+}
+
+// Generates and stores embeddings for descriptions and reports that have none from the given API's model,
+// in batches, until none are left. Returns the number of embeddings generated
+func backfillEmbeddings(api models.EmbeddingAPI) (int, error) {
+	key := embeddingModelKey(api)
+	count := 0
+
+	for {
+		descs, err := store.GetUnembeddedDescriptions(key, embeddingBatchSize)
+		if err != nil {
+			return count, err
+		}
+		if len(descs) == 0 {
+			break
+		}
+
+		texts := make([]string, len(descs))
+		for i, desc := range descs {
+			texts[i] = desc.Description
+		}
+
+		vectors, err := api.Embed(texts)
+		if err != nil {
+			return count, err
+		}
+
+		for i, desc := range descs {
+			if err := store.SaveEmbedding(db.EmbeddingSourceCapture, desc.CaptureID, key, vectors[i]); err != nil {
+				return count, err
+			}
+		}
+		count += len(descs)
+	}
+
+	for {
+		reports, err := store.GetUnembeddedReports(key, embeddingBatchSize)
+		if err != nil {
+			return count, err
+		}
+		if len(reports) == 0 {
+			break
+		}
+
+		texts := make([]string, len(reports))
+		for i, rep := range reports {
+			texts[i] = rep.Content
+		}
+
+		vectors, err := api.Embed(texts)
+		if err != nil {
+			return count, err
+		}
+
+		for i, rep := range reports {
+			if err := store.SaveEmbedding(db.EmbeddingSourceReport, rep.ReportID, key, vectors[i]); err != nil {
+				return count, err
+			}
+		}
+		count += len(reports)
+	}
+
+	return count, nil
+}
+
+// Returns the cosine similarity of two vectors, or 0 if their lengths differ or either is all zeroes
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// An embedding along with its similarity to a query
+type scoredEmbedding struct {
+	db.Embedding
+	Score float64
+}
+
+// Ranks stored embeddings of the given source type in the from-to range by their cosine similarity
+// to the query vector, returning the k most similar
+func rankEmbeddings(api models.EmbeddingAPI, sourceType string, queryVec []float32, from int64, to int64, k int) ([]scoredEmbedding, error) {
+	embeddings, err := store.GetEmbeddings(sourceType, embeddingModelKey(api), from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	scored := make([]scoredEmbedding, len(embeddings))
+	for i, emb := range embeddings {
+		scored[i] = scoredEmbedding{Embedding: emb, Score: cosineSimilarity(queryVec, emb.Vector)}
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].Score > scored[j].Score
+	})
+
+	return scored[:min(k, len(scored))], nil
+}
+
+// Returns up to k captures taken between the from and to UNIX second timestamps whose descriptions
+// are the most similar in meaning to the query, ranked by cosine similarity. A from or to value of 0
+// leaves that end of the range open. Captures without an embedding yet aren't included
+func SemanticSearch(query string, from int64, to int64, k int) ([]db.SemanticMatch, error) {
+	api := embeddingAPI
+	if api == nil {
+		return nil, fmt.Errorf("semantic search is disabled; enable it and select an embedding API in settings")
+	}
+
+	return semanticSearch(api, query, from, to, k)
+}
+
+// Embeds the query and ranks captures against it. See SemanticSearch
+func semanticSearch(api models.EmbeddingAPI, query string, from int64, to int64, k int) ([]db.SemanticMatch, error) {
+	vectors, err := api.Embed([]string{query})
+	if err != nil {
+		return nil, fmt.Errorf("error embedding query: %w", err)
+	}
+
+	ranked, err := rankEmbeddings(api, db.EmbeddingSourceCapture, vectors[0], from, to, k)
+	if err != nil {
+		return nil, fmt.Errorf("error ranking embeddings: %w", err)
+	}
+
+	if len(ranked) == 0 {
+		return []db.SemanticMatch{}, nil
+	}
+
+	ids := make([]int, len(ranked))
+	for i, r := range ranked {
+		ids[i] = r.SourceID
+	}
+
+	caps, err := store.GetScreenshotByIds(ids)
+	if err != nil {
+		return nil, fmt.Errorf("error getting captures: %w", err)
+	}
+
+	descriptions := make(map[int]string, len(caps))
+	for _, cap := range caps {
+		if cap.Description != nil {
+			descriptions[cap.CaptureID] = *cap.Description
+		}
+	}
+
+	matches := make([]db.SemanticMatch, 0, len(ranked))
+	for _, r := range ranked {
+		desc, ok := descriptions[r.SourceID]
+		if !ok {
+			continue
+		}
+		matches = append(matches, db.SemanticMatch{
+			CaptureID:   r.SourceID,
+			Timestamp:   r.Timestamp,
+			Description: desc,
+			Score:       r.Score,
+		})
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,32 @@
+package llm
+
+import (
+	"context"
+	"recap/internal/db"
+	"recap/internal/models"
+	"time"
+)
+
+// Maximum time a provider check may take before it's reported as unreachable
+const healthCheckTimeout = 20 * time.Second
+
+// Checks whether the given API can be used with the given model. Pending holds settings that haven't been saved
+// yet, such as an API key, URL or headers being edited, and is applied over the saved configuration for the check
+// only. Returns the result of each step of the check, or an error if the API isn't registered or a pending setting
+// is invalid. The active vision and text models aren't changed
+func TestProvider(api string, model string, pending map[string]string) (*models.HealthCheckResult, error) {
+	factory, err := models.GetConfiguredAPI(api)
+	if err != nil {
+		return nil, err
+	}
+
+	conf, err := db.PendingConfig(pending)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+
+	return factory(model, conf).HealthCheck(ctx), nil
+}
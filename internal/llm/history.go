@@ -0,0 +1,231 @@
+package llm
+
+import (
+	"fmt"
+	"log"
+	"recap/internal/config"
+	"recap/internal/db"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Maximum number of descriptions and reports passed to the text model as context for a question
+const (
+	historyDescriptionLimit = 40
+	historyReportLimit      = 5
+)
+
+// Reports can be long, so only their beginning is passed as context
+const historyReportMaxLength = 2000
+
+// Matches citations the model is asked to write, e.g. "[capture 12]" or "[report 3]"
+var citationRegex = regexp.MustCompile(`(?i)\[(capture|report)\s+#?(\d+)\]`)
+
+// Common words that don't help find relevant descriptions
+var stopWords = map[string]bool{
+	"a": true, "about": true, "an": true, "and": true, "are": true, "at": true, "be": true, "did": true, "do": true,
+	"does": true, "for": true, "from": true, "have": true, "how": true, "i": true, "in": true, "is": true,
+	"it": true, "look": true, "looked": true, "me": true, "my": true, "of": true, "on": true, "or": true,
+	"the": true, "to": true, "was": true, "were": true, "what": true, "when": true, "where": true,
+	"which": true, "who": true, "with": true, "work": true, "worked": true, "working": true, "you": true,
+}
+
+var wordRegex = regexp.MustCompile(`[\p{L}\p{N}][\p{L}\p{N}_\-.]*`)
+
+// A capture or report that an answer is based on
+type HistoryCitation struct {
+	Kind      string `json:"Kind"` // "capture" or "report"
+	ID        int    `json:"ID"`
+	Timestamp int64  `json:"Timestamp"`
+}
+
+// The answer to a question about the user's history, along with the captures and reports it cites
+type HistoryAnswer struct {
+	Answer    string            `json:"Answer"`
+	Citations []HistoryCitation `json:"Citations"`
+}
+
+// Extracts search terms from a question, leaving out stop words and words that describe a time range
+func extractSearchTerms(question string) []string {
+	var terms []string
+	seen := map[string]bool{}
+
+	for _, word := range wordRegex.FindAllString(strings.ToLower(question), -1) {
+		word = strings.Trim(word, ".-")
+		if len(word) < 2 || stopWords[word] || timeRangeWords[word] || seen[word] {
+			continue
+		}
+		if _, err := strconv.Atoi(word); err == nil || isoDateRegex.MatchString(word) {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+	}
+
+	return terms
+}
+
+// Builds the prompt sent to the text model, listing the retrieved descriptions and reports
+// with their IDs and timestamps so the model can cite them
+func buildHistoryPrompt(question string, descs []db.CaptureDescription, reports []db.Report, now time.Time) string {
+	var sb strings.Builder
+
+	sb.WriteString("You are an assistant answering a user's question about their own computer activity. ")
+	sb.WriteString("Below are descriptions of screenshots captured on the user's computer and reports generated from them. ")
+	sb.WriteString("Answer the question using only this information. If it isn't enough to answer, say so. ")
+	sb.WriteString("Cite the entries your answer is based on by writing their tags exactly as given, e.g. [capture 12] or [report 3].\n")
+	sb.WriteString(fmt.Sprintf("The current time is %s.\n\n", now.Format("Monday, 2006-01-02 15:04")))
+
+	sb.WriteString("BEGIN SCREENSHOT DESCRIPTIONS\n")
+	for _, desc := range descs {
+		sb.WriteString(fmt.Sprintf("[capture %d] (%s) %s\n", desc.CaptureID, time.Unix(desc.Timestamp, 0).In(now.Location()).Format("Monday, 2006-01-02 15:04"), desc.Description))
+	}
+	sb.WriteString("END SCREENSHOT DESCRIPTIONS\n\n")
+
+	if len(reports) > 0 {
+		sb.WriteString("BEGIN REPORTS\n")
+		for _, rep := range reports {
+			sb.WriteString(fmt.Sprintf("[report %d] (%s) %s\n", rep.ReportID, config.StartOfDay(time.Unix(int64(rep.Timestamp), 0)).Format("Monday, 2006-01-02"), truncateString(rep.Content, historyReportMaxLength)))
+		}
+		sb.WriteString("END REPORTS\n\n")
+	}
+
+	sb.WriteString("QUESTION: ")
+	sb.WriteString(question)
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
+// Adds semantic search matches to keyword search results, skipping captures that are already included
+func mergeDescriptions(descs []db.CaptureDescription, matches []db.SemanticMatch) []db.CaptureDescription {
+	seen := make(map[int]bool, len(descs))
+	for _, desc := range descs {
+		seen[desc.CaptureID] = true
+	}
+
+	for _, match := range matches {
+		if seen[match.CaptureID] {
+			continue
+		}
+		seen[match.CaptureID] = true
+		descs = append(descs, db.CaptureDescription{
+			CaptureID:   match.CaptureID,
+			Timestamp:   match.Timestamp,
+			Description: match.Description,
+		})
+	}
+
+	return descs
+}
+
+// Finds the citations in an answer that refer to retrieved descriptions or reports, in the order they appear.
+// Citations of entries that weren't retrieved are ignored
+func parseCitations(answer string, descs []db.CaptureDescription, reports []db.Report) []HistoryCitation {
+	captureTimes := make(map[int]int64, len(descs))
+	for _, desc := range descs {
+		captureTimes[desc.CaptureID] = desc.Timestamp
+	}
+
+	reportTimes := make(map[int]int64, len(reports))
+	for _, rep := range reports {
+		reportTimes[rep.ReportID] = int64(rep.Timestamp)
+	}
+
+	citations := []HistoryCitation{}
+	seen := map[string]bool{}
+
+	for _, match := range citationRegex.FindAllStringSubmatch(answer, -1) {
+		kind := strings.ToLower(match[1])
+		id, err := strconv.Atoi(match[2])
+		if err != nil || seen[kind+match[2]] {
+			continue
+		}
+
+		var ts int64
+		var ok bool
+		if kind == "capture" {
+			ts, ok = captureTimes[id]
+		} else {
+			ts, ok = reportTimes[id]
+		}
+
+		if !ok {
+			continue
+		}
+
+		seen[kind+match[2]] = true
+		citations = append(citations, HistoryCitation{Kind: kind, ID: id, Timestamp: ts})
+	}
+
+	return citations
+}
+
+// Answers a question about the user's history. Descriptions and reports relevant to the question
+// are retrieved by parsing any time range it mentions and searching for its keywords, along with
+// semantic search if embeddings are enabled, then passed to the text model. Returns the answer along with the captures and reports it cites
+func AskHistory(question string) (*HistoryAnswer, error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return nil, fmt.Errorf("question is empty")
+	}
+
+	now := time.Now().In(config.Location())
+	var from, to int64
+	if start, end, ok := parseTimeRange(question, now); ok {
+		from, to = start.Unix(), end.Unix()
+	}
+
+	terms := extractSearchTerms(question)
+
+	descs, err := store.SearchDescriptions(terms, from, to, historyDescriptionLimit)
+	if err != nil {
+		return nil, fmt.Errorf("error searching descriptions: %w", err)
+	}
+
+	// Nothing matched the keywords; fall back to everything in the time range, if one was given
+	if len(descs) == 0 && len(terms) > 0 && (from > 0 || to > 0) {
+		descs, err = store.SearchDescriptions(nil, from, to, historyDescriptionLimit)
+		if err != nil {
+			return nil, fmt.Errorf("error searching descriptions: %w", err)
+		}
+	}
+
+	// Add captures that match the question's meaning but not its exact words
+	if api := embeddingAPI; api != nil {
+		matches, err := semanticSearch(api, question, from, to, historyDescriptionLimit/2)
+		if err != nil {
+			log.Printf("Semantic search failed, using keyword results only: %v", err)
+		}
+		descs = mergeDescriptions(descs, matches)
+	}
+
+	reports, err := store.SearchReports(terms, from, to, historyReportLimit)
+	if err != nil {
+		return nil, fmt.Errorf("error searching reports: %w", err)
+	}
+
+	if len(descs) == 0 && len(reports) == 0 {
+		return &HistoryAnswer{Answer: "No descriptions or reports matching your question were found.", Citations: []HistoryCitation{}}, nil
+	}
+
+	// Present the context chronologically so the model can reason about order
+	sort.SliceStable(descs, func(i, j int) bool {
+		return descs[i].Timestamp < descs[j].Timestamp
+	})
+
+	log.Printf("Answering question with %d descriptions and %d reports", len(descs), len(reports))
+
+	res, _, err := generateTextCached(textAPI, textParams, buildHistoryPrompt(question, descs, reports, now), false)
+	if err != nil {
+		return nil, fmt.Errorf("error generating text: %w", err)
+	}
+
+	return &HistoryAnswer{
+		Answer:    res,
+		Citations: parseCitations(res, descs, reports),
+	}, nil
+}
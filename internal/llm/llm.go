@@ -1,28 +1,47 @@
 package llm
 
 import (
-	"database/sql"
 	"fmt"
 	"log"
 	"recap/internal/config"
 	"recap/internal/db"
 	"recap/internal/models"
+	"recap/internal/preprocess"
+	"sort"
+	"strings"
 	"time"
 )
 
-var visionAPI models.TextVisionAPI
-var textAPI models.TextVisionAPI
+// Database the package reads captures from and saves descriptions, reports and cached responses to
+var store db.Store
 
-// Processes descriptions from screenshots into formatted context to be passed to the LLM
+var visionAPI *models.FallbackChain
+var textAPI *models.FallbackChain
+
+// Generation parameters the chains were set up with, which are part of the keys of cached responses
+var visionParams models.GenerationParams
+var textParams models.GenerationParams
+
+// Maximum number of vision requests sent before waiting for one minute
+const requestsPerMinute = 15
+
+// Processes descriptions from screenshots into formatted context to be passed to the LLM.
+// Descriptions are scrubbed of private information again, in case they were saved before redaction
+// was enabled or the redaction settings have changed since
 func preprocessContext(caps []db.CaptureDescription) string {
 	prompt := config.Config.ReportPrompt
+	redacted := 0
 
 	for _, cap := range caps {
+		desc, count := scrubText(cap.Description)
+		redacted += count
+
 		prompt += "BEGIN DESCRIPTION\n"
-		prompt += cap.Description
+		prompt += desc
 		prompt += "END DESCRIPTION\n"
 	}
 
+	recordRedactions(redacted)
 	return prompt
 }
 
@@ -30,16 +49,9 @@ func preprocessContext(caps []db.CaptureDescription) string {
 // It retrieves today's captures, processes them through AI for descriptions,
 // and logs the resulting report. Returns the ID of the logged report or an error.
 func GenerateDailyReport() (*int64, error) {
-	dbCl, err := db.CreateConnection()
-	if err != nil {
-		fmt.Println("Error creating database connection:", err)
-		return nil, err
-	}
-	defer dbCl.Close()
-
 	SendQueue() // Make sure all screenshots are described by AI
 
-	todayCaps, err := db.GetCapturesToday(dbCl)
+	todayCaps, err := store.GetCapturesToday()
 	if err != nil {
 		fmt.Println("Error getting unprocessed captures:", err)
 		return nil, err
@@ -52,27 +64,28 @@ func GenerateDailyReport() (*int64, error) {
 
 	finalPrompt := preprocessContext(todayCaps)
 
-	res, err := textAPI.GenerateText(finalPrompt)
+	res, producer, err := generateTextCached(textAPI, textParams, finalPrompt, false)
 	if err != nil {
-		log.Fatalln(err.Error())
-		// return nil, err
+		return nil, fmt.Errorf("error generating text: %w", err)
+	}
+
+	reportId, err := store.LogDailyReport(res, todayCaps, producer.GetAPIName(), producer.GetAPIModelName())
+	if err == nil {
+		BackfillEmbeddings()
 	}
+	pruneResponses()
 
-	return db.LogDailyReport(dbCl, res, todayCaps, config.Config.ReportAPI, config.Config.ReportModel)
+	return reportId, err
 }
 
 // Generates a report using a selected list of screenshot IDs.
 // It retrieves the specified captures, processes those that lack descriptions,
-// and generates a report based on the combined descriptions. Returns the ID of the logged report or an error.
-func GenerateReportWithSelectScr(ids []int) (*int64, error) {
+// and generates a report based on the combined descriptions. If bypassCache is true, cached responses aren't reused,
+// so the report is generated again. Returns the ID of the logged report or an error.
+func GenerateReportWithSelectScr(ids []int, bypassCache bool) (*int64, error) {
 	log.Println("Starting report generation")
-	dbCl, err := db.CreateConnection()
-	if err != nil {
-		return nil, fmt.Errorf("error creating database connection: %w", err)
-	}
-	defer dbCl.Close()
 
-	caps, err := db.GetScreenshotByIds(dbCl, ids)
+	caps, err := store.GetScreenshotByIds(ids)
 	if err != nil {
 		return nil, fmt.Errorf("error getting unprocessed captures: %w", err)
 	}
@@ -91,7 +104,7 @@ func GenerateReportWithSelectScr(ids []int) (*int64, error) {
 		}
 	}
 
-	newDescs, err := SendQueueFromObject(dbCl, toProcess)
+	newDescs, err := SendQueueFromObject(toProcess, bypassCache)
 	if err != nil {
 		return nil, fmt.Errorf("error sending queue: %w", err)
 	}
@@ -104,76 +117,163 @@ func GenerateReportWithSelectScr(ids []int) (*int64, error) {
 
 	finalPrompt := preprocessContext(descs)
 
-	res, err := textAPI.GenerateText(finalPrompt)
+	res, producer, err := generateTextCached(textAPI, textParams, finalPrompt, bypassCache)
 	if err != nil {
 		return nil, fmt.Errorf("error generating text: %w", err)
 	}
 
 	log.Println("Logging report")
-	return db.LogDailyReport(dbCl, res, descs, config.Config.ReportAPI, config.Config.ReportModel)
+	reportId, err := store.LogDailyReport(res, descs, producer.GetAPIName(), producer.GetAPIModelName())
+	if err == nil {
+		BackfillEmbeddings()
+	}
+	pruneResponses()
+
+	return reportId, err
 }
 
 // Processes a batch of screenshot captures to generate descriptions.
 // It describes each screenshot using the vision model and updates the descriptions in the database.
+// If bypassCache is true, cached descriptions aren't reused.
 // Returns a slice of CaptureDescription with the generated descriptions or an error.
-func SendQueueFromObject(dbCl *sql.DB, scrs []db.CaptureScreenshot) ([]db.CaptureDescription, error) {
-	if dbCl == nil {
-		newCl, err := db.CreateConnection()
-		if err != nil {
-			return nil, fmt.Errorf("failed to create database connection: %w", err)
-		}
-		defer newCl.Close()
-		dbCl = newCl
-	}
-
+func SendQueueFromObject(scrs []db.CaptureScreenshot, bypassCache bool) ([]db.CaptureDescription, error) {
 	if scrs == nil {
 		fmt.Printf("input slice of CaptureScreenshot is nil, returning nil. Everything good")
 		return nil, nil
 	}
 
+	returnQ := describeCaptures(scrs, bypassCache)
+
+	log.Printf("Queue processing completed. Processed %d items.", len(returnQ))
+	return returnQ, nil
+}
+
+// Builds the vision prompt for the next capture. If previous descriptions are given, they're appended
+// along with their timestamps so the model can tell ongoing work apart from a switch to something new
+func buildDescPrompt(history []db.CaptureDescription) string {
+	prompt := config.Config.DescGenPrompt
+	if len(history) == 0 {
+		return prompt
+	}
+
+	prompt += "\n\nFor context, these are descriptions of the user's previous screenshots, oldest first. "
+	prompt += "Use them to tell whether the user is still working on the same thing or has switched to something else. "
+	prompt += "Only describe the screenshots attached to this request.\n"
+	prompt += "BEGIN PREVIOUS DESCRIPTIONS\n"
+	for _, desc := range history {
+		prompt += fmt.Sprintf("[%s] %s\n", time.Unix(desc.Timestamp, 0).In(config.Location()).Format("2006-01-02 15:04"), desc.Description)
+	}
+	prompt += "END PREVIOUS DESCRIPTIONS\n"
+
+	return prompt
+}
+
+// Describes captures with the vision model and writes the descriptions to the database.
+// Captures are processed in chronological order. If DescGenContextCount is set, the last descriptions before each
+// capture are included in its prompt as context. Consecutive captures are grouped into requests of DescGenBatchSize screenshots each; if a batched
+// request fails or its response can't be split per screenshot, the batch is retried one screenshot at a time.
+// No more than requestsPerMinute requests are sent before waiting for one minute. Descriptions are reused from the
+// response cache unless bypassCache is true.
+// Returns the descriptions that were generated successfully
+func describeCaptures(scrs []db.CaptureScreenshot, bypassCache bool) []db.CaptureDescription {
 	var returnQ []db.CaptureDescription
-	batchSize := 15
+	batchSize := max(config.Config.DescGenBatchSize, 1)
+	contextCount := max(config.Config.DescGenContextCount, 0)
+	requestCount := 0
 
-	for i := 0; i < len(scrs); i += batchSize {
-		end := min(i+batchSize, len(scrs))
-		processingQueue := scrs[i:end]
+	var history []db.CaptureDescription
 
-		fmt.Println("Starting batch processing")
+	waitForRateLimit := func() {
+		if requestCount > 0 && requestCount%requestsPerMinute == 0 {
+			log.Println("Waiting for 1 minute before processing next batch...")
+			time.Sleep(time.Minute)
+		}
+		requestCount++
+	}
 
-		for _, cap := range processingQueue {
-			if cap.Filename == "" {
-				log.Printf("Warning: Empty filename for capture ID %d, skipping", cap.CaptureID)
-				continue
-			}
+	saveDescription := func(cap db.CaptureScreenshot, res string, producer models.TextVisionAPI) {
+		res, redacted := scrubText(res)
+		recordRedactions(redacted)
 
-			res, err := visionAPI.DescribeScreenshot(cap.Filename, config.Config.DescGenPrompt)
-			if err != nil {
-				log.Printf("Error processing file %s: %v", cap.Filename, err)
-				continue
-			}
+		newDescObj := db.CaptureDescription{
+			CaptureID:   cap.CaptureID,
+			Timestamp:   cap.Timestamp,
+			Description: res,
+		}
+		returnQ = append(returnQ, newDescObj)
 
-			newDescObj := db.CaptureDescription{
-				CaptureID:   cap.CaptureID,
-				Timestamp:   cap.Timestamp,
-				Description: res,
-			}
-			returnQ = append(returnQ, newDescObj)
+		if contextCount > 0 {
+			history = append(history, newDescObj)
+			history = history[max(len(history)-contextCount, 0):]
+		}
+
+		fmt.Printf("Processed capture ID %d: %s\n", cap.CaptureID, truncateString(newDescObj.Description, 50))
+
+		if _, err := store.UpdateScreenshotDescription(cap.CaptureID, res, producer.GetAPIName(), producer.GetAPIModelName()); err != nil {
+			log.Printf("Error updating description for capture %d: %v", cap.CaptureID, err)
+		}
+	}
+
+	describeOne := func(cap db.CaptureScreenshot) {
+		waitForRateLimit()
+		res, producer, err := describeScreenshotsCached(visionAPI, visionParams, []string{cap.Filename}, buildDescPrompt(history), bypassCache)
+		if err != nil {
+			log.Printf("Error processing file %s: %v", cap.Filename, err)
+			return
+		}
+		saveDescription(cap, res[0], producer)
+	}
+
+	var valid []db.CaptureScreenshot
+	for _, cap := range scrs {
+		if cap.Filename == "" {
+			log.Printf("Warning: Empty filename for capture ID %d, skipping", cap.CaptureID)
+			continue
+		}
+		valid = append(valid, cap)
+	}
 
-			fmt.Printf("Processed capture ID %d: %s\n", cap.CaptureID, truncateString(newDescObj.Description, 50))
+	sort.SliceStable(valid, func(i, j int) bool {
+		return valid[i].Timestamp < valid[j].Timestamp
+	})
+
+	if contextCount > 0 && len(valid) > 0 {
+		prev, err := store.GetDescriptionsBefore(valid[0].Timestamp, contextCount)
+		if err != nil {
+			log.Printf("Error getting previous descriptions for context: %v", err)
+		}
+		history = prev
+	}
+
+	for i := 0; i < len(valid); i += batchSize {
+		batch := valid[i:min(i+batchSize, len(valid))]
+
+		if len(batch) == 1 {
+			describeOne(batch[0])
+			continue
+		}
+
+		fileNames := make([]string, len(batch))
+		for j, cap := range batch {
+			fileNames[j] = cap.Filename
+		}
 
-			if _, err := db.UpdateScreenshotDescription(dbCl, cap.CaptureID, res, config.Config.DescGenAPI, config.Config.DescGenModel); err != nil {
-				log.Printf("Error updating description for capture %d: %v", cap.CaptureID, err)
+		waitForRateLimit()
+		results, producer, err := describeScreenshotsCached(visionAPI, visionParams, fileNames, buildDescPrompt(history), bypassCache)
+		if err != nil {
+			log.Printf("Error processing batch of %d screenshots, retrying one at a time: %v", len(batch), err)
+			for _, cap := range batch {
+				describeOne(cap)
 			}
+			continue
 		}
 
-		if end < len(scrs) {
-			log.Println("Waiting for 1 minute before processing next batch...")
-			time.Sleep(time.Minute)
+		for j, cap := range batch {
+			saveDescription(cap, results[j], producer)
 		}
 	}
 
-	log.Printf("Queue processing completed. Processed %d items.", len(returnQ))
-	return returnQ, nil
+	return returnQ
 }
 
 // Truncates a string to a specified maximum length and appends ellipsis if truncated.
@@ -193,91 +293,78 @@ func min(a, b int) int {
 	return b
 }
 
-// Processes unprocessed captures from the database in batches,
+// Processes unprocessed captures from the database in chronological order and in batches,
 // generates descriptions using the vision model, and updates the database.
 // Waits for one minute between batches if necessary.
 func SendQueue() {
-	dbCl, err := db.CreateConnection()
-	if err != nil {
-		fmt.Println("Error creating database connection:", err)
-		return
-	}
-	defer dbCl.Close()
 	// defer instance.AppInstance.SendLLMRanMessage()
 
-	fullQueue, err := db.GetUnprocessedCaptures(dbCl)
+	fullQueue, err := store.GetUnprocessedCaptures()
 	if err != nil {
 		fmt.Println("Error getting unprocessed captures:", err)
 		return
 	}
 
-	for i := 0; i < len(fullQueue); i += 15 {
-		end := i + 15
-		if end > len(fullQueue) {
-			end = len(fullQueue)
-		}
-		processingQueue := fullQueue[i:end]
-
-		for _, cap := range processingQueue {
-			res, err := visionAPI.DescribeScreenshot(cap.Filename, config.Config.DescGenPrompt)
-			if err != nil {
-				fmt.Printf("Error processing file %s: %v\n", cap.Filename, err)
-				continue
-			}
-
-			// Update the database with the Gemini response
-			_, err = db.UpdateScreenshotDescription(dbCl, cap.CaptureID, res, config.Config.DescGenAPI, config.Config.DescGenModel)
-			if err != nil {
-				fmt.Printf("Error updating description for capture %d: %v\n", cap.CaptureID, err)
-			}
-		}
-
-		// If there are more items to process, wait for one minute
-		if end < len(fullQueue) {
-			fmt.Println("Waiting for 1 minute before processing next batch...")
-			time.Sleep(time.Minute)
-		}
-	}
+	describeCaptures(fullQueue, false)
+	preprocess.PruneCache()
+	pruneResponses()
 
 	fmt.Println("Queue processing completed")
+	BackfillEmbeddings()
 }
 
-// Sets up the vision and text models based on configuration settings.
-// It selects the appropriate API clients for image description and report generation.
-func Initialize() {
-	selectedVisionAPI := config.Config.DescGenAPI
-	selectedVisionModelName := config.Config.DescGenModel
+// Builds a fallback chain starting with the selected API and model, followed by the entries of the fallback list,
+// and sets the generation parameters of its APIs. If the selected API isn't registered, Ollama is used in its place
+func buildChain(selectedAPI string, selectedModelName string, fallbackList string, params models.GenerationParams) *models.FallbackChain {
+	if _, err := models.GetAPI(selectedAPI); err != nil {
+		fmt.Printf("COULD NOT FIND MODEL: %s\nSwitching to Ollama API as a fallback\n", selectedAPI)
+		selectedAPI = "Ollama"
+	}
 
-	selectedTextAPI := config.Config.ReportAPI
-	selectedTextModelName := config.Config.ReportModel
+	entries := []models.FallbackEntry{{API: selectedAPI, Model: selectedModelName}}
 
-	visionAPIFactory, err := models.GetAPI(selectedVisionAPI)
+	fallbacks, err := models.ParseFallbackList(fallbackList)
 	if err != nil {
-		fmt.Printf("COULD NOT FIND MODEL: %s\nSwitching to Ollama API as a fallback\n", selectedVisionAPI)
-
-		visionAPIFactory, err = models.GetAPI("Ollama")
-		if err != nil {
-			log.Fatalf("Could not switch to Ollama fallback!")
-		}
+		fmt.Printf("Ignoring fallback list %q: %v\n", fallbackList, err)
 	}
+	entries = append(entries, fallbacks...)
 
-	visionAPI = visionAPIFactory(selectedVisionModelName)
+	chain, err := models.NewFallbackChain(entries)
+	if err != nil {
+		log.Fatalf("Could not switch to Ollama fallback!")
+	}
 
-	if selectedVisionModelName == selectedTextModelName {
-		textAPI = visionAPI
-	} else {
-		textModelFactory, err := models.GetAPI(selectedTextAPI)
-		if err != nil {
-			fmt.Printf("COULD NOT FIND MODEL: %s\nSwitching to Ollama API as a fallback\n", selectedTextAPI)
+	// APIs that don't accept the parameters are still used, with their defaults
+	if err := chain.SetGenerationParams(params); err != nil {
+		fmt.Printf("Using default generation parameters where they aren't accepted: %v\n", err)
+	}
 
-			textModelFactory, err = models.GetAPI("Ollama")
-			if err != nil {
-				log.Fatalf("Could not switch to Ollama fallback!")
-			}
-		}
+	return chain
+}
 
-		textAPI = textModelFactory(selectedTextModelName)
+// Returns a readable list of the APIs and models in a chain, e.g. "Gemini gemini-1.5-flash, then Ollama llava"
+func describeChain(chain *models.FallbackChain) string {
+	var names []string
+	for _, api := range chain.APIs() {
+		names = append(names, api.GetAPIName()+" "+api.GetAPIModelName())
 	}
+	return strings.Join(names, ", then ")
+}
+
+// Sets up the vision and text models based on configuration settings.
+// It selects the appropriate API clients for image description and report generation,
+// each followed by the fallbacks configured for it.
+func Initialize(dbStore db.Store) {
+	store = dbStore
+
+	visionParams = models.NewGenerationParams(config.Config.DescGenTemperature, config.Config.DescGenTopP, config.Config.DescGenMaxTokens)
+	textParams = models.NewGenerationParams(config.Config.ReportTemperature, config.Config.ReportTopP, config.Config.ReportMaxTokens)
+
+	visionAPI = buildChain(config.Config.DescGenAPI, config.Config.DescGenModel, config.Config.DescGenFallback, visionParams)
+	textAPI = buildChain(config.Config.ReportAPI, config.Config.ReportModel, config.Config.ReportFallback, textParams)
+
+	fmt.Printf("Using %s for vision and %s for text\n", describeChain(visionAPI), describeChain(textAPI))
 
-	fmt.Printf("Using %s %s for vision and %s %s for text\n", selectedVisionAPI, selectedVisionModelName, selectedTextAPI, selectedTextModelName)
+	initializeEmbeddings()
+	BackfillEmbeddings()
 }
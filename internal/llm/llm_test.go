@@ -0,0 +1,437 @@
+package llm
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"recap/internal/config"
+	"recap/internal/db"
+	"recap/internal/models"
+	_ "recap/internal/models/mock"
+	_ "recap/internal/models/ollama"
+	"recap/internal/secrets"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Opens an in-memory store with the Mock API selected for descriptions and reports, and sets the package up with it
+func setupTestStore(t *testing.T) db.Store {
+	t.Helper()
+
+	secrets.UseStore(secrets.NewMemoryStore())
+	t.Setenv("RECAP_MOCK_FIXTURES", "")
+
+	testStore, err := db.OpenMemory()
+	if err != nil {
+		t.Fatalf("could not open in-memory store: %v", err)
+	}
+	t.Cleanup(func() { testStore.Close() })
+
+	config.Config.ScrPath = t.TempDir()
+	config.Config.DescGenAPI = "Mock"
+	config.Config.DescGenModel = "mock-vision"
+	config.Config.DescGenFallback = ""
+	config.Config.DescGenBatchSize = 1
+	config.Config.DescGenContextCount = 0
+	config.Config.ReportAPI = "Mock"
+	config.Config.ReportModel = "mock-text"
+	config.Config.ReportFallback = ""
+	config.Config.EmbeddingEnabled = 0
+	config.Config.ResponseCacheEnabled = 1
+
+	Initialize(testStore)
+	return testStore
+}
+
+// Saves count small screenshots with different colors and inserts a capture for each. Returns the capture IDs
+func insertTestCaptures(t *testing.T, testStore db.Store, count int) []int {
+	t.Helper()
+
+	ids := make([]int, count)
+	for i := range ids {
+		img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+		for p := 0; p < len(img.Pix); p += 4 {
+			img.Pix[p], img.Pix[p+1], img.Pix[p+2], img.Pix[p+3] = uint8(i*40), 100, 200, 255
+		}
+		img.Set(i, i, color.Black)
+
+		fileName := fmt.Sprintf("capture_%d.png", i)
+		f, err := os.Create(filepath.Join(config.Config.ScrPath, fileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := png.Encode(f, img); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+
+		ids[i] = int(testStore.InsertCapture([]db.FullThumbScrPair{{Full: fileName, Thumb: fileName}}))
+	}
+
+	return ids
+}
+
+func writeReportFixture(t *testing.T, dir string, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(dir, "report.txt"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSendQueueDescribesUnprocessedCaptures(t *testing.T) {
+	for _, batchSize := range []int{1, 2} {
+		t.Run(fmt.Sprintf("batch size %d", batchSize), func(t *testing.T) {
+			testStore := setupTestStore(t)
+			config.Config.DescGenBatchSize = batchSize
+			ids := insertTestCaptures(t, testStore, 3)
+
+			SendQueue()
+
+			queue, err := testStore.GetUnprocessedCaptures()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(queue) != 0 {
+				t.Fatalf("expected no unprocessed captures, got %d", len(queue))
+			}
+
+			for _, id := range ids {
+				cap, err := testStore.GetScreenshotById(id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if cap.Description == nil || !strings.Contains(*cap.Description, "mock description") {
+					t.Errorf("capture %d has unexpected description %v", id, cap.Description)
+				}
+				if cap.GenWithApi == nil || *cap.GenWithApi != "Mock" || cap.GenWithModel == nil || *cap.GenWithModel != "mock-vision" {
+					t.Errorf("capture %d wasn't attributed to Mock mock-vision", id)
+				}
+			}
+		})
+	}
+}
+
+func TestSendQueueSkipsDescribedCaptures(t *testing.T) {
+	testStore := setupTestStore(t)
+	ids := insertTestCaptures(t, testStore, 2)
+
+	if _, err := testStore.UpdateScreenshotDescription(ids[0], "already described", "Other", "other-model"); err != nil {
+		t.Fatal(err)
+	}
+
+	SendQueue()
+
+	caps, err := testStore.GetScreenshotByIds(ids[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(caps) != 1 || caps[0].Description == nil || *caps[0].Description != "already described" {
+		t.Errorf("described capture was described again: %+v", caps)
+	}
+}
+
+func TestGenerateReportWithSelectScr(t *testing.T) {
+	testStore := setupTestStore(t)
+	ids := insertTestCaptures(t, testStore, 3)
+
+	reportId, err := GenerateReportWithSelectScr(ids[:2], false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reportId == nil {
+		t.Fatal("expected a report ID")
+	}
+
+	report, err := testStore.GetReportById(int(*reportId))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(report.Content, "Generated from 2 descriptions") {
+		t.Errorf("report wasn't generated from the two selected captures:\n%s", report.Content)
+	}
+	if report.GenWithApi != "Mock" || report.GenWithModel != "mock-text" {
+		t.Errorf("report attributed to %s %s, expected Mock mock-text", report.GenWithApi, report.GenWithModel)
+	}
+
+	// Only the selected captures are described
+	queue, err := testStore.GetUnprocessedCaptures()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 1 || queue[0].CaptureID != ids[2] {
+		t.Errorf("expected only capture %d to be left undescribed, got %+v", ids[2], queue)
+	}
+}
+
+func TestReportsShareCaptures(t *testing.T) {
+	testStore := setupTestStore(t)
+	ids := insertTestCaptures(t, testStore, 3)
+
+	first, err := GenerateReportWithSelectScr(ids[:2], false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := GenerateReportWithSelectScr(ids[1:], true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A second report from the same capture doesn't take it away from the first
+	for _, reportId := range []int64{*first, *second} {
+		scrs, err := testStore.GetScreenshotsForReport(int(reportId))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(scrs) != 2 {
+			t.Errorf("expected report %d to be linked to 2 captures, got %d", reportId, len(scrs))
+		}
+	}
+
+	reports, err := testStore.GetReportsForScreenshot(ids[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 2 {
+		t.Errorf("expected capture %d to be linked to 2 reports, got %d", ids[1], len(reports))
+	}
+
+	// Deleting a report leaves the other one's captures linked
+	if err := testStore.DeleteReportsById([]int{int(*first)}); err != nil {
+		t.Fatal(err)
+	}
+	reports, err = testStore.GetReportsForScreenshot(ids[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 || int64(reports[0].ReportID) != *second {
+		t.Errorf("expected capture %d to be linked to report %d only, got %+v", ids[1], *second, reports)
+	}
+}
+
+func TestGenerateReportWithNoCaptures(t *testing.T) {
+	setupTestStore(t)
+
+	if _, err := GenerateReportWithSelectScr([]int{}, false); err == nil {
+		t.Error("expected an error when no captures are selected")
+	}
+}
+
+func TestGenerateReportReusesCachedResponse(t *testing.T) {
+	testStore := setupTestStore(t)
+	ids := insertTestCaptures(t, testStore, 2)
+
+	fixtures := t.TempDir()
+	t.Setenv("RECAP_MOCK_FIXTURES", fixtures)
+
+	writeReportFixture(t, fixtures, "first report")
+	if _, err := GenerateReportWithSelectScr(ids, false); err != nil {
+		t.Fatal(err)
+	}
+
+	writeReportFixture(t, fixtures, "second report")
+
+	cachedId, err := GenerateReportWithSelectScr(ids, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached, err := testStore.GetReportById(int(*cachedId))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached.Content != "first report" {
+		t.Errorf("expected the cached report, got %q", cached.Content)
+	}
+
+	regeneratedId, err := GenerateReportWithSelectScr(ids, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	regenerated, err := testStore.GetReportById(int(*regeneratedId))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if regenerated.Content != "second report" {
+		t.Errorf("expected bypassing the cache to generate a new report, got %q", regenerated.Content)
+	}
+}
+
+func TestPruneResponseCacheKeepsNewestWithinLimit(t *testing.T) {
+	testStore := setupTestStore(t)
+
+	for i, response := range []string{"oldest response", "middle response", "newest response"} {
+		key := db.ResponseCacheKey{PromptHash: fmt.Sprint(i), API: "Mock", Model: "mock-text"}
+		if err := testStore.SaveCachedResponse(key, response); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleted, err := testStore.PruneResponseCache(time.Hour, int64(len("newest response")+len("middle response")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 response to be deleted, got %d", deleted)
+	}
+
+	for i, kept := range []bool{false, true, true} {
+		key := db.ResponseCacheKey{PromptHash: fmt.Sprint(i), API: "Mock", Model: "mock-text"}
+		if _, ok, err := testStore.GetCachedResponse(key, time.Hour); err != nil || ok != kept {
+			t.Errorf("response %d: expected kept to be %v, got %v, %v", i, kept, ok, err)
+		}
+	}
+}
+
+func TestCacheKeyIncludesBaseURL(t *testing.T) {
+	setupTestStore(t)
+	factory, err := models.GetAPI("Ollama")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config.Config.OllamaURL = "http://localhost:11434"
+	local := cacheKey(factory("llava"), models.GenerationParams{}, "", "prompt")
+	config.Config.OllamaURL = "http://gpu-server:11434/"
+	remote := cacheKey(factory("llava"), models.GenerationParams{}, "", "prompt")
+
+	if local.Params == remote.Params {
+		t.Errorf("expected different Ollama URLs to give different cache keys, both gave %q", local.Params)
+	}
+}
+
+func TestGenerateDailyReport(t *testing.T) {
+	testStore := setupTestStore(t)
+	insertTestCaptures(t, testStore, 2)
+
+	reportId, err := GenerateDailyReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reportId == nil {
+		t.Fatal("expected a report to be generated from today's captures")
+	}
+
+	report, err := testStore.GetReportById(int(*reportId))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(report.Content, "Generated from 2 descriptions") {
+		t.Errorf("report wasn't generated from today's captures:\n%s", report.Content)
+	}
+
+	// Captures are only included in one daily report
+	reportId, err = GenerateDailyReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reportId != nil {
+		t.Errorf("expected no report without new captures, got report %d", *reportId)
+	}
+}
+
+func TestDescriptionsAreScrubbed(t *testing.T) {
+	testStore := setupTestStore(t)
+	ids := insertTestCaptures(t, testStore, 1)
+
+	fixtures := t.TempDir()
+	t.Setenv("RECAP_MOCK_FIXTURES", fixtures)
+	if err := os.WriteFile(filepath.Join(fixtures, "capture_0.txt"), []byte("Writing to jane.doe@example.com"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config.Config.ScrubEnabled = 1
+	config.Config.ScrubDetectors = "email"
+
+	SendQueue()
+
+	caps, err := testStore.GetScreenshotByIds(ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(caps) != 1 || caps[0].Description == nil {
+		t.Fatalf("capture wasn't described: %+v", caps)
+	}
+	if strings.Contains(*caps[0].Description, "jane.doe@example.com") {
+		t.Errorf("email address wasn't redacted: %q", *caps[0].Description)
+	}
+
+	info, err := testStore.ReadInfo("RedactedItemCount")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Value != "1" {
+		t.Errorf("expected 1 redacted item to be counted, got %s", info.Value)
+	}
+}
+
+func TestParseTimeRangeUsesConfiguredDayStart(t *testing.T) {
+	setupTestStore(t)
+	config.Config.Timezone = "America/New_York"
+	config.Config.DayStartsAt = "04:00"
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+
+	// 02:30 on Saturday in New York, which still belongs to Friday
+	now := time.Date(2024, 10, 5, 6, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		question string
+		from, to time.Time
+	}{
+		{"what did I do today?", time.Date(2024, 10, 4, 4, 0, 0, 0, newYork), now},
+		{"what did I do yesterday?", time.Date(2024, 10, 3, 4, 0, 0, 0, newYork), time.Date(2024, 10, 4, 4, 0, 0, 0, newYork)},
+		{"what was I doing on 2024-10-01?", time.Date(2024, 10, 1, 4, 0, 0, 0, newYork), time.Date(2024, 10, 2, 4, 0, 0, 0, newYork)},
+		{"what did I work on this week?", time.Date(2024, 9, 30, 4, 0, 0, 0, newYork), now},
+	}
+
+	for _, test := range tests {
+		from, to, ok := parseTimeRange(test.question, now)
+		if !ok {
+			t.Errorf("%q: no time range found", test.question)
+			continue
+		}
+		if !from.Equal(test.from) || !to.Equal(test.to) {
+			t.Errorf("%q: expected %s to %s, got %s to %s", test.question, test.from, test.to, from, to)
+		}
+	}
+}
+
+func TestProviderUsesPendingSettings(t *testing.T) {
+	setupTestStore(t)
+	config.Config.OpenAIAPIKey = "sk-saved-openai-key"
+	config.Config.CustomOpenAIURL = "http://saved.example"
+
+	conf, err := db.PendingConfig(map[string]string{
+		"OpenAIAPIKey":    secrets.Mask("sk-saved-openai-key"),
+		"CustomOpenAIURL": "http://pending.example",
+	})
+	if err != nil {
+		t.Fatalf("PendingConfig failed: %v", err)
+	}
+	if conf.OpenAIAPIKey != "sk-saved-openai-key" {
+		t.Errorf("expected the masked key to keep the saved key, got %q", conf.OpenAIAPIKey)
+	}
+	if conf.CustomOpenAIURL != "http://pending.example" {
+		t.Errorf("expected the pending URL, got %q", conf.CustomOpenAIURL)
+	}
+	if config.Config.CustomOpenAIURL != "http://saved.example" {
+		t.Errorf("expected the saved configuration to be unchanged, got %q", config.Config.CustomOpenAIURL)
+	}
+
+	if _, err := TestProvider("Mock", "mock-vision", map[string]string{"OllamaNumCtx": "lots"}); err == nil {
+		t.Error("expected an invalid pending setting to be rejected")
+	}
+
+	result, err := TestProvider("Mock", "mock-vision", map[string]string{"CustomOpenAIURL": "http://pending.example"})
+	if err != nil {
+		t.Fatalf("TestProvider failed: %v", err)
+	}
+	if result == nil {
+		t.Fatal("expected a health check result")
+	}
+}
@@ -0,0 +1,136 @@
+package llm
+
+import (
+	"log"
+	"recap/internal/config"
+	"regexp"
+	"strings"
+)
+
+// A pattern for one kind of private information, and the placeholder it's replaced with
+type piiDetector struct {
+	Name        string
+	Placeholder string
+	Regex       *regexp.Regexp
+	Validate    func(match string) bool // Optional, rejects matches that only look like this kind of information
+}
+
+// Detectors run in this order, so more specific patterns go first; e.g. a JWT shouldn't be left
+// partly redacted by a broader pattern. Names are the values accepted by the ScrubDetectors setting
+var piiDetectors = []piiDetector{
+	{Name: "jwt", Placeholder: "[JWT]", Regex: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{5,}`)},
+	{Name: "github", Placeholder: "[GITHUB_TOKEN]", Regex: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`)},
+	{Name: "aws", Placeholder: "[AWS_KEY]", Regex: regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`)},
+	{Name: "email", Placeholder: "[EMAIL]", Regex: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)},
+	{Name: "creditcard", Placeholder: "[CREDIT_CARD]", Regex: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), Validate: passesLuhn},
+	{Name: "ip", Placeholder: "[IP_ADDRESS]", Regex: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\.){3}(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\b`)},
+	{Name: "phone", Placeholder: "[PHONE]", Regex: regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{3}\)|\b\d{3})[ .-]\d{3}[ .-]\d{4}\b|\+\d{1,3}(?:[ .-]?\d{2,4}){3,4}\b`)},
+}
+
+// Placeholder for words from the user's ScrubWords list
+const scrubWordPlaceholder = "[REDACTED]"
+
+// Reports whether a string of digits, ignoring spaces and dashes, passes the Luhn checksum used by card numbers
+func passesLuhn(number string) bool {
+	sum := 0
+	double := false
+	digits := 0
+
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+		digits++
+	}
+
+	return digits >= 13 && sum%10 == 0
+}
+
+// Splits a comma-separated setting into its trimmed, non-empty items
+func splitSettingList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Builds a pattern matching any word from the user's word list as a whole word, ignoring case.
+// Returns nil if the list is empty
+func buildWordListRegex(words []string) *regexp.Regexp {
+	if len(words) == 0 {
+		return nil
+	}
+
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = regexp.QuoteMeta(word)
+	}
+
+	return regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)
+}
+
+// Replaces private information in text with placeholders such as [EMAIL], using the detectors enabled
+// in ScrubDetectors and the words in ScrubWords. Returns the scrubbed text and the number of items redacted.
+// Text is returned unchanged if ScrubEnabled is off
+func scrubText(text string) (string, int) {
+	if config.Config.ScrubEnabled != 1 {
+		return text, 0
+	}
+
+	enabled := map[string]bool{}
+	for _, name := range splitSettingList(config.Config.ScrubDetectors) {
+		enabled[strings.ToLower(name)] = true
+	}
+
+	count := 0
+
+	for _, det := range piiDetectors {
+		if !enabled[det.Name] {
+			continue
+		}
+
+		text = det.Regex.ReplaceAllStringFunc(text, func(match string) string {
+			if det.Validate != nil && !det.Validate(match) {
+				return match
+			}
+			count++
+			return det.Placeholder
+		})
+	}
+
+	if wordListRegex := buildWordListRegex(splitSettingList(config.Config.ScrubWords)); wordListRegex != nil {
+		text = wordListRegex.ReplaceAllStringFunc(text, func(string) string {
+			count++
+			return scrubWordPlaceholder
+		})
+	}
+
+	return text, count
+}
+
+// Adds to the stored count of redacted items, logging instead of failing if it can't be saved
+func recordRedactions(count int) {
+	if count == 0 {
+		return
+	}
+
+	log.Printf("Redacted %d items of private information", count)
+
+	if err := store.AddRedactedItemCount(count); err != nil {
+		log.Printf("Error saving redacted item count: %v", err)
+	}
+}
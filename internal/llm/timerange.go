@@ -0,0 +1,92 @@
+package llm
+
+import (
+	"recap/internal/config"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	lastNRegex   = regexp.MustCompile(`\b(?:last|past)\s+(\d+)\s+(day|week|month)s?\b`)
+	isoDateRegex = regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})\b`)
+	weekdayRegex = regexp.MustCompile(`\b(?:on|last)?\s*(monday|tuesday|wednesday|thursday|friday|saturday|sunday)\b`)
+)
+
+// Words that describe a time range rather than what the user is looking for. Removed from search terms
+var timeRangeWords = map[string]bool{
+	"today": true, "yesterday": true, "week": true, "weeks": true, "month": true, "months": true,
+	"day": true, "days": true, "last": true, "past": true, "this": true, "ago": true,
+	"monday": true, "tuesday": true, "wednesday": true, "thursday": true, "friday": true, "saturday": true, "sunday": true,
+}
+
+// Returns when the week t falls in started. Weeks start on Monday, at the configured start of day
+func startOfWeek(t time.Time) time.Time {
+	today := config.StartOfDay(t)
+	y, m, d := today.Date()
+	offset := (int(today.Weekday()) + 6) % 7
+	return config.DayStart(y, m, d-offset)
+}
+
+// Finds a time range mentioned in a question, such as "yesterday", "last week", "past 3 days",
+// "on Monday" or "2024-10-05". Returns the start and end of the range, with the end being exclusive.
+// Days start at the configured time in the configured time zone. ok is false if the question doesn't mention a time range
+func parseTimeRange(question string, now time.Time) (from time.Time, to time.Time, ok bool) {
+	q := strings.ToLower(question)
+	today := config.StartOfDay(now)
+	ty, tm, td := today.Date()
+
+	if m := isoDateRegex.FindStringSubmatch(q); m != nil {
+		y, _ := strconv.Atoi(m[1])
+		mo, _ := strconv.Atoi(m[2])
+		d, _ := strconv.Atoi(m[3])
+		return config.DayStart(y, time.Month(mo), d), config.DayStart(y, time.Month(mo), d+1), true
+	}
+
+	if m := lastNRegex.FindStringSubmatch(q); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "day":
+			return config.DayStart(ty, tm, td-n+1), now, true
+		case "week":
+			return config.DayStart(ty, tm, td-7*n), now, true
+		case "month":
+			return config.DayStart(ty, tm-time.Month(n), td), now, true
+		}
+	}
+
+	switch {
+	case strings.Contains(q, "yesterday"):
+		return config.DayStart(ty, tm, td-1), today, true
+	case strings.Contains(q, "today"), strings.Contains(q, "this morning"), strings.Contains(q, "this afternoon"):
+		return today, now, true
+	case strings.Contains(q, "last week"):
+		week := startOfWeek(now)
+		wy, wm, wd := week.Date()
+		return config.DayStart(wy, wm, wd-7), week, true
+	case strings.Contains(q, "this week"):
+		return startOfWeek(now), now, true
+	case strings.Contains(q, "last month"):
+		return config.DayStart(ty, tm-1, 1), config.DayStart(ty, tm, 1), true
+	case strings.Contains(q, "this month"):
+		return config.DayStart(ty, tm, 1), now, true
+	}
+
+	if m := weekdayRegex.FindStringSubmatch(q); m != nil {
+		for wd := time.Sunday; wd <= time.Saturday; wd++ {
+			if strings.ToLower(wd.String()) != m[1] {
+				continue
+			}
+
+			// Most recent occurrence of the weekday before today
+			offset := (int(today.Weekday()) - int(wd) + 7) % 7
+			if offset == 0 {
+				offset = 7
+			}
+			return config.DayStart(ty, tm, td-offset), config.DayStart(ty, tm, td-offset+1), true
+		}
+	}
+
+	return time.Time{}, time.Time{}, false
+}
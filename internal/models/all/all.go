@@ -1,7 +1,10 @@
 package all
 
 import (
+	_ "recap/internal/models/anthropic"
+	_ "recap/internal/models/custom"
 	_ "recap/internal/models/gemini"
+	_ "recap/internal/models/mock"
 	_ "recap/internal/models/ollama"
 	_ "recap/internal/models/openai"
 	_ "recap/internal/models/openrouter"
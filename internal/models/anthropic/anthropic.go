@@ -0,0 +1,278 @@
+package anthropic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"recap/internal/config"
+	"recap/internal/models"
+	"recap/internal/utils"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	apiVersion = "2023-06-01"
+
+	// The Messages API requires max_tokens, so this is sent unless another limit is set
+	defaultMaxTokens = 4096
+	maxTemperature   = 1
+
+	// Overloaded and rate limited requests are retried this many times before giving up,
+	// waiting for the time the API asks for, up to maxRetryWait
+	maxRetries   = 2
+	maxRetryWait = 30 * time.Second
+)
+
+type AIModel struct {
+	ApiName      string
+	ApiKeyPtr    *string
+	client       *http.Client
+	clientTicker *time.Ticker
+	Endpoint     string // Base URL of the API. Can point to a local server for testing
+	Model        string
+	params       models.GenerationParams
+	mu           sync.Mutex
+}
+
+// Starts or resets a timer that closes the HTTP client
+// after 5 minutes of inactivity. If the client is already active, it resets the timer.
+func (a *AIModel) startClientDeadline() {
+	if a.clientTicker != nil {
+		a.clientTicker.Reset(5 * time.Minute)
+		fmt.Println("Resetting deadline timer")
+		return
+	}
+
+	a.clientTicker = time.NewTicker(5 * time.Minute)
+	fmt.Println("Created deadline timer")
+
+	go func() {
+		for { // nolint: all
+			select {
+			case <-a.clientTicker.C:
+				a.mu.Lock()
+				if a.client != nil {
+					fmt.Println("Closing client due to inactivity.")
+					a.client.CloseIdleConnections()
+					a.client = nil
+					a.clientTicker.Stop()
+					a.clientTicker = nil // Set to nil after stopping
+				}
+				a.mu.Unlock()
+				return
+			}
+		}
+	}()
+}
+
+// Generates text based on the provided prompt using the AI client.
+// It returns the generated text or an error if client creation fails.
+func (a *AIModel) GenerateText(prompt string) (string, error) {
+	client := a.generateClient()
+	if client == nil {
+		return "", fmt.Errorf("failed to create client")
+	}
+	defer a.startClientDeadline()
+
+	return a.sendToAnthropic(client, nil, prompt)
+}
+
+// Generates a description for a screenshot specified by its filename
+// using the AI client. It returns the description or an error if client creation fails.
+func (a *AIModel) DescribeScreenshot(fileName string, prompt string) (string, error) {
+	client := a.generateClient()
+	if client == nil {
+		return "", fmt.Errorf("failed to create client")
+	}
+	defer a.startClientDeadline()
+
+	return a.sendToAnthropic(client, []string{fileName}, prompt)
+}
+
+// Generates descriptions for multiple screenshots provided in the fileNames slice
+// with a single request. It returns one description per screenshot or an error.
+func (a *AIModel) DescribeBulkScreenshots(fileNames []string, prompt string) ([]string, error) {
+	client := a.generateClient()
+	if client == nil {
+		return nil, fmt.Errorf("failed to create client")
+	}
+	defer a.startClientDeadline()
+
+	res, err := a.sendToAnthropic(client, fileNames, models.BuildBulkPrompt(prompt, len(fileNames)))
+	if err != nil {
+		return nil, fmt.Errorf("error sending files to Anthropic: %w", err)
+	}
+
+	return models.ParseBulkResponse(res, len(fileNames))
+}
+
+// Builds the content of a message from the screenshots, as base64 image blocks, followed by the prompt
+func buildContent(fileNames []string, prompt string) ([]ContentBlock, error) {
+	content := make([]ContentBlock, 0, len(fileNames)+1)
+
+	for _, fileName := range fileNames {
+		dataURL := utils.ReadImageToBase64(fileName)
+		header, data, ok := strings.Cut(dataURL, ",")
+		if !ok {
+			return nil, fmt.Errorf("failed to read image file %s", fileName)
+		}
+
+		mediaType := strings.TrimSuffix(strings.TrimPrefix(header, "data:"), ";base64")
+		content = append(content, ContentBlock{Type: "image", Source: &ImageSource{Type: "base64", MediaType: mediaType, Data: data}})
+	}
+
+	content = append(content, ContentBlock{Type: "text", Text: prompt})
+	return content, nil
+}
+
+// Sets the headers every request to the API needs
+func (a *AIModel) setHeaders(req *http.Request) {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", *a.ApiKeyPtr)
+	req.Header.Set("anthropic-version", apiVersion)
+}
+
+// Converts an error response to *models.APIError, using the error type and message from the body if it has one
+func parseErrorResponse(apiName string, statusCode int, body []byte) *models.APIError {
+	apiErr := &models.APIError{API: apiName, StatusCode: statusCode, Message: string(body)}
+
+	var errRes ErrorResponse
+	if err := json.Unmarshal(body, &errRes); err == nil && errRes.Error.Message != "" {
+		apiErr.Code = errRes.Error.Type
+		apiErr.Message = errRes.Error.Message
+	}
+
+	return apiErr
+}
+
+// Returns how long to wait before retrying an overloaded or rate limited request, from the retry-after header
+// if the API sent one, or with exponential backoff otherwise
+func retryWait(res *http.Response, attempt int) time.Duration {
+	if seconds, err := strconv.Atoi(res.Header.Get("retry-after")); err == nil && seconds >= 0 {
+		return min(time.Duration(seconds)*time.Second, maxRetryWait)
+	}
+	return min(time.Duration(1<<attempt)*time.Second, maxRetryWait)
+}
+
+// Sends a request to the Messages API with the screenshots (if any file names are given) and the prompt.
+// Requests that fail because the API is overloaded (529) or rate limited (429) are retried a few times.
+// It returns the text of the response or an error. Error statuses are returned as *models.APIError
+func (a *AIModel) sendToAnthropic(client *http.Client, fileNames []string, prompt string) (string, error) {
+	content, err := buildContent(fileNames, prompt)
+	if err != nil {
+		return "", err
+	}
+
+	maxTokens := defaultMaxTokens
+	if a.params.MaxTokens > 0 {
+		maxTokens = a.params.MaxTokens
+	}
+
+	requestBody := MessagesRequest{
+		Model:       a.Model,
+		MaxTokens:   maxTokens,
+		Messages:    []Message{{Role: "user", Content: content}},
+		Temperature: a.params.Temperature,
+		TopP:        a.params.TopP,
+	}
+
+	preparedBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling JSON request body: %w", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest("POST", strings.TrimSuffix(a.Endpoint, "/")+"/v1/messages", bytes.NewBuffer(preparedBody))
+		if err != nil {
+			return "", fmt.Errorf("error creating request to Anthropic: %w", err)
+		}
+		a.setHeaders(req)
+
+		res, err := client.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("error sending request to Anthropic: %w", err)
+		}
+
+		readRes, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return "", fmt.Errorf("error reading response from Anthropic: %w", err)
+		}
+
+		if res.StatusCode < 200 || res.StatusCode >= 300 {
+			apiErr := parseErrorResponse(a.ApiName, res.StatusCode, readRes)
+
+			retryable := res.StatusCode == http.StatusTooManyRequests || apiErr.Code == "overloaded_error" || res.StatusCode == 529
+			if retryable && attempt < maxRetries {
+				wait := retryWait(res, attempt)
+				fmt.Printf("Anthropic request failed (%s), retrying in %v\n", apiErr.Message, wait)
+				time.Sleep(wait)
+				continue
+			}
+
+			return "", apiErr
+		}
+
+		var messagesResponse MessagesResponse
+		if err := json.Unmarshal(readRes, &messagesResponse); err != nil {
+			return "", fmt.Errorf("%w: error decoding Anthropic response: %v", models.ErrBadResponse, err)
+		}
+
+		var sb strings.Builder
+		for _, block := range messagesResponse.Content {
+			if block.Type == "text" {
+				sb.WriteString(block.Text)
+			}
+		}
+
+		fmt.Printf("%s %s used %d input and %d output tokens\n", a.ApiName, a.Model, messagesResponse.Usage.InputTokens, messagesResponse.Usage.OutputTokens)
+		if messagesResponse.StopReason == "max_tokens" {
+			fmt.Printf("Warning: Anthropic response was cut off at %d tokens\n", maxTokens)
+		}
+
+		return sb.String(), nil
+	}
+}
+
+// Creates and returns a new HTTP client if one does not already exist.
+// It ensures thread-safe access to the client instance.
+func (a *AIModel) generateClient() *http.Client {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.client == nil {
+		a.client = models.NewHTTPClient(a.ApiName)
+	}
+
+	return a.client
+}
+
+func (a *AIModel) SetGenerationParams(params models.GenerationParams) error {
+	if err := params.Validate(a.ApiName, maxTemperature); err != nil {
+		return err
+	}
+	a.params = params
+	return nil
+}
+
+func (a *AIModel) GetAPIName() string {
+	return a.ApiName
+}
+
+func (a *AIModel) GetAPIModelName() string {
+	return a.Model
+}
+
+// Initializes a new AIModel instance with the specified model name, using the API key in conf.
+func CreateAPIClient(model string, conf *config.AppConfig) models.TextVisionAPI {
+	return &AIModel{ApiName: "Anthropic", Endpoint: "https://api.anthropic.com", Model: model, ApiKeyPtr: &conf.AnthropicAPIKey}
+}
+
+func init() {
+	models.RegisterAPI("Anthropic", CreateAPIClient)
+}
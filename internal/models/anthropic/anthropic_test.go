@@ -0,0 +1,159 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"errors"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"recap/internal/config"
+	"recap/internal/models"
+	"sync/atomic"
+	"testing"
+)
+
+const testReply = `{"id": "msg_1", "model": "claude-test", "content": [{"type": "text", "text": "A code editor"}], "stop_reason": "end_turn", "usage": {"input_tokens": 10, "output_tokens": 3}}`
+
+// Starts a stand-in for the Messages API that answers the nth request with replies[n], repeating the last reply
+// once they run out. Returns the model pointed at it and the number of requests it received
+func newTestModel(t *testing.T, replies ...func(w http.ResponseWriter, r *http.Request)) (*AIModel, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(requests.Add(1)) - 1
+		replies[min(n, len(replies)-1)](w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	key := "test-key"
+	return &AIModel{ApiName: "Anthropic", Endpoint: server.URL, Model: "claude-test", ApiKeyPtr: &key}, &requests
+}
+
+func reply(status int, body string) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("retry-after", "0")
+		w.WriteHeader(status)
+		w.Write([]byte(body)) // nolint: errcheck
+	}
+}
+
+func TestDescribeScreenshotSendsImageBlock(t *testing.T) {
+	config.Config.ScrPath = t.TempDir()
+	file, err := os.Create(filepath.Join(config.Config.ScrPath, "capture.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(file, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	var received MessagesRequest
+	model, _ := newTestModel(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" || r.Header.Get("x-api-key") != "test-key" || r.Header.Get("anthropic-version") != apiVersion {
+			t.Errorf("unexpected request to %s with headers %v", r.URL.Path, r.Header)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("could not decode request: %v", err)
+		}
+		reply(http.StatusOK, testReply)(w, r)
+	})
+
+	res, err := model.DescribeScreenshot("capture.png", "Describe this")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != "A code editor" {
+		t.Errorf("expected the text of the reply, got %q", res)
+	}
+
+	if len(received.Messages) != 1 || len(received.Messages[0].Content) != 2 {
+		t.Fatalf("expected one message with an image and a text block, got %+v", received.Messages)
+	}
+	img, text := received.Messages[0].Content[0], received.Messages[0].Content[1]
+	if img.Type != "image" || img.Source == nil || img.Source.Type != "base64" || img.Source.MediaType != "image/png" || img.Source.Data == "" {
+		t.Errorf("expected a base64 PNG image block, got %+v", img)
+	}
+	if text.Type != "text" || text.Text != "Describe this" {
+		t.Errorf("expected the prompt as a text block, got %+v", text)
+	}
+	if received.MaxTokens != defaultMaxTokens {
+		t.Errorf("expected max_tokens to default to %d, got %d", defaultMaxTokens, received.MaxTokens)
+	}
+}
+
+func TestRateLimitedAndOverloadedRequestsAreRetried(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"rate limited", http.StatusTooManyRequests, `{"type": "error", "error": {"type": "rate_limit_error", "message": "Slow down"}}`},
+		{"overloaded", 529, `{"type": "error", "error": {"type": "overloaded_error", "message": "Overloaded"}}`},
+	}
+
+	for _, test := range tests {
+		model, requests := newTestModel(t, reply(test.status, test.body), reply(http.StatusOK, testReply))
+
+		res, err := model.GenerateText("Summarize")
+		if err != nil {
+			t.Errorf("%s: expected the retry to succeed, got %v", test.name, err)
+			continue
+		}
+		if res != "A code editor" || requests.Load() != 2 {
+			t.Errorf("%s: expected the reply after one retry, got %q after %d requests", test.name, res, requests.Load())
+		}
+	}
+}
+
+func TestErrorResponsesAreClassified(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		code     string
+		class    models.ErrorClass
+		attempts int32
+	}{
+		{"still overloaded", 529, `{"type": "error", "error": {"type": "overloaded_error", "message": "Overloaded"}}`, "overloaded_error", models.ErrorClassUnavailable, maxRetries + 1},
+		{"invalid key", http.StatusUnauthorized, `{"type": "error", "error": {"type": "authentication_error", "message": "invalid x-api-key"}}`, "authentication_error", models.ErrorClassAuth, 1},
+		{"unknown model", http.StatusNotFound, `{"type": "error", "error": {"type": "not_found_error", "message": "model: claude-test"}}`, "not_found_error", models.ErrorClassNotFound, 1},
+		{"plain text error", http.StatusInternalServerError, "Internal Server Error", "", models.ErrorClassUnavailable, 1},
+	}
+
+	for _, test := range tests {
+		model, requests := newTestModel(t, reply(test.status, test.body))
+
+		_, err := model.GenerateText("Summarize")
+		var apiErr *models.APIError
+		if !errors.As(err, &apiErr) {
+			t.Errorf("%s: expected *models.APIError, got %v", test.name, err)
+			continue
+		}
+		if apiErr.StatusCode != test.status || apiErr.Code != test.code {
+			t.Errorf("%s: expected status %d and code %q, got %d and %q", test.name, test.status, test.code, apiErr.StatusCode, apiErr.Code)
+		}
+		if class := models.ClassifyError(err); class != test.class {
+			t.Errorf("%s: expected class %s, got %s", test.name, test.class, class)
+		}
+		if requests.Load() != test.attempts {
+			t.Errorf("%s: expected %d requests, got %d", test.name, test.attempts, requests.Load())
+		}
+	}
+}
+
+func TestMalformedResponseIsBadResponse(t *testing.T) {
+	model, _ := newTestModel(t, reply(http.StatusOK, `{"content": [`))
+
+	_, err := model.GenerateText("Summarize")
+	if !errors.Is(err, models.ErrBadResponse) {
+		t.Errorf("expected ErrBadResponse, got %v", err)
+	}
+	if class := models.ClassifyError(err); class != models.ErrorClassBadResponse {
+		t.Errorf("expected class %s, got %s", models.ErrorClassBadResponse, class)
+	}
+}
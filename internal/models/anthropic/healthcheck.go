@@ -0,0 +1,138 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"recap/internal/models"
+	"slices"
+	"strings"
+)
+
+// Models that only accept text. Every model since Claude 3 accepts images
+var textOnlyPrefixes = []string{"claude-2", "claude-instant"}
+
+// Sends an authenticated GET request to a path under the API's base URL and decodes the JSON response into out
+func (a *AIModel) getJSON(ctx context.Context, client *http.Client, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(a.Endpoint, "/")+path, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request to %s: %w", a.ApiName, err)
+	}
+	a.setHeaders(req)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending request to %s: %w", a.ApiName, err)
+	}
+	defer res.Body.Close()
+
+	readRes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response from %s: %w", a.ApiName, err)
+	}
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return parseErrorResponse(a.ApiName, res.StatusCode, readRes)
+	}
+
+	if err := json.Unmarshal(readRes, out); err != nil {
+		return fmt.Errorf("error decoding %s response: %w", a.ApiName, err)
+	}
+
+	return nil
+}
+
+// Lists every model, following the pagination of the /v1/models endpoint
+func (a *AIModel) fetchModels(ctx context.Context, client *http.Client) ([]ModelEntry, error) {
+	var entries []ModelEntry
+	afterID := ""
+
+	for {
+		path := "/v1/models?limit=1000"
+		if afterID != "" {
+			path += "&after_id=" + url.QueryEscape(afterID)
+		}
+
+		var page ModelsResponse
+		if err := a.getJSON(ctx, client, path, &page); err != nil {
+			return nil, err
+		}
+		entries = append(entries, page.Data...)
+
+		if !page.HasMore || page.LastID == "" {
+			return entries, nil
+		}
+		afterID = page.LastID
+	}
+}
+
+// Returns whether a model ID or alias refers to a listed model. Aliases such as claude-3-5-sonnet-latest
+// aren't listed, so they match any model whose ID starts with the alias without its -latest suffix
+func modelMatches(id string, model string) bool {
+	if id == model {
+		return true
+	}
+	alias, ok := strings.CutSuffix(model, "-latest")
+	return ok && strings.HasPrefix(id, alias+"-")
+}
+
+func getVisionSupport(model string) models.VisionSupport {
+	for _, prefix := range textOnlyPrefixes {
+		if strings.HasPrefix(model, prefix) {
+			return models.VisionUnsupported
+		}
+	}
+	return models.VisionSupported
+}
+
+// Checks that the API is reachable, that the API key is accepted, that the model exists,
+// and whether it supports images
+func (a *AIModel) HealthCheck(ctx context.Context) *models.HealthCheckResult {
+	result := models.NewHealthCheckResult(a.ApiName, a.Model)
+
+	list, err := a.fetchModels(ctx, models.NewHTTPClient(a.ApiName))
+	if err != nil {
+		if models.ClassifyError(err) == models.ErrorClassAuth {
+			result.Add(models.HealthCheckReachable, models.HealthCheckPassed, "%s is reachable", a.ApiName)
+			result.Fail(models.HealthCheckCredentials, models.DescribeHealthCheckError(err), models.HealthCheckModel, models.HealthCheckVision)
+		} else {
+			result.Fail(models.HealthCheckReachable, models.DescribeHealthCheckError(err), models.HealthCheckCredentials, models.HealthCheckModel, models.HealthCheckVision)
+		}
+		return result.Finish()
+	}
+	result.Add(models.HealthCheckReachable, models.HealthCheckPassed, "%s is reachable", a.ApiName)
+	result.Add(models.HealthCheckCredentials, models.HealthCheckPassed, "API key was accepted")
+
+	if !slices.ContainsFunc(list, func(m ModelEntry) bool { return modelMatches(m.ID, a.Model) }) {
+		result.Fail(models.HealthCheckModel, fmt.Errorf("model %s isn't available on %s", a.Model, a.ApiName), models.HealthCheckVision)
+		return result.Finish()
+	}
+	result.Add(models.HealthCheckModel, models.HealthCheckPassed, "Model %s is available", a.Model)
+
+	if getVisionSupport(a.Model) == models.VisionSupported {
+		result.Add(models.HealthCheckVision, models.HealthCheckPassed, "Model supports images")
+	} else {
+		result.Add(models.HealthCheckVision, models.HealthCheckFailed, "Model doesn't support images and can only be used for reports")
+	}
+
+	return result.Finish()
+}
+
+// Lists the models available from the API's /v1/models endpoint
+func (a *AIModel) ListModels(ctx context.Context) ([]models.ModelInfo, error) {
+	entries, err := a.fetchModels(ctx, models.NewHTTPClient(a.ApiName))
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]models.ModelInfo, len(entries))
+	for i, m := range entries {
+		list[i] = models.ModelInfo{Name: m.ID, Vision: getVisionSupport(m.ID)}
+	}
+
+	slices.SortFunc(list, func(x, y models.ModelInfo) int { return strings.Compare(x.Name, y.Name) })
+	return list, nil
+}
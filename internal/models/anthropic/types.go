@@ -0,0 +1,60 @@
+package anthropic
+
+type ImageSource struct {
+	Type      string `json:"type"` // Always "base64"
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+// A content block of a message, either text or an image
+type ContentBlock struct {
+	Type   string       `json:"type"` // "text" or "image"
+	Text   string       `json:"text,omitempty"`
+	Source *ImageSource `json:"source,omitempty"`
+}
+
+type Message struct {
+	Role    string         `json:"role"`
+	Content []ContentBlock `json:"content"`
+}
+
+type MessagesRequest struct {
+	Model       string    `json:"model"`
+	MaxTokens   int       `json:"max_tokens"`
+	Messages    []Message `json:"messages"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	TopP        *float64  `json:"top_p,omitempty"`
+}
+
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type MessagesResponse struct {
+	ID         string         `json:"id"`
+	Model      string         `json:"model"`
+	Content    []ContentBlock `json:"content"`
+	StopReason string         `json:"stop_reason"`
+	Usage      Usage          `json:"usage"`
+}
+
+// Error body returned along with error statuses
+type ErrorResponse struct {
+	Type  string `json:"type"`
+	Error struct {
+		Type    string `json:"type"` // e.g. "overloaded_error" or "rate_limit_error"
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type ModelEntry struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"display_name"`
+}
+
+type ModelsResponse struct {
+	Data    []ModelEntry `json:"data"`
+	HasMore bool         `json:"has_more"`
+	LastID  string       `json:"last_id"`
+}
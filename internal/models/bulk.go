@@ -0,0 +1,63 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Matches the per-image headers the model is asked to write, e.g. "### IMAGE 2"
+var bulkHeaderRegex = regexp.MustCompile(`(?im)^[ \t#*]*IMAGE[ \t]+(\d+)[ \t]*[:.)*#-]*`)
+
+// Wraps the description prompt with instructions for describing multiple images in a single request.
+// The model is asked to write one section per image, each starting with a numbered header, so that
+// ParseBulkResponse can map the output back to the images in the order they were sent
+func BuildBulkPrompt(prompt string, count int) string {
+	var sb strings.Builder
+
+	sb.WriteString(prompt)
+	sb.WriteString("\n\n")
+	sb.WriteString(fmt.Sprintf("You are given %d images, in the order they were captured. ", count))
+	sb.WriteString("Describe each image separately. Begin each description with a header line in the form \"### IMAGE n\", ")
+	sb.WriteString(fmt.Sprintf("where n is the position of the image, starting from 1 and ending at %d. ", count))
+	sb.WriteString("Do not write anything before the first header.")
+
+	return sb.String()
+}
+
+// Splits a response produced with a BuildBulkPrompt prompt into one description per image.
+// Returns an error if the response does not contain exactly one non-empty section for each of the count images
+func ParseBulkResponse(response string, count int) ([]string, error) {
+	matches := bulkHeaderRegex.FindAllStringSubmatchIndex(response, -1)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%w: response does not contain any image headers", ErrBadResponse)
+	}
+
+	descriptions := make([]string, count)
+
+	for i, match := range matches {
+		idx, err := strconv.Atoi(response[match[2]:match[3]])
+		if err != nil || idx < 1 || idx > count {
+			return nil, fmt.Errorf("%w: response contains an invalid image header: %q", ErrBadResponse, response[match[0]:match[1]])
+		}
+
+		end := len(response)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+
+		if descriptions[idx-1] != "" {
+			return nil, fmt.Errorf("%w: response describes image %d more than once", ErrBadResponse, idx)
+		}
+		descriptions[idx-1] = strings.TrimSpace(response[match[1]:end])
+	}
+
+	for i, desc := range descriptions {
+		if desc == "" {
+			return nil, fmt.Errorf("%w: response is missing a description for image %d", ErrBadResponse, i+1)
+		}
+	}
+
+	return descriptions, nil
+}
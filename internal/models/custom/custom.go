@@ -0,0 +1,59 @@
+package custom
+
+import (
+	"log"
+	"recap/internal/config"
+	"recap/internal/models"
+	"recap/internal/models/openai"
+	"strings"
+)
+
+/**
+Many local servers, such as LM Studio, llama.cpp's server, vLLM and LocalAI, expose an OpenAI-compatible API.
+This creates an OpenAI API connector instance pointing to a server set in the settings, with an optional
+API key, extra headers, and a setting for whether its models accept images, as these servers don't report it
+*/
+
+// Parses extra headers given as "Name: value" entries separated by semicolons. Invalid entries are skipped
+func parseHeaders(list string) map[string]string {
+	headers := make(map[string]string)
+
+	for _, item := range strings.Split(list, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(item, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			log.Printf("Skipping invalid header %q, expected Name: value", item)
+			continue
+		}
+
+		headers[name] = strings.TrimSpace(value)
+	}
+
+	return headers
+}
+
+// Initializes a new AIModel instance with the specified model name, using the URL, API key and headers in conf.
+func CreateAPIClient(model string, conf *config.AppConfig) models.TextVisionAPI {
+	vision := models.VisionUnsupported
+	if conf.CustomOpenAIVision == 1 {
+		vision = models.VisionSupported
+	}
+
+	return &openai.AIModel{
+		ApiName:   "Custom OpenAI-compatible",
+		Endpoint:  strings.TrimSuffix(conf.CustomOpenAIURL, "/") + "/chat/completions",
+		Headers:   parseHeaders(conf.CustomOpenAIHeaders),
+		Vision:    vision,
+		Model:     model,
+		ApiKeyPtr: &conf.CustomOpenAIAPIKey,
+	}
+}
+
+func init() {
+	models.RegisterAPI("Custom OpenAI-compatible", CreateAPIClient)
+}
@@ -0,0 +1,13 @@
+package models
+
+type EmbeddingAPI interface {
+	// Get this API's name
+	GetAPIName() string
+
+	// Get this API's embedding model name
+	GetAPIModelName() string
+
+	// Generates embedding vectors for the given texts. Returns one vector per text, in the same
+	// order as texts, or an error if one is received
+	Embed(texts []string) ([][]float32, error)
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// ErrorClass groups API errors by what caused them, which decides whether a request should be
+// retried with another provider
+type ErrorClass string
+
+const (
+	ErrorClassRateLimited    ErrorClass = "rate_limited"    // Too many requests or quota exhausted
+	ErrorClassUnavailable    ErrorClass = "unavailable"     // Provider is down, overloaded, unreachable or timed out
+	ErrorClassAuth           ErrorClass = "auth"            // API key is missing or invalid
+	ErrorClassNotFound       ErrorClass = "not_found"       // Model doesn't exist for this provider
+	ErrorClassInvalidRequest ErrorClass = "invalid_request" // Provider rejected the request itself
+	ErrorClassBadResponse    ErrorClass = "bad_response"    // Response couldn't be used, e.g. a bulk response that couldn't be split
+	ErrorClassUnknown        ErrorClass = "unknown"
+)
+
+// APIError is returned by connectors when a provider responds with an error status
+type APIError struct {
+	API        string
+	StatusCode int
+	Code       string // Provider-specific error code, e.g. "insufficient_quota", if the provider returned one
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("%s API returned status %d (%s): %s", e.API, e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("%s API returned status %d: %s", e.API, e.StatusCode, e.Message)
+}
+
+// Returned when a response was received but couldn't be used
+var ErrBadResponse = errors.New("unusable response")
+
+// Classifies an HTTP status code returned by a provider
+func ClassifyStatusCode(code int) ErrorClass {
+	switch {
+	case code == http.StatusTooManyRequests:
+		return ErrorClassRateLimited
+	case code == http.StatusUnauthorized, code == http.StatusForbidden:
+		return ErrorClassAuth
+	case code == http.StatusNotFound:
+		return ErrorClassNotFound
+	case code == http.StatusRequestTimeout, code >= 500:
+		return ErrorClassUnavailable
+	case code >= 400:
+		return ErrorClassInvalidRequest
+	default:
+		return ErrorClassUnknown
+	}
+}
+
+// Returns the class of an error returned by a TextVisionAPI
+func ClassifyError(err error) ErrorClass {
+	var apiErr *APIError
+	var netErr net.Error
+
+	switch {
+	case err == nil:
+		return ErrorClassUnknown
+	case errors.As(err, &apiErr):
+		return ClassifyStatusCode(apiErr.StatusCode)
+	case errors.Is(err, ErrBadResponse):
+		return ErrorClassBadResponse
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr):
+		return ErrorClassUnavailable
+	default:
+		return ErrorClassUnknown
+	}
+}
+
+// Reports whether a request that failed with an error of this class may succeed with another provider.
+// Bad responses aren't retried elsewhere, as the caller can usually recover by changing the request
+func (c ErrorClass) ShouldFailover() bool {
+	return c != ErrorClassBadResponse
+}
@@ -2,20 +2,31 @@ package models
 
 import (
 	"fmt"
+	"recap/internal/config"
 	"sync"
 )
 
 // TextVisionAPIFactory is a type alias for clarity
 type TextVisionAPIFactory func(string) TextVisionAPI
 
+// Creates a TextVisionAPI for the given model that reads its API key, URL and headers from conf.
+// Connectors are registered with one, so they can be tried with settings that haven't been saved yet
+type ConfiguredAPIFactory func(model string, conf *config.AppConfig) TextVisionAPI
+
+// EmbeddingAPIFactory creates an EmbeddingAPI for the given model name
+type EmbeddingAPIFactory func(string) EmbeddingAPI
+
 var (
-	registeredAPIs = make(map[string]TextVisionAPIFactory)
+	registeredAPIs = make(map[string]ConfiguredAPIFactory)
 	APIList        []string
 	apiMutex       sync.RWMutex
+
+	registeredEmbeddingAPIs = make(map[string]EmbeddingAPIFactory)
+	EmbeddingAPIList        []string
 )
 
 // RegisterAPI safely registers a new API factory
-func RegisterAPI(name string, factory TextVisionAPIFactory) {
+func RegisterAPI(name string, factory ConfiguredAPIFactory) {
 	apiMutex.Lock()
 	defer apiMutex.Unlock()
 
@@ -31,8 +42,18 @@ func RegisterAPI(name string, factory TextVisionAPIFactory) {
 	APIList = append(APIList, name)
 }
 
-// GetAPI safely retrieves a registered API factory
+// GetAPI safely retrieves a registered API factory. The APIs it creates use the saved configuration
 func GetAPI(name string) (TextVisionAPIFactory, error) {
+	factory, err := GetConfiguredAPI(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(model string) TextVisionAPI { return factory(model, &config.Config) }, nil
+}
+
+// Retrieves a registered API factory that creates APIs using the given configuration instead of the saved one
+func GetConfiguredAPI(name string) (ConfiguredAPIFactory, error) {
 	apiMutex.RLock()
 	defer apiMutex.RUnlock()
 
@@ -51,3 +72,41 @@ func ListRegisteredAPIs() []string {
 
 	return APIList
 }
+
+// RegisterEmbeddingAPI safely registers a new embedding API factory
+func RegisterEmbeddingAPI(name string, factory EmbeddingAPIFactory) {
+	apiMutex.Lock()
+	defer apiMutex.Unlock()
+
+	if factory == nil {
+		panic("Attempted to register nil embedding factory for " + name)
+	}
+
+	if _, exists := registeredEmbeddingAPIs[name]; exists {
+		panic("Attempted to register duplicate embedding API: " + name)
+	}
+
+	registeredEmbeddingAPIs[name] = factory
+	EmbeddingAPIList = append(EmbeddingAPIList, name)
+}
+
+// GetEmbeddingAPI safely retrieves a registered embedding API factory
+func GetEmbeddingAPI(name string) (EmbeddingAPIFactory, error) {
+	apiMutex.RLock()
+	defer apiMutex.RUnlock()
+
+	factory, ok := registeredEmbeddingAPIs[name]
+	if !ok {
+		return nil, fmt.Errorf("embedding API not found: %s", name)
+	}
+
+	return factory, nil
+}
+
+// ListRegisteredEmbeddingAPIs returns a list of all registered embedding API names
+func ListRegisteredEmbeddingAPIs() []string {
+	apiMutex.RLock()
+	defer apiMutex.RUnlock()
+
+	return EmbeddingAPIList
+}
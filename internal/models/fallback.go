@@ -0,0 +1,221 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long a provider is skipped after failing with an error that's likely to persist for a while
+var fallbackCooldowns = map[ErrorClass]time.Duration{
+	ErrorClassRateLimited: time.Minute,
+	ErrorClassUnavailable: time.Minute,
+	ErrorClassAuth:        10 * time.Minute,
+	ErrorClassNotFound:    10 * time.Minute,
+}
+
+// An API and model in a fallback chain
+type FallbackEntry struct {
+	API   string
+	Model string
+}
+
+// FallbackChain sends each request to its APIs in order, moving on to the next one when a request fails with an
+// error that another provider may not run into. Providers that are rate limited, down, or misconfigured are
+// skipped for a while, so later requests don't wait on them. FallbackChain implements TextVisionAPI, identifying
+// as its first API; use the *WithProducer methods to find out which API produced a response
+type FallbackChain struct {
+	apis          []TextVisionAPI
+	cooldownUntil []time.Time
+	mu            sync.Mutex
+}
+
+// Parses a comma-separated list of "API:model" entries, e.g. "OpenRouter:google/gemini-flash-1.5, Ollama:llava:13b".
+// The API name ends at the first colon, so model names may contain colons. Empty entries are ignored
+func ParseFallbackList(list string) ([]FallbackEntry, error) {
+	var entries []FallbackEntry
+
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		api, model, ok := strings.Cut(item, ":")
+		api, model = strings.TrimSpace(api), strings.TrimSpace(model)
+		if !ok || api == "" || model == "" {
+			return nil, fmt.Errorf("invalid fallback entry %q, expected API:model", item)
+		}
+
+		entries = append(entries, FallbackEntry{API: api, Model: model})
+	}
+
+	return entries, nil
+}
+
+// Creates a fallback chain from entries, in order. Entries with unregistered APIs are skipped with a warning.
+// Returns an error if none of the entries can be used
+func NewFallbackChain(entries []FallbackEntry) (*FallbackChain, error) {
+	chain := &FallbackChain{}
+
+	for _, entry := range entries {
+		factory, err := GetAPI(entry.API)
+		if err != nil {
+			log.Printf("Skipping %s %s in fallback chain: %v", entry.API, entry.Model, err)
+			continue
+		}
+		chain.apis = append(chain.apis, factory(entry.Model))
+	}
+
+	if len(chain.apis) == 0 {
+		return nil, fmt.Errorf("no usable APIs in fallback chain")
+	}
+
+	chain.cooldownUntil = make([]time.Time, len(chain.apis))
+	return chain, nil
+}
+
+// Returns the APIs in the chain, in order
+func (c *FallbackChain) APIs() []TextVisionAPI {
+	return c.apis
+}
+
+// Returns the indexes of APIs to try, in order. APIs on cooldown are moved to the back rather than
+// left out, so a request is still attempted if every API is cooling down
+func (c *FallbackChain) attemptOrder() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	order := make([]int, 0, len(c.apis))
+	var cooling []int
+
+	for i := range c.apis {
+		if now.Before(c.cooldownUntil[i]) {
+			cooling = append(cooling, i)
+		} else {
+			order = append(order, i)
+		}
+	}
+
+	return append(order, cooling...)
+}
+
+func (c *FallbackChain) setCooldown(i int, class ErrorClass) {
+	duration, ok := fallbackCooldowns[class]
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	c.cooldownUntil[i] = time.Now().Add(duration)
+	c.mu.Unlock()
+}
+
+func (c *FallbackChain) clearCooldown(i int) {
+	c.mu.Lock()
+	c.cooldownUntil[i] = time.Time{}
+	c.mu.Unlock()
+}
+
+// Runs call against the chain's APIs in order until one succeeds or fails with an error that shouldn't
+// be retried elsewhere. Returns the result along with the API that produced it, or the last error
+func runFallback[T any](c *FallbackChain, call func(api TextVisionAPI) (T, error)) (T, TextVisionAPI, error) {
+	var zero T
+	var lastErr error
+
+	for _, i := range c.attemptOrder() {
+		api := c.apis[i]
+
+		res, err := call(api)
+		if err == nil {
+			c.clearCooldown(i)
+			return res, api, nil
+		}
+
+		class := ClassifyError(err)
+		c.setCooldown(i, class)
+		lastErr = fmt.Errorf("%s %s: %w", api.GetAPIName(), api.GetAPIModelName(), err)
+
+		if !class.ShouldFailover() {
+			return zero, nil, lastErr
+		}
+
+		log.Printf("Request to %s %s failed (%s), trying next API in chain: %v", api.GetAPIName(), api.GetAPIModelName(), class, err)
+	}
+
+	return zero, nil, lastErr
+}
+
+// Same as GenerateText, also returning the API that produced the response
+func (c *FallbackChain) GenerateTextWithProducer(prompt string) (string, TextVisionAPI, error) {
+	return runFallback(c, func(api TextVisionAPI) (string, error) {
+		return api.GenerateText(prompt)
+	})
+}
+
+// Same as DescribeScreenshot, also returning the API that produced the response.
+// The screenshot is preprocessed with the image settings of each API it's sent to
+func (c *FallbackChain) DescribeScreenshotWithProducer(fileName string, prompt string) (string, TextVisionAPI, error) {
+	return runFallback(c, func(api TextVisionAPI) (string, error) {
+		return api.DescribeScreenshot(preprocessScreenshots(api.GetAPIName(), []string{fileName})[0], prompt)
+	})
+}
+
+// Same as DescribeBulkScreenshots, also returning the API that produced the response.
+// The screenshots are preprocessed with the image settings of each API they're sent to
+func (c *FallbackChain) DescribeBulkScreenshotsWithProducer(fileNames []string, prompt string) ([]string, TextVisionAPI, error) {
+	return runFallback(c, func(api TextVisionAPI) ([]string, error) {
+		return api.DescribeBulkScreenshots(preprocessScreenshots(api.GetAPIName(), fileNames), prompt)
+	})
+}
+
+func (c *FallbackChain) GenerateText(prompt string) (string, error) {
+	res, _, err := c.GenerateTextWithProducer(prompt)
+	return res, err
+}
+
+func (c *FallbackChain) DescribeScreenshot(fileName string, prompt string) (string, error) {
+	res, _, err := c.DescribeScreenshotWithProducer(fileName, prompt)
+	return res, err
+}
+
+func (c *FallbackChain) DescribeBulkScreenshots(fileNames []string, prompt string) ([]string, error) {
+	res, _, err := c.DescribeBulkScreenshotsWithProducer(fileNames, prompt)
+	return res, err
+}
+
+// Sets the parameters of every API in the chain. APIs that don't accept them keep their current parameters,
+// and their errors are returned together
+func (c *FallbackChain) SetGenerationParams(params GenerationParams) error {
+	var errs []error
+	for _, api := range c.apis {
+		if err := api.SetGenerationParams(params); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Checks only the first API in the chain, the one it identifies as. The fallback APIs aren't checked,
+// since they're only used when the first one fails
+func (c *FallbackChain) HealthCheck(ctx context.Context) *HealthCheckResult {
+	return c.apis[0].HealthCheck(ctx)
+}
+
+// Lists only the models of the first API in the chain, the one it identifies as
+func (c *FallbackChain) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	return c.apis[0].ListModels(ctx)
+}
+
+func (c *FallbackChain) GetAPIName() string {
+	return c.apis[0].GetAPIName()
+}
+
+func (c *FallbackChain) GetAPIModelName() string {
+	return c.apis[0].GetAPIModelName()
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// API that fails every request with err, or responds with its name if err is nil. Counts the requests it gets
+type stubAPI struct {
+	TextVisionAPI
+	name  string
+	err   error
+	calls int
+}
+
+func (a *stubAPI) GetAPIName() string      { return a.name }
+func (a *stubAPI) GetAPIModelName() string { return a.name + "-model" }
+
+func (a *stubAPI) GenerateText(prompt string) (string, error) {
+	a.calls++
+	if a.err != nil {
+		return "", a.err
+	}
+	return a.name, nil
+}
+
+func newTestChain(apis ...*stubAPI) *FallbackChain {
+	chain := &FallbackChain{cooldownUntil: make([]time.Time, len(apis))}
+	for _, api := range apis {
+		chain.apis = append(chain.apis, api)
+	}
+	return chain
+}
+
+func TestRateLimitedRequestFailsOver(t *testing.T) {
+	primary := &stubAPI{name: "Primary", err: &APIError{API: "Primary", StatusCode: http.StatusTooManyRequests, Message: "slow down"}}
+	secondary := &stubAPI{name: "Secondary"}
+	chain := newTestChain(primary, secondary)
+
+	res, producer, err := chain.GenerateTextWithProducer("prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != "Secondary" || producer != secondary {
+		t.Errorf("expected the secondary API to respond, got %q from %v", res, producer)
+	}
+
+	if !time.Now().Before(chain.cooldownUntil[0]) {
+		t.Error("expected the rate limited API to be put on cooldown")
+	}
+	if !chain.cooldownUntil[1].IsZero() {
+		t.Error("expected the API that responded not to be on cooldown")
+	}
+}
+
+func TestBadResponseDoesNotFailOver(t *testing.T) {
+	primary := &stubAPI{name: "Primary", err: fmt.Errorf("%w: could not split the response", ErrBadResponse)}
+	secondary := &stubAPI{name: "Secondary"}
+	chain := newTestChain(primary, secondary)
+
+	_, producer, err := chain.GenerateTextWithProducer("prompt")
+	if ClassifyError(err) != ErrorClassBadResponse {
+		t.Fatalf("expected the bad response error to be returned, got %v", err)
+	}
+	if producer != nil || secondary.calls != 0 {
+		t.Errorf("expected the secondary API not to be tried, it got %d requests", secondary.calls)
+	}
+	if !chain.cooldownUntil[0].IsZero() {
+		t.Error("expected a bad response not to put the API on cooldown")
+	}
+}
+
+func TestAPIOnCooldownIsSkipped(t *testing.T) {
+	primary := &stubAPI{name: "Primary", err: &APIError{API: "Primary", StatusCode: http.StatusTooManyRequests, Message: "slow down"}}
+	secondary := &stubAPI{name: "Secondary"}
+	chain := newTestChain(primary, secondary)
+
+	if _, err := chain.GenerateText("prompt"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The primary API has recovered, but its cooldown hasn't ended yet
+	primary.err = nil
+	res, err := chain.GenerateText("prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != "Secondary" || primary.calls != 1 {
+		t.Errorf("expected the primary API to be skipped, got %q after %d requests to it", res, primary.calls)
+	}
+
+	// Once the cooldown ends, the primary API is tried first again
+	chain.cooldownUntil[0] = time.Now().Add(-time.Second)
+	if res, err := chain.GenerateText("prompt"); err != nil || res != "Primary" {
+		t.Errorf("expected the primary API to respond after its cooldown, got %q, %v", res, err)
+	}
+}
+
+func TestAPIsOnCooldownAreStillTriedLast(t *testing.T) {
+	primary := &stubAPI{name: "Primary", err: &APIError{API: "Primary", StatusCode: http.StatusServiceUnavailable, Message: "down"}}
+	secondary := &stubAPI{name: "Secondary", err: &APIError{API: "Secondary", StatusCode: http.StatusServiceUnavailable, Message: "down"}}
+	chain := newTestChain(primary, secondary)
+
+	if _, err := chain.GenerateText("prompt"); err == nil {
+		t.Fatal("expected an error when every API fails")
+	}
+
+	primary.err = nil
+	if res, err := chain.GenerateText("prompt"); err != nil || res != "Primary" {
+		t.Errorf("expected the chain to still try APIs on cooldown, got %q, %v", res, err)
+	}
+}
@@ -0,0 +1,109 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"recap/internal/config"
+	"sync"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/googleapi"
+)
+
+// Uploaded files that couldn't be deleted are saved to this file, next to the database, and deleted the next
+// time a client is created. Gemini deletes uploaded files itself after 48 hours, so they don't pile up forever
+const cleanupFileName = "gemini_cleanup.json"
+
+var cleanupMutex sync.Mutex
+
+func cleanupFilePath() string {
+	return filepath.Join(config.GetProjectRoot(), cleanupFileName)
+}
+
+// Reads the names of files waiting to be deleted. Returns an empty list if there are none
+func readPendingDeletions() ([]string, error) {
+	data, err := os.ReadFile(cleanupFilePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", cleanupFileName, err)
+	}
+
+	return names, nil
+}
+
+// Saves the names of files waiting to be deleted, removing the list if it's empty
+func writePendingDeletions(names []string) error {
+	if len(names) == 0 {
+		err := os.Remove(cleanupFilePath())
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	data, err := json.Marshal(names)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(cleanupFilePath(), data, 0600)
+}
+
+// Adds an uploaded file that couldn't be deleted to the cleanup list
+func addPendingDeletion(name string) {
+	cleanupMutex.Lock()
+	defer cleanupMutex.Unlock()
+
+	names, err := readPendingDeletions()
+	if err != nil {
+		fmt.Printf("Could not read Gemini cleanup list, %s won't be retried: %v\n", name, err)
+		return
+	}
+
+	if err := writePendingDeletions(append(names, name)); err != nil {
+		fmt.Printf("Could not save Gemini cleanup list, %s won't be retried: %v\n", name, err)
+	}
+}
+
+// Deletes uploaded files on the cleanup list, keeping the ones that still can't be deleted.
+// Files Gemini no longer has are dropped from the list
+func retryPendingDeletions(ctx context.Context, client *genai.Client) {
+	cleanupMutex.Lock()
+	defer cleanupMutex.Unlock()
+
+	names, err := readPendingDeletions()
+	if err != nil {
+		fmt.Printf("Could not read Gemini cleanup list: %v\n", err)
+		return
+	}
+	if len(names) == 0 {
+		return
+	}
+
+	var remaining []string
+	for _, name := range names {
+		err := client.DeleteFile(ctx, name)
+
+		var gErr *googleapi.Error
+		if err != nil && !(errors.As(err, &gErr) && gErr.Code == http.StatusNotFound) {
+			remaining = append(remaining, name)
+		}
+	}
+
+	fmt.Printf("Deleted %d of %d files left on Gemini\n", len(names)-len(remaining), len(names))
+	if err := writePendingDeletions(remaining); err != nil {
+		fmt.Printf("Could not save Gemini cleanup list: %v\n", err)
+	}
+}
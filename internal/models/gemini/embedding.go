@@ -0,0 +1,68 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"recap/internal/config"
+	"recap/internal/models"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// Maximum number of texts Gemini accepts in a single batch embedding request
+const maxEmbeddingBatchSize = 100
+
+type EmbeddingModel struct {
+	apiName string
+	model   string
+}
+
+// Generates embeddings for the given texts with batch embedding requests. A client is created
+// for the call and closed afterwards. It returns one vector per text or an error.
+func (e *EmbeddingModel) Embed(texts []string) ([][]float32, error) {
+	ctx := context.Background()
+
+	client, err := newClient(ctx, config.Config.GeminiAPIKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	defer client.Close()
+
+	em := client.EmbeddingModel(e.model)
+	vectors := make([][]float32, 0, len(texts))
+
+	for i := 0; i < len(texts); i += maxEmbeddingBatchSize {
+		batch := em.NewBatch()
+		for _, text := range texts[i:min(i+maxEmbeddingBatchSize, len(texts))] {
+			batch.AddContent(genai.Text(text))
+		}
+
+		res, err := em.BatchEmbedContents(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, emb := range res.Embeddings {
+			vectors = append(vectors, emb.Values)
+		}
+	}
+
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("Gemini returned %d embeddings for %d texts", len(vectors), len(texts))
+	}
+
+	return vectors, nil
+}
+
+func (e *EmbeddingModel) GetAPIName() string {
+	return e.apiName
+}
+
+func (e *EmbeddingModel) GetAPIModelName() string {
+	return e.model
+}
+
+// CreateEmbeddingClient is a factory method for creating the embedding client
+func CreateEmbeddingClient(model string) models.EmbeddingAPI {
+	return &EmbeddingModel{apiName: "Gemini", model: model}
+}
@@ -2,8 +2,10 @@ package gemini
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"path/filepath"
 	"recap/internal/config"
 	"recap/internal/models"
@@ -12,6 +14,7 @@ import (
 	"time"
 
 	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 )
 
@@ -20,6 +23,8 @@ type AIModel struct {
 	client       *genai.Client
 	clientTicker *time.Ticker
 	model        string
+	params       models.GenerationParams
+	conf         *config.AppConfig
 	mu           sync.Mutex // Added mutex for thread-safety
 }
 
@@ -70,6 +75,21 @@ func joinContentToString(resp *genai.GenerateContentResponse) string {
 	return sb.String()
 }
 
+// Converts error statuses returned by the Gemini API to *models.APIError so they can be classified.
+// Other errors are returned unchanged
+func wrapError(err error) error {
+	var gErr *googleapi.Error
+	if errors.As(err, &gErr) {
+		code := gErr.Code
+		// Gemini rejects invalid API keys with 400 Bad Request rather than 401
+		if code == http.StatusBadRequest && strings.Contains(gErr.Message, "API key") {
+			code = http.StatusUnauthorized
+		}
+		return &models.APIError{API: "Gemini", StatusCode: code, Message: gErr.Message}
+	}
+	return err
+}
+
 // Sends a text generation request to the model with the given prompt.
 func (a *AIModel) GenerateText(prompt string) (string, error) {
 	client, ctx := a.generateClient()
@@ -78,11 +98,10 @@ func (a *AIModel) GenerateText(prompt string) (string, error) {
 	}
 	defer a.startClientDeadline()
 
-	model := client.GenerativeModel(a.model)
-	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
+	resp, err := a.generativeModel(client).GenerateContent(ctx, genai.Text(prompt))
 
 	if err != nil {
-		return "", err
+		return "", wrapError(err)
 	}
 
 	return joinContentToString(resp), nil
@@ -96,55 +115,120 @@ func (a *AIModel) DescribeScreenshot(fileName string, prompt string) (string, er
 	}
 	defer a.startClientDeadline()
 
-	return sendFileToGemini(client, ctx, a.model, fileName, prompt)
+	return a.sendFilesToGemini(ctx, a.generativeModel(client), client, []string{fileName}, prompt)
 }
 
-// Sends multiple files for analysis
-func (a *AIModel) DescribeBulkScreenshots(fileNames []string, prompt string) (string, error) {
+// Sends multiple files for analysis in a single request
+func (a *AIModel) DescribeBulkScreenshots(fileNames []string, prompt string) ([]string, error) {
 	client, ctx := a.generateClient()
 	if client == nil {
-		return "", fmt.Errorf("failed to create client")
+		return nil, fmt.Errorf("failed to create client")
 	}
 	defer a.startClientDeadline()
 
-	descriptions := make([]string, 0, len(fileNames)) // Proper initialization
-
-	for _, fn := range fileNames {
-		res, err := sendFileToGemini(client, ctx, a.model, fn, prompt)
-		if err != nil {
-			fmt.Printf("An error occurred sending file to Gemini: %v\n", err.Error())
-			return "", err
-		}
-		descriptions = append(descriptions, res) // Use append properly
+	res, err := a.sendFilesToGemini(ctx, a.generativeModel(client), client, fileNames, models.BuildBulkPrompt(prompt, len(fileNames)))
+	if err != nil {
+		fmt.Printf("An error occurred sending files to Gemini: %v\n", err.Error())
+		return nil, err
 	}
 
-	return strings.Join(descriptions, "\n"), nil
+	return models.ParseBulkResponse(res, len(fileNames))
 }
 
-// Sends a file for analysis to the Gemini model
-func sendFileToGemini(client *genai.Client, ctx context.Context, modelName string, fileName string, prompt string) (string, error) {
-	file, err := client.UploadFileFromPath(ctx, filepath.Join(config.Config.ScrPath, fileName), nil)
-	if err != nil {
-		return "", err
-	}
+// Sends files for analysis to the Gemini model, in the order they're given. Images are sent inline in the request
+// while they fit within its size limit if inline images are enabled, and uploaded with the File API otherwise.
+// Uploaded files are deleted once the response is received, or added to the cleanup list if that fails
+func (a *AIModel) sendFilesToGemini(ctx context.Context, model *genai.GenerativeModel, client *genai.Client, fileNames []string, prompt string) (string, error) {
+	parts := make([]genai.Part, 0, len(fileNames)+1)
+	inlineBytes := len(prompt)
+
+	for _, fileName := range fileNames {
+		if a.conf.GeminiInlineImages == 1 {
+			data, format, err := readInlineImage(fileName)
+			if err != nil {
+				return "", err
+			}
 
-	defer func() {
-		err = client.DeleteFile(ctx, file.Name)
+			if inlineBytes+len(data) <= inlineRequestLimit {
+				inlineBytes += len(data)
+				parts = append(parts, genai.ImageData(format, data))
+				continue
+			}
+		}
+
+		file, err := client.UploadFileFromPath(ctx, filepath.Join(config.Config.ScrPath, fileName), nil)
 		if err != nil {
-			fmt.Printf("Failed to delete file from Gemini (was it already deleted?): %v\n", err.Error())
+			return "", wrapError(err)
 		}
-	}() // Defer file deletion
 
-	model := client.GenerativeModel(modelName)
-	resp, err := model.GenerateContent(ctx, genai.FileData{URI: file.URI}, genai.Text(prompt))
+		defer func() {
+			err := client.DeleteFile(ctx, file.Name)
+			if err != nil {
+				fmt.Printf("Failed to delete file %s from Gemini, adding it to the cleanup list: %v\n", file.Name, err.Error())
+				addPendingDeletion(file.Name)
+			}
+		}() // Defer file deletion
+
+		parts = append(parts, genai.FileData{URI: file.URI})
+	}
+
+	parts = append(parts, genai.Text(prompt))
+
+	resp, err := model.GenerateContent(ctx, parts...)
 
 	if err != nil {
-		return "", err
+		return "", wrapError(err)
 	}
 
 	return joinContentToString(resp), nil
 }
 
+// Returns the model with the generation parameters applied to its GenerationConfig
+func (a *AIModel) generativeModel(client *genai.Client) *genai.GenerativeModel {
+	model := client.GenerativeModel(a.model)
+	if a.params.Temperature != nil {
+		model.SetTemperature(float32(*a.params.Temperature))
+	}
+	if a.params.TopP != nil {
+		model.SetTopP(float32(*a.params.TopP))
+	}
+	if a.params.MaxTokens > 0 {
+		model.SetMaxOutputTokens(int32(a.params.MaxTokens))
+	}
+	return model
+}
+
+// Gemini accepts temperatures between 0 and 2
+func (a *AIModel) SetGenerationParams(params models.GenerationParams) error {
+	if err := params.Validate(a.apiName, 2); err != nil {
+		return err
+	}
+	a.params = params
+	return nil
+}
+
+// Adds the API key to every request. The key passed with option.WithAPIKey isn't sent when the client
+// is given its own HTTP client
+type apiKeyTransport struct {
+	key  string
+	base http.RoundTripper
+}
+
+func (t *apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("x-goog-api-key", t.key)
+	return t.base.RoundTrip(req)
+}
+
+// Creates a genai client that uses the network settings for Gemini and the given API key
+func newClient(ctx context.Context, apiKey string) (*genai.Client, error) {
+	httpClient := models.NewHTTPClient("Gemini")
+	httpClient.Transport = &apiKeyTransport{key: apiKey, base: httpClient.Transport}
+
+	// The API key option is still passed for the parts of the library that don't use the HTTP client
+	return genai.NewClient(ctx, option.WithHTTPClient(httpClient), option.WithAPIKey(apiKey))
+}
+
 // Initializes the genai client if it currently doesn't exist, or returns the existing client.
 // Remember to call a.startClientDeadline(). This sets a 5-minute timer before the client is stopped
 func (a *AIModel) generateClient() (*genai.Client, context.Context) {
@@ -154,12 +238,15 @@ func (a *AIModel) generateClient() (*genai.Client, context.Context) {
 	defer a.mu.Unlock()
 
 	if a.client == nil {
-		client, err := genai.NewClient(ctx, option.WithAPIKey(config.Config.GeminiAPIKey))
+		client, err := newClient(ctx, a.conf.GeminiAPIKey)
 		if err != nil {
 			log.Fatal(err)
 			return nil, ctx
 		}
 		a.client = client
+
+		// Files that couldn't be deleted before are retried whenever a new client is created
+		retryPendingDeletions(ctx, client)
 	}
 
 	return a.client, ctx
@@ -174,10 +261,11 @@ func (a *AIModel) GetAPIModelName() string {
 }
 
 // CreateAPIClient is a factory method for creating the AI client
-func CreateAPIClient(model string) models.TextVisionAPI {
-	return &AIModel{apiName: "Gemini", model: model}
+func CreateAPIClient(model string, conf *config.AppConfig) models.TextVisionAPI {
+	return &AIModel{apiName: "Gemini", model: model, conf: conf}
 }
 
 func init() {
 	models.RegisterAPI("Gemini", CreateAPIClient)
+	models.RegisterEmbeddingAPI("Gemini", CreateEmbeddingClient)
 }
@@ -0,0 +1,109 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"recap/internal/models"
+	"slices"
+	"strings"
+
+	"google.golang.org/api/iterator"
+)
+
+// Gemini models that only accept text. Other Gemini models accept images
+var textOnlyModels = []string{"gemini-pro", "gemini-1.0-pro"}
+
+// Checks that Gemini is reachable, that the API key is accepted, that the model exists and can
+// generate content, and whether it supports images
+func (a *AIModel) HealthCheck(ctx context.Context) *models.HealthCheckResult {
+	result := models.NewHealthCheckResult(a.apiName, a.model)
+
+	// A separate client is used so a failed check doesn't affect the shared one
+	client, err := newClient(ctx, a.conf.GeminiAPIKey)
+	if err != nil {
+		result.Fail(models.HealthCheckReachable, err, models.HealthCheckCredentials, models.HealthCheckModel, models.HealthCheckVision)
+		return result.Finish()
+	}
+	defer client.Close()
+
+	info, err := client.GenerativeModel(a.model).Info(ctx)
+	if err != nil {
+		err = wrapError(err)
+
+		switch models.ClassifyError(err) {
+		case models.ErrorClassAuth:
+			result.Add(models.HealthCheckReachable, models.HealthCheckPassed, "Gemini is reachable")
+			result.Fail(models.HealthCheckCredentials, models.DescribeHealthCheckError(err), models.HealthCheckModel, models.HealthCheckVision)
+		case models.ErrorClassNotFound:
+			result.Add(models.HealthCheckReachable, models.HealthCheckPassed, "Gemini is reachable")
+			result.Add(models.HealthCheckCredentials, models.HealthCheckPassed, "API key was accepted")
+			result.Fail(models.HealthCheckModel, fmt.Errorf("model %s isn't available on Gemini", a.model), models.HealthCheckVision)
+		default:
+			result.Fail(models.HealthCheckReachable, models.DescribeHealthCheckError(err), models.HealthCheckCredentials, models.HealthCheckModel, models.HealthCheckVision)
+		}
+		return result.Finish()
+	}
+
+	result.Add(models.HealthCheckReachable, models.HealthCheckPassed, "Gemini is reachable")
+	result.Add(models.HealthCheckCredentials, models.HealthCheckPassed, "API key was accepted")
+
+	if !slices.Contains(info.SupportedGenerationMethods, "generateContent") {
+		result.Fail(models.HealthCheckModel, fmt.Errorf("model %s can't be used to generate text", a.model), models.HealthCheckVision)
+		return result.Finish()
+	}
+	result.Add(models.HealthCheckModel, models.HealthCheckPassed, "Model %s is available", a.model)
+
+	switch getVisionSupport(strings.TrimPrefix(info.Name, "models/")) {
+	case models.VisionSupported:
+		result.Add(models.HealthCheckVision, models.HealthCheckPassed, "Model supports images")
+	case models.VisionUnsupported:
+		result.Add(models.HealthCheckVision, models.HealthCheckFailed, "Model doesn't support images and can only be used for reports")
+	default:
+		result.Add(models.HealthCheckVision, models.HealthCheckUnknown, "Could not determine whether the model supports images")
+	}
+
+	return result.Finish()
+}
+
+// Returns whether a Gemini model accepts images. The API doesn't report it, so it's decided by name
+func getVisionSupport(name string) models.VisionSupport {
+	switch {
+	case slices.ContainsFunc(textOnlyModels, func(m string) bool { return name == m || strings.HasPrefix(name, m+"-0") }):
+		return models.VisionUnsupported
+	case strings.HasPrefix(name, "gemini-"):
+		return models.VisionSupported
+	default:
+		return models.VisionUnknown
+	}
+}
+
+// Lists the Gemini models that can generate content
+func (a *AIModel) ListModels(ctx context.Context) ([]models.ModelInfo, error) {
+	client, err := newClient(ctx, a.conf.GeminiAPIKey)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	list := []models.ModelInfo{}
+	iter := client.ListModels(ctx)
+
+	for {
+		info, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, wrapError(err)
+		}
+
+		if !slices.Contains(info.SupportedGenerationMethods, "generateContent") {
+			continue
+		}
+
+		name := strings.TrimPrefix(info.Name, "models/")
+		list = append(list, models.ModelInfo{Name: name, Vision: getVisionSupport(name)})
+	}
+
+	return list, nil
+}
@@ -0,0 +1,34 @@
+package gemini
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"recap/internal/config"
+	"strings"
+)
+
+// Gemini rejects requests larger than 20 MB. Inline images are sent base64-encoded, which adds a third to their
+// size, so images are only sent inline while their total stays under this limit. Larger images are uploaded
+const inlineRequestLimit = 14 * 1024 * 1024
+
+// Reads a screenshot to be sent inline. Returns the image's bytes and its format ("png", "jpeg" or "webp").
+// Screenshots are downscaled beforehand with the image settings, e.g. a Gemini: max_edge override
+func readInlineImage(fileName string) ([]byte, string, error) {
+	fullPath := filepath.Join(config.Config.ScrPath, fileName)
+
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read image file %s: %w", fileName, err)
+	}
+
+	format := "jpeg"
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".png":
+		format = "png"
+	case ".webp":
+		format = "webp"
+	}
+
+	return data, format, nil
+}
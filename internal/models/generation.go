@@ -0,0 +1,59 @@
+package models
+
+import "fmt"
+
+// Sampling parameters sent with requests. Parameters left unset use the provider's defaults
+type GenerationParams struct {
+	Temperature *float64 // Lower values give more focused and repeatable responses
+	TopP        *float64 // Nucleus sampling probability mass
+	MaxTokens   int      // Maximum number of tokens in the response. 0 leaves it to the provider
+}
+
+// Creates GenerationParams from setting values, where a negative temperature or top_p and a max tokens
+// of 0 mean the provider's default is used
+func NewGenerationParams(temperature float64, topP float64, maxTokens int) GenerationParams {
+	params := GenerationParams{MaxTokens: maxTokens}
+	if temperature >= 0 {
+		params.Temperature = &temperature
+	}
+	if topP >= 0 {
+		params.TopP = &topP
+	}
+	return params
+}
+
+// Returns the parameters as text, e.g. "temperature=0.2 top_p=default max_tokens=0"
+func (p GenerationParams) String() string {
+	temperature, topP := "default", "default"
+	if p.Temperature != nil {
+		temperature = fmt.Sprint(*p.Temperature)
+	}
+	if p.TopP != nil {
+		topP = fmt.Sprint(*p.TopP)
+	}
+	return fmt.Sprintf("temperature=%s top_p=%s max_tokens=%d", temperature, topP, p.MaxTokens)
+}
+
+// Checks the parameters against the ranges an API accepts. Temperature must be between 0 and maxTemperature,
+// top_p between 0 and 1, and max tokens can't be negative
+func (p GenerationParams) Validate(api string, maxTemperature float64) error {
+	if p.Temperature != nil && (*p.Temperature < 0 || *p.Temperature > maxTemperature) {
+		return fmt.Errorf("%s accepts temperatures between 0 and %g, got %g", api, maxTemperature, *p.Temperature)
+	}
+	if p.TopP != nil && (*p.TopP < 0 || *p.TopP > 1) {
+		return fmt.Errorf("%s accepts top_p values between 0 and 1, got %g", api, *p.TopP)
+	}
+	if p.MaxTokens < 0 {
+		return fmt.Errorf("max tokens can't be negative, got %d", p.MaxTokens)
+	}
+	return nil
+}
+
+// Checks whether an API accepts the parameters, without sending a request
+func ValidateGenerationParams(api string, params GenerationParams) error {
+	factory, err := GetAPI(api)
+	if err != nil {
+		return err
+	}
+	return factory("").SetGenerationParams(params)
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Outcome of a single health check step
+type HealthCheckStatus string
+
+const (
+	HealthCheckPassed  HealthCheckStatus = "passed"
+	HealthCheckFailed  HealthCheckStatus = "failed"
+	HealthCheckUnknown HealthCheckStatus = "unknown" // The provider doesn't say, e.g. whether a model supports vision
+	HealthCheckSkipped HealthCheckStatus = "skipped" // Not applicable, or an earlier step failed
+)
+
+// Names of the steps every connector reports, in order
+const (
+	HealthCheckReachable   = "Reachable"
+	HealthCheckCredentials = "Credentials"
+	HealthCheckModel       = "Model"
+	HealthCheckVision      = "Vision"
+)
+
+// One step of a health check, with a message explaining its outcome
+type HealthCheckItem struct {
+	Name    string            `json:"Name"`
+	Status  HealthCheckStatus `json:"Status"`
+	Message string            `json:"Message"`
+}
+
+// The result of checking whether a provider can be used with a model.
+// Healthy is true if the provider is reachable, the credentials are accepted and the model exists.
+// Vision support is reported separately, as it's only required for describing screenshots
+type HealthCheckResult struct {
+	API       string            `json:"API"`
+	Model     string            `json:"Model"`
+	Healthy   bool              `json:"Healthy"`
+	Vision    HealthCheckStatus `json:"Vision"`
+	LatencyMs int64             `json:"LatencyMs"`
+	Checks    []HealthCheckItem `json:"Checks"`
+	started   time.Time
+}
+
+// Creates an empty result for the given API and model, starting its latency timer
+func NewHealthCheckResult(api string, model string) *HealthCheckResult {
+	return &HealthCheckResult{API: api, Model: model, Vision: HealthCheckUnknown, Checks: []HealthCheckItem{}, started: time.Now()}
+}
+
+// Records the outcome of a step
+func (r *HealthCheckResult) Add(name string, status HealthCheckStatus, format string, args ...interface{}) {
+	r.Checks = append(r.Checks, HealthCheckItem{Name: name, Status: status, Message: fmt.Sprintf(format, args...)})
+}
+
+// Records an error as a failed step, and marks the given later steps as skipped
+func (r *HealthCheckResult) Fail(name string, err error, skipped ...string) {
+	r.Add(name, HealthCheckFailed, "%v", err)
+	for _, s := range skipped {
+		r.Add(s, HealthCheckSkipped, "Skipped because the %s check failed", name)
+	}
+}
+
+// Sets Healthy and Vision from the recorded steps and stops the latency timer. Returns the result
+func (r *HealthCheckResult) Finish() *HealthCheckResult {
+	r.Healthy = true
+	for _, c := range r.Checks {
+		if c.Name == HealthCheckVision {
+			r.Vision = c.Status
+			continue
+		}
+		if c.Status == HealthCheckFailed {
+			r.Healthy = false
+		}
+	}
+
+	r.LatencyMs = time.Since(r.started).Milliseconds()
+	return r
+}
+
+// Describes a failed request for a health check message, adding a hint for common error classes
+func DescribeHealthCheckError(err error) error {
+	switch ClassifyError(err) {
+	case ErrorClassAuth:
+		return fmt.Errorf("the API key was rejected, check that it's correct: %w", err)
+	case ErrorClassUnavailable:
+		return fmt.Errorf("the provider couldn't be reached or is unavailable: %w", err)
+	case ErrorClassRateLimited:
+		return fmt.Errorf("the provider is rate limiting requests, try again later: %w", err)
+	case ErrorClassNotFound:
+		return fmt.Errorf("the model wasn't found: %w", err)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,197 @@
+package models
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"recap/internal/config"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Network settings used by an API's HTTP clients
+type HTTPSettings struct {
+	Proxy           string        // Proxy URL. Empty uses the HTTPS_PROXY and HTTP_PROXY environment variables, "direct" disables proxying
+	CABundle        string        // Path to a PEM file with certificates trusted in addition to the system's
+	ConnectTimeout  time.Duration // Time allowed to open a connection. 0 disables the limit
+	ResponseTimeout time.Duration // Time allowed for the response to arrive once the request is sent. 0 disables the limit
+}
+
+// Returns the network settings for an API, from the HTTP settings with the API's overrides applied
+func GetHTTPSettings(api string) HTTPSettings {
+	settings := HTTPSettings{
+		Proxy:           strings.TrimSpace(config.Config.HTTPProxy),
+		CABundle:        strings.TrimSpace(config.Config.HTTPCABundle),
+		ConnectTimeout:  time.Duration(max(config.Config.HTTPConnectTimeoutSecs, 0)) * time.Second,
+		ResponseTimeout: time.Duration(max(config.Config.HTTPResponseTimeoutSecs, 0)) * time.Second,
+	}
+
+	overrides, err := ParseHTTPOverrides(config.Config.HTTPOverrides)
+	if err != nil {
+		log.Printf("Ignoring HTTP overrides: %v", err)
+		return settings
+	}
+
+	for key, value := range overrides[api] {
+		switch key {
+		case "proxy":
+			settings.Proxy = value
+		case "ca_bundle":
+			settings.CABundle = value
+		case "connect_timeout":
+			secs, _ := strconv.Atoi(value)
+			settings.ConnectTimeout = time.Duration(max(secs, 0)) * time.Second
+		case "response_timeout":
+			secs, _ := strconv.Atoi(value)
+			settings.ResponseTimeout = time.Duration(max(secs, 0)) * time.Second
+		}
+	}
+
+	return settings
+}
+
+// Parses per-API overrides of the HTTP settings, given as semicolon-separated "API: key=value, key=value" entries,
+// e.g. "Ollama: proxy=direct, response_timeout=600; OpenAI: ca_bundle=/etc/ssl/corp.pem".
+// Keys are proxy, ca_bundle, connect_timeout and response_timeout, with timeouts in seconds
+func ParseHTTPOverrides(list string) (map[string]map[string]string, error) {
+	return config.ParseOverrides(list, validateHTTPOverride)
+}
+
+// Checks HTTP overrides before they're saved. Besides parsing them, the CA bundles they name are read,
+// which is left until requests are sent when the overrides are loaded
+func CheckHTTPOverrides(list string) error {
+	overrides, err := ParseHTTPOverrides(list)
+	if err != nil {
+		return err
+	}
+
+	for api, values := range overrides {
+		if bundle, ok := values["ca_bundle"]; ok {
+			if err := CheckCABundle(bundle); err != nil {
+				return fmt.Errorf("ca_bundle for %s: %w", api, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Checks one HTTP override
+func validateHTTPOverride(api string, key string, value string) error {
+	switch key {
+	case "connect_timeout", "response_timeout":
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("%s for %s must be a number of seconds", key, api)
+		}
+	case "proxy":
+		if err := CheckProxy(value); err != nil {
+			return fmt.Errorf("proxy for %s: %w", api, err)
+		}
+	case "ca_bundle":
+	default:
+		return fmt.Errorf("unknown override %q for %s", key, api)
+	}
+
+	return nil
+}
+
+// Returns the proxy function for a proxy setting
+func proxyFunc(proxy string) (func(*http.Request) (*url.URL, error), error) {
+	switch proxy {
+	case "":
+		return http.ProxyFromEnvironment, nil
+	case "direct":
+		return nil, nil
+	}
+
+	proxyURL, err := url.Parse(proxy)
+	if err != nil || proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q", proxy)
+	}
+	return http.ProxyURL(proxyURL), nil
+}
+
+// Returns the system's certificate pool with the certificates in a PEM file added
+func loadCABundle(path string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read CA bundle: %w", err)
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", path)
+	}
+
+	return pool, nil
+}
+
+// Creates an HTTP transport with the given settings. Settings are checked when they're saved, but if they can't
+// be used, e.g. because the CA bundle was removed since, every request fails with the reason rather than being
+// sent directly or with only the system's certificates
+func NewHTTPTransport(api string, settings HTTPSettings) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	dialer := &net.Dialer{Timeout: settings.ConnectTimeout, KeepAlive: 30 * time.Second}
+	transport.DialContext = dialer.DialContext
+	transport.TLSHandshakeTimeout = settings.ConnectTimeout
+	transport.ResponseHeaderTimeout = settings.ResponseTimeout
+
+	proxy, err := proxyFunc(settings.Proxy)
+	if err != nil {
+		log.Printf("Requests to %s will fail: %v", api, err)
+		transport.Proxy = failingProxy(err)
+		return transport
+	}
+	transport.Proxy = proxy
+
+	if settings.CABundle != "" {
+		pool, err := loadCABundle(settings.CABundle)
+		if err != nil {
+			log.Printf("Requests to %s will fail: %v", api, err)
+			transport.Proxy = failingProxy(err)
+			return transport
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	return transport
+}
+
+// Returns a proxy function that fails every request with err. The transport calls it before connecting
+func failingProxy(err error) func(*http.Request) (*url.URL, error) {
+	return func(*http.Request) (*url.URL, error) {
+		return nil, err
+	}
+}
+
+// Checks that a proxy setting is empty, "direct" or a proxy URL
+func CheckProxy(proxy string) error {
+	_, err := proxyFunc(strings.TrimSpace(proxy))
+	return err
+}
+
+// Checks that a CA bundle setting is empty or the path of a readable PEM file with at least one certificate
+func CheckCABundle(path string) error {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil
+	}
+	_, err := loadCABundle(path)
+	return err
+}
+
+// Creates an HTTP client for an API with the proxy, CA bundle and timeouts set for it.
+// Connectors should use this rather than creating clients themselves
+func NewHTTPClient(api string) *http.Client {
+	return &http.Client{Transport: NewHTTPTransport(api, GetHTTPSettings(api))}
+}
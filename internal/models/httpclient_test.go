@@ -0,0 +1,65 @@
+package models
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInvalidNetworkSettingsAreRejected(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+
+	if err := CheckProxy("proxy.example.com"); err == nil {
+		t.Error("expected a proxy without a scheme to be rejected")
+	}
+	if err := CheckProxy("http://proxy.example.com:8080"); err != nil {
+		t.Errorf("expected a proxy URL to be accepted, got %v", err)
+	}
+	if err := CheckCABundle(missing); err == nil {
+		t.Error("expected a missing CA bundle to be rejected")
+	}
+	if err := CheckHTTPOverrides("Ollama: proxy=://bad"); err == nil {
+		t.Error("expected an invalid proxy override to be rejected")
+	}
+	if err := CheckHTTPOverrides("OpenAI: ca_bundle=" + missing); err == nil {
+		t.Error("expected a missing CA bundle override to be rejected")
+	}
+	if err := CheckHTTPOverrides("Ollama: proxy=direct, response_timeout=600"); err != nil {
+		t.Errorf("expected valid overrides to be accepted, got %v", err)
+	}
+}
+
+func TestUnusableNetworkSettingsFailRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	tests := []struct {
+		settings HTTPSettings
+		reason   string
+	}{
+		{HTTPSettings{Proxy: "direct"}, ""},
+		{HTTPSettings{Proxy: "://bad"}, "invalid proxy URL"},
+		{HTTPSettings{Proxy: "direct", CABundle: filepath.Join(t.TempDir(), "missing.pem")}, "could not read CA bundle"},
+	}
+
+	for _, test := range tests {
+		client := &http.Client{Transport: NewHTTPTransport("Test", test.settings)}
+		res, err := client.Get(server.URL)
+		if test.reason == "" {
+			if err != nil {
+				t.Errorf("%+v: expected the request to succeed, got %v", test.settings, err)
+			} else {
+				res.Body.Close()
+			}
+			continue
+		}
+		if err == nil {
+			res.Body.Close()
+			t.Errorf("%+v: expected the request to fail", test.settings)
+		} else if !strings.Contains(err.Error(), test.reason) {
+			t.Errorf("%+v: expected the request to fail with %q, got %v", test.settings, test.reason, err)
+		}
+	}
+}
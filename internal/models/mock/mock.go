@@ -0,0 +1,239 @@
+package mock
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"recap/internal/config"
+	"recap/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+The Mock API returns deterministic descriptions and reports without any network access, for development
+and end-to-end testing. Responses are read from a fixture directory if one is given, and generated from
+hashes of the screenshots and prompts otherwise, so the same input always gives the same output.
+
+It's configured with environment variables:
+  - RECAP_MOCK_FIXTURES: Directory with fixture responses. A screenshot's description is read from
+    <screenshot name without extension>.txt or <SHA-256 of the screenshot>.txt, and reports from report.txt
+  - RECAP_MOCK_LATENCY_MS: Milliseconds to wait before each response
+  - RECAP_MOCK_FAILURE_RATE: Fraction of requests, from 0 to 1, that fail. Which requests fail is decided
+    by their input, so failures are reproducible
+  - RECAP_MOCK_FAILURE_STATUS: HTTP status failed requests report, 503 by default. 429 simulates rate limiting
+*/
+
+// Model that only accepts text. Requests with screenshots fail, as they would with a real text-only model
+const textOnlyModel = "mock-text"
+
+var subjects = []string{"a code editor", "a web browser", "a terminal", "a spreadsheet", "a chat application", "a document editor", "an email client", "a design tool"}
+var activities = []string{"editing a file", "reading documentation", "running tests", "reviewing changes", "writing a message", "searching for information", "organizing tasks", "comparing two versions"}
+
+type AIModel struct {
+	apiName string
+	model   string
+	params  models.GenerationParams // Validated like a real provider's, but don't affect responses
+}
+
+// Settings read from the environment
+type mockOptions struct {
+	fixtures      string
+	latency       time.Duration
+	failureRate   float64
+	failureStatus int
+}
+
+func loadOptions() mockOptions {
+	opts := mockOptions{
+		fixtures:      os.Getenv("RECAP_MOCK_FIXTURES"),
+		failureStatus: http.StatusServiceUnavailable,
+	}
+
+	if ms, err := strconv.Atoi(os.Getenv("RECAP_MOCK_LATENCY_MS")); err == nil && ms > 0 {
+		opts.latency = time.Duration(ms) * time.Millisecond
+	}
+	if rate, err := strconv.ParseFloat(os.Getenv("RECAP_MOCK_FAILURE_RATE"), 64); err == nil {
+		opts.failureRate = rate
+	}
+	if status, err := strconv.Atoi(os.Getenv("RECAP_MOCK_FAILURE_STATUS")); err == nil && status >= 400 {
+		opts.failureStatus = status
+	}
+
+	return opts
+}
+
+func hashBytes(data []byte) [32]byte {
+	return sha256.Sum256(data)
+}
+
+// Waits for the configured latency, then decides from the request's hash whether it should fail
+func simulateRequest(opts mockOptions, requestHash [32]byte) error {
+	time.Sleep(opts.latency)
+
+	if opts.failureRate <= 0 {
+		return nil
+	}
+
+	// Maps the hash to a number in [0, 1)
+	fraction := float64(binary.BigEndian.Uint64(requestHash[:8])>>11) / float64(1<<53)
+	if fraction < opts.failureRate {
+		return &models.APIError{API: "Mock", StatusCode: opts.failureStatus, Message: "injected failure"}
+	}
+
+	return nil
+}
+
+// Reads a fixture file, returning false if fixtures aren't configured or the file doesn't exist
+func readFixture(opts mockOptions, names ...string) (string, bool) {
+	if opts.fixtures == "" {
+		return "", false
+	}
+
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(opts.fixtures, name))
+		if err == nil {
+			return strings.TrimSpace(string(data)), true
+		}
+	}
+
+	return "", false
+}
+
+// Returns a description for a screenshot, from a fixture or generated from the image and prompt hashes
+func describe(opts mockOptions, fileName string, prompt string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(config.Config.ScrPath, fileName))
+	if err != nil {
+		return "", fmt.Errorf("failed to read image file %s: %w", fileName, err)
+	}
+
+	imageHash := hashBytes(data)
+	if err := simulateRequest(opts, hashBytes(append(imageHash[:], prompt...))); err != nil {
+		return "", err
+	}
+
+	base := strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+	if fixture, ok := readFixture(opts, base+".txt", hex.EncodeToString(imageHash[:])+".txt"); ok {
+		return fixture, nil
+	}
+
+	promptHash := hashBytes([]byte(prompt))
+	return fmt.Sprintf("The user has %s open and is %s. (mock description of image %s for prompt %s)",
+		subjects[int(imageHash[0])%len(subjects)],
+		activities[int(imageHash[1])%len(activities)],
+		hex.EncodeToString(imageHash[:4]),
+		hex.EncodeToString(promptHash[:4]),
+	), nil
+}
+
+// Returns a report listing the descriptions found in the prompt, or the report.txt fixture
+func (a *AIModel) GenerateText(prompt string) (string, error) {
+	opts := loadOptions()
+	promptHash := hashBytes([]byte(prompt))
+
+	if err := simulateRequest(opts, promptHash); err != nil {
+		return "", err
+	}
+
+	if fixture, ok := readFixture(opts, "report.txt"); ok {
+		return fixture, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Mock report %s\n\n", hex.EncodeToString(promptHash[:4])))
+
+	descs := strings.Split(prompt, "BEGIN DESCRIPTION\n")[1:]
+	sb.WriteString(fmt.Sprintf("Generated from %d descriptions.\n\n", len(descs)))
+	for _, desc := range descs {
+		desc, _, _ = strings.Cut(desc, "END DESCRIPTION")
+		desc, _, _ = strings.Cut(strings.TrimSpace(desc), "\n")
+		sb.WriteString("- " + desc + "\n")
+	}
+
+	return sb.String(), nil
+}
+
+// Returns a deterministic description of the screenshot
+func (a *AIModel) DescribeScreenshot(fileName string, prompt string) (string, error) {
+	if a.model == textOnlyModel {
+		return "", &models.APIError{API: a.apiName, StatusCode: http.StatusBadRequest, Message: "model doesn't support images"}
+	}
+
+	return describe(loadOptions(), fileName, prompt)
+}
+
+// Builds a bulk response with one "### IMAGE n" section per screenshot and parses it back, so the
+// same parsing path as real providers is exercised
+func (a *AIModel) DescribeBulkScreenshots(fileNames []string, prompt string) ([]string, error) {
+	if a.model == textOnlyModel {
+		return nil, &models.APIError{API: a.apiName, StatusCode: http.StatusBadRequest, Message: "model doesn't support images"}
+	}
+
+	opts := loadOptions()
+	bulkPrompt := models.BuildBulkPrompt(prompt, len(fileNames))
+
+	var sb strings.Builder
+	for i, fileName := range fileNames {
+		desc, err := describe(opts, fileName, bulkPrompt)
+		if err != nil {
+			return nil, err
+		}
+		sb.WriteString(fmt.Sprintf("### IMAGE %d\n%s\n\n", i+1, desc))
+	}
+
+	return models.ParseBulkResponse(sb.String(), len(fileNames))
+}
+
+// Always passes, reporting vision support according to the model
+func (a *AIModel) HealthCheck(ctx context.Context) *models.HealthCheckResult {
+	result := models.NewHealthCheckResult(a.apiName, a.model)
+	result.Add(models.HealthCheckReachable, models.HealthCheckPassed, "Mock API doesn't use the network")
+	result.Add(models.HealthCheckCredentials, models.HealthCheckSkipped, "Mock API doesn't use an API key")
+	result.Add(models.HealthCheckModel, models.HealthCheckPassed, "Mock API accepts any model name")
+
+	if a.model == textOnlyModel {
+		result.Add(models.HealthCheckVision, models.HealthCheckFailed, "Model doesn't support images and can only be used for reports")
+	} else {
+		result.Add(models.HealthCheckVision, models.HealthCheckPassed, "Model supports images")
+	}
+
+	return result.Finish()
+}
+
+func (a *AIModel) ListModels(ctx context.Context) ([]models.ModelInfo, error) {
+	return []models.ModelInfo{
+		{Name: "mock-vision", Vision: models.VisionSupported},
+		{Name: textOnlyModel, Vision: models.VisionUnsupported},
+	}, nil
+}
+
+func (a *AIModel) SetGenerationParams(params models.GenerationParams) error {
+	if err := params.Validate(a.apiName, 2); err != nil {
+		return err
+	}
+	a.params = params
+	return nil
+}
+
+func (a *AIModel) GetAPIName() string {
+	return a.apiName
+}
+
+func (a *AIModel) GetAPIModelName() string {
+	return a.model
+}
+
+// Initializes a new AIModel instance with the specified model name.
+func CreateAPIClient(model string, _ *config.AppConfig) models.TextVisionAPI {
+	return &AIModel{apiName: "Mock", model: model}
+}
+
+func init() {
+	models.RegisterAPI("Mock", CreateAPIClient)
+}
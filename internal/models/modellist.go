@@ -0,0 +1,118 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Whether a model accepts images
+type VisionSupport string
+
+const (
+	VisionSupported   VisionSupport = "yes"
+	VisionUnsupported VisionSupport = "no"
+	VisionUnknown     VisionSupport = "unknown" // The provider doesn't say
+)
+
+// A model offered by a provider
+type ModelInfo struct {
+	Name   string        `json:"Name"`
+	Vision VisionSupport `json:"Vision"`
+}
+
+// Model lists are cached for modelListTTL. Failed requests are cached for modelListErrorTTL,
+// so an unreachable provider doesn't delay every settings page load
+const (
+	modelListTTL      = 10 * time.Minute
+	modelListErrorTTL = time.Minute
+	modelListTimeout  = 5 * time.Second
+)
+
+type cachedModelList struct {
+	models    []ModelInfo
+	err       error
+	fetchedAt time.Time
+}
+
+var (
+	modelListCache = make(map[string]cachedModelList)
+	modelListMutex sync.Mutex
+	// Bumped by InvalidateModelLists, so lists fetched with old settings aren't cached
+	modelListGeneration int
+	// Callers asking for the same API while its list is being fetched share one request
+	modelListFetches singleflight.Group
+)
+
+// Returns the models offered by a registered API, from the cache if it was fetched recently.
+// The request is abandoned after a few seconds so callers such as the settings page aren't kept waiting.
+// The cache isn't locked while the list is fetched, so other APIs and InvalidateModelLists aren't held up
+func ListAPIModels(api string) ([]ModelInfo, error) {
+	modelListMutex.Lock()
+	if cached, ok := modelListCache[api]; ok {
+		ttl := modelListTTL
+		if cached.err != nil {
+			ttl = modelListErrorTTL
+		}
+		if time.Since(cached.fetchedAt) < ttl {
+			modelListMutex.Unlock()
+			return cached.models, cached.err
+		}
+	}
+	generation := modelListGeneration
+	modelListMutex.Unlock()
+
+	factory, err := GetAPI(api)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err, _ := modelListFetches.Do(fmt.Sprintf("%s/%d", api, generation), func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), modelListTimeout)
+		defer cancel()
+
+		list, err := factory("").ListModels(ctx)
+
+		modelListMutex.Lock()
+		if generation == modelListGeneration {
+			modelListCache[api] = cachedModelList{models: list, err: err, fetchedAt: time.Now()}
+		}
+		modelListMutex.Unlock()
+
+		return list, err
+	})
+
+	list, _ := result.([]ModelInfo)
+	return list, err
+}
+
+// Clears cached model lists, e.g. after an API key or URL changes. Lists still being fetched aren't cached
+func InvalidateModelLists() {
+	modelListMutex.Lock()
+	defer modelListMutex.Unlock()
+
+	modelListCache = make(map[string]cachedModelList)
+	modelListGeneration++
+}
+
+// Returns the names of the models offered by an API, leaving out models known not to accept images
+// if visionOnly is set. Returns nil if the models couldn't be listed
+func ModelNames(api string, visionOnly bool) []string {
+	list, err := ListAPIModels(api)
+	if err != nil {
+		return nil
+	}
+
+	names := []string{}
+	for _, m := range list {
+		if visionOnly && m.Vision == VisionUnsupported {
+			continue
+		}
+		names = append(names, m.Name)
+	}
+
+	return names
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"recap/internal/config"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// API whose model list is only returned once release is closed, counting how often it's fetched
+type blockingListAPI struct {
+	TextVisionAPI
+	fetches *atomic.Int32
+	release chan struct{}
+}
+
+func (a *blockingListAPI) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	a.fetches.Add(1)
+	<-a.release
+	return []ModelInfo{{Name: "model", Vision: VisionSupported}}, nil
+}
+
+// APIs can't be registered twice, so each run of the test registers one with a new name
+var blockingListRuns atomic.Int32
+
+func TestListAPIModelsDoesNotBlockWhileFetching(t *testing.T) {
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	api := fmt.Sprintf("BlockingList%d", blockingListRuns.Add(1))
+	RegisterAPI(api, func(model string, conf *config.AppConfig) TextVisionAPI {
+		return &blockingListAPI{fetches: &fetches, release: release}
+	})
+	InvalidateModelLists()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if list, err := ListAPIModels(api); err != nil || len(list) != 1 {
+			t.Errorf("expected one model, got %v, %v", list, err)
+		}
+	}()
+
+	// Wait for the fetch to start, then check that invalidating the cache doesn't wait for it
+	for fetches.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	invalidated := make(chan struct{})
+	go func() {
+		InvalidateModelLists()
+		close(invalidated)
+	}()
+	select {
+	case <-invalidated:
+	case <-time.After(time.Second):
+		t.Fatal("InvalidateModelLists waited for a model list to be fetched")
+	}
+
+	close(release)
+	wg.Wait()
+
+	// The list was fetched before the cache was invalidated, so it isn't cached
+	if _, err := ListAPIModels(api); err != nil {
+		t.Fatal(err)
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("expected the list to be fetched again after invalidation, got %d fetches", n)
+	}
+}
@@ -0,0 +1,73 @@
+package ollama
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"recap/internal/config"
+	"recap/internal/models"
+	"strings"
+)
+
+type EmbeddingModel struct {
+	apiName string
+	client  *http.Client
+	model   string
+}
+
+// Generates embeddings for the given texts with a single request to Ollama's /api/embed endpoint.
+// It returns one vector per text or an error.
+func (e *EmbeddingModel) Embed(texts []string) ([][]float32, error) {
+	requestBody := OllamaEmbedRequest{
+		Model: e.model,
+		Input: texts,
+	}
+
+	preparedBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling JSON request body: %w", err)
+	}
+
+	endpoint := strings.TrimSuffix(config.Config.OllamaURL, "/") + "/api/embed"
+	res, err := e.client.Post(endpoint, "application/json", bytes.NewBuffer(preparedBody))
+	if err != nil {
+		return nil, fmt.Errorf("error sending request to Ollama: %w", err)
+	}
+	defer res.Body.Close()
+
+	readRes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response from Ollama: %w", err)
+	}
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, fmt.Errorf("Ollama API returned non-2xx status: %d - %s", res.StatusCode, string(readRes))
+	}
+
+	var embedResponse OllamaEmbedResponse
+	err = json.Unmarshal(readRes, &embedResponse)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding Ollama response: %w", err)
+	}
+
+	if len(embedResponse.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("Ollama returned %d embeddings for %d texts", len(embedResponse.Embeddings), len(texts))
+	}
+
+	return embedResponse.Embeddings, nil
+}
+
+func (e *EmbeddingModel) GetAPIName() string {
+	return e.apiName
+}
+
+func (e *EmbeddingModel) GetAPIModelName() string {
+	return e.model
+}
+
+// Initializes a new EmbeddingModel instance with the specified model name.
+func CreateEmbeddingClient(model string) models.EmbeddingAPI {
+	return &EmbeddingModel{apiName: "Ollama", client: models.NewHTTPClient("Ollama"), model: model}
+}
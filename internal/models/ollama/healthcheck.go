@@ -0,0 +1,85 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"recap/internal/models"
+	"slices"
+	"strings"
+)
+
+// Model families of vision projectors. Older Ollama versions don't report capabilities,
+// but list one of these families for vision models
+var visionFamilies = []string{"clip", "mllama"}
+
+// Reports whether an installed model's name matches the requested one. Ollama adds the
+// "latest" tag to names given without one
+func modelNameMatches(installed string, requested string) bool {
+	return installed == requested || !strings.Contains(requested, ":") && installed == requested+":latest"
+}
+
+// Checks that Ollama is reachable at OllamaURL, that the model is installed, and whether it supports images
+func (a *AIModel) HealthCheck(ctx context.Context) *models.HealthCheckResult {
+	result := models.NewHealthCheckResult(a.apiName, a.model)
+	client := models.NewHTTPClient(a.apiName)
+
+	var tags OllamaTagsResponse
+	if err := a.requestOllama(ctx, client, "/api/tags", nil, &tags); err != nil {
+		result.Fail(models.HealthCheckReachable, fmt.Errorf("could not reach Ollama at %s, is it running? %w", a.conf.OllamaURL, err), models.HealthCheckModel, models.HealthCheckVision)
+		return result.Finish()
+	}
+	result.Add(models.HealthCheckReachable, models.HealthCheckPassed, "Ollama is running at %s", a.conf.OllamaURL)
+	result.Add(models.HealthCheckCredentials, models.HealthCheckSkipped, "Ollama doesn't use an API key")
+
+	found := slices.ContainsFunc(tags.Models, func(m OllamaTagsModel) bool {
+		return modelNameMatches(m.Name, a.model)
+	})
+	if !found {
+		result.Fail(models.HealthCheckModel, fmt.Errorf("model %s isn't installed, run \"ollama pull %s\"", a.model, a.model), models.HealthCheckVision)
+		return result.Finish()
+	}
+	result.Add(models.HealthCheckModel, models.HealthCheckPassed, "Model %s is installed", a.model)
+
+	switch a.getVisionSupport(ctx, client, a.model) {
+	case models.VisionSupported:
+		result.Add(models.HealthCheckVision, models.HealthCheckPassed, "Model supports images")
+	case models.VisionUnsupported:
+		result.Add(models.HealthCheckVision, models.HealthCheckFailed, "Model doesn't support images and can only be used for reports")
+	default:
+		result.Add(models.HealthCheckVision, models.HealthCheckUnknown, "Could not get model details")
+	}
+
+	return result.Finish()
+}
+
+// Asks Ollama whether an installed model accepts images
+func (a *AIModel) getVisionSupport(ctx context.Context, client *http.Client, model string) models.VisionSupport {
+	var show OllamaShowResponse
+	if err := a.requestOllama(ctx, client, "/api/show", OllamaShowRequest{Model: model}, &show); err != nil {
+		return models.VisionUnknown
+	}
+
+	if slices.Contains(show.Capabilities, "vision") || slices.ContainsFunc(show.Details.Families, func(f string) bool { return slices.Contains(visionFamilies, f) }) {
+		return models.VisionSupported
+	}
+
+	return models.VisionUnsupported
+}
+
+// Lists the models installed in Ollama
+func (a *AIModel) ListModels(ctx context.Context) ([]models.ModelInfo, error) {
+	client := models.NewHTTPClient(a.apiName)
+
+	var tags OllamaTagsResponse
+	if err := a.requestOllama(ctx, client, "/api/tags", nil, &tags); err != nil {
+		return nil, err
+	}
+
+	list := make([]models.ModelInfo, len(tags.Models))
+	for i, m := range tags.Models {
+		list[i] = models.ModelInfo{Name: m.Name, Vision: a.getVisionSupport(ctx, client, m.Name)}
+	}
+
+	return list, nil
+}
@@ -2,12 +2,16 @@ package ollama
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"recap/internal/config"
 	"recap/internal/models"
 	"recap/internal/utils"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -18,9 +22,14 @@ type AIModel struct {
 	client       *http.Client
 	clientTicker *time.Ticker
 	model        string
+	params       models.GenerationParams
+	conf         *config.AppConfig
 	mu           sync.Mutex // Added mutex for thread-safety
 }
 
+// Ollama doesn't limit temperature, but values above 2 make any model's output incoherent
+const maxTemperature = 2
+
 // Starts or resets a timer that closes the HTTP client
 // after 5 minutes of inactivity. If the client is already active, it resets the timer.
 func (a *AIModel) startClientDeadline() {
@@ -61,7 +70,7 @@ func (a *AIModel) GenerateText(prompt string) (string, error) {
 	}
 	defer a.startClientDeadline()
 
-	return sendToOllama(client, a.model, nil, prompt)
+	return a.sendToOllama(client, a.model, a.params, nil, prompt)
 }
 
 // Generates a description for a screenshot specified by its filename
@@ -73,74 +82,126 @@ func (a *AIModel) DescribeScreenshot(fileName string, prompt string) (string, er
 	}
 	defer a.startClientDeadline()
 
-	return sendToOllama(client, a.model, &fileName, prompt)
+	return a.sendToOllama(client, a.model, a.params, []string{fileName}, prompt)
 }
 
-// Generates descriptions for multiple screenshots
-// provided in the fileNames slice. It returns concatenated descriptions or an error.
-func (a *AIModel) DescribeBulkScreenshots(fileNames []string, prompt string) (string, error) {
+// Generates descriptions for multiple screenshots provided in the fileNames slice
+// with a single request. It returns one description per screenshot or an error.
+func (a *AIModel) DescribeBulkScreenshots(fileNames []string, prompt string) ([]string, error) {
 	client := a.generateClient()
 	if client == nil {
-		return "", fmt.Errorf("failed to create client")
+		return nil, fmt.Errorf("failed to create client")
 	}
 	defer a.startClientDeadline()
 
-	var descriptions []string
+	res, err := a.sendToOllama(client, a.model, a.params, fileNames, models.BuildBulkPrompt(prompt, len(fileNames)))
+	if err != nil {
+		return nil, fmt.Errorf("error sending files to Ollama: %w", err)
+	}
 
-	for _, fn := range fileNames {
-		res, err := sendToOllama(client, a.model, &fn, prompt)
+	return models.ParseBulkResponse(res, len(fileNames))
+}
+
+// Sends a request to Ollama's API at the configured OllamaURL and decodes the JSON response into out.
+// A nil body sends a GET request, otherwise body is sent as JSON with POST
+func (a *AIModel) requestOllama(ctx context.Context, client *http.Client, path string, body interface{}, out interface{}) error {
+	method := http.MethodGet
+	var reqBody io.Reader
+
+	if body != nil {
+		preparedBody, err := json.Marshal(body)
 		if err != nil {
-			return "", fmt.Errorf("error sending file to Ollama: %w", err)
+			return fmt.Errorf("error marshalling JSON request body: %w", err)
 		}
-		descriptions = append(descriptions, res)
+		method = http.MethodPost
+		reqBody = bytes.NewBuffer(preparedBody)
 	}
 
-	return strings.Join(descriptions, "\n"), nil
-}
+	endpoint := strings.TrimSuffix(a.conf.OllamaURL, "/") + path
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reqBody)
+	if err != nil {
+		return fmt.Errorf("error creating request to Ollama: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
 
-// Sends a request to the Ollama API with the specified client, model,
-// and image data (if applicable). It returns the response from the API or an error.
-func sendToOllama(client *http.Client, modelName string, fileName *string, prompt string) (string, error) {
-	var images []string
-	if fileName == nil {
-		return "", fmt.Errorf("No file name provided\n")
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending request to Ollama: %w", err)
 	}
+	defer res.Body.Close()
 
-	imageBase64 := utils.ReadImageToBase64(*fileName)
-	if imageBase64 == "" {
-		return "", fmt.Errorf("failed to read image file")
+	readRes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response from Ollama: %w", err)
 	}
-	images = []string{imageBase64}
 
-	requestBody := OllamaRequest{
-		Model:  modelName,
-		Prompt: prompt,
-		Stream: false,
-		Images: &images,
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return &models.APIError{API: "Ollama", StatusCode: res.StatusCode, Message: string(readRes)}
 	}
 
-	preparedBody, err := json.Marshal(requestBody)
-	if err != nil {
-		return "", fmt.Errorf("error marshalling JSON request body: %w", err)
+	if err := json.Unmarshal(readRes, out); err != nil {
+		return fmt.Errorf("error decoding Ollama response: %w", err)
 	}
 
-	res, err := client.Post("http://localhost:11434/api/generate", "application/json", bytes.NewBuffer(preparedBody))
-	readRes, _ := io.ReadAll(res.Body)
+	return nil
+}
 
-	if err != nil {
-		fmt.Printf("Error from Ollama: %s\n", readRes)
-		return "", fmt.Errorf("error sending request to Ollama: %v", err.Error())
+// Converts the OllamaKeepAlive setting to a keep_alive value. Ollama reads plain numbers, such as 0 or -1,
+// as seconds only if they're sent as JSON numbers. Returns nil if the setting is empty
+func keepAliveValue(setting string) interface{} {
+	setting = strings.TrimSpace(setting)
+	if setting == "" {
+		return nil
 	}
-	defer res.Body.Close()
+	if seconds, err := strconv.Atoi(setting); err == nil {
+		return seconds
+	}
+	return setting
+}
 
-	var ollamaResponse OllamaFullResponse
-	err = json.Unmarshal(readRes, &ollamaResponse)
-	if err != nil {
-		fmt.Printf("error decoding Ollama response: %v", err.Error())
-		return "", fmt.Errorf("error decoding Ollama response: %v", err.Error())
+// Sends a chat request to the Ollama API at OllamaURL with the specified client, model, generation parameters,
+// and screenshots (if any file names are given). It returns the response from the API or an error.
+func (a *AIModel) sendToOllama(client *http.Client, modelName string, params models.GenerationParams, fileNames []string, prompt string) (string, error) {
+	message := OllamaChatMessage{Role: "user", Content: prompt}
+
+	for _, fileName := range fileNames {
+		imageBase64 := utils.ReadImageToBase64(fileName)
+		if imageBase64 == "" {
+			return "", fmt.Errorf("failed to read image file %s", fileName)
+		}
+
+		// Ollama expects plain base64 rather than a data URL
+		_, data, _ := strings.Cut(imageBase64, ",")
+		message.Images = append(message.Images, data)
 	}
 
-	return ollamaResponse.Response, nil
+	requestBody := OllamaChatRequest{
+		Model:     modelName,
+		Messages:  []OllamaChatMessage{message},
+		Stream:    false,
+		KeepAlive: keepAliveValue(a.conf.OllamaKeepAlive),
+	}
+
+	options := OllamaOptions{
+		NumCtx:      max(a.conf.OllamaNumCtx, 0),
+		NumPredict:  params.MaxTokens,
+		Temperature: params.Temperature,
+		TopP:        params.TopP,
+	}
+	if options != (OllamaOptions{}) {
+		requestBody.Options = &options
+	}
+
+	var ollamaResponse OllamaChatResponse
+	if err := a.requestOllama(context.Background(), client, "/api/chat", requestBody, &ollamaResponse); err != nil {
+		return "", err
+	}
+
+	if a.conf.OllamaNumCtx > 0 && ollamaResponse.PromptEvalCount >= a.conf.OllamaNumCtx {
+		log.Printf("Warning: Ollama prompt filled the whole context window of %d tokens and may have been truncated. Increase the context size in settings", a.conf.OllamaNumCtx)
+	}
+
+	return ollamaResponse.Message.Content, nil
 }
 
 // Creates and returns a new HTTP client if one does not already exist.
@@ -150,12 +211,20 @@ func (a *AIModel) generateClient() *http.Client {
 	defer a.mu.Unlock()
 
 	if a.client == nil {
-		a.client = &http.Client{}
+		a.client = models.NewHTTPClient(a.apiName)
 	}
 
 	return a.client
 }
 
+func (a *AIModel) SetGenerationParams(params models.GenerationParams) error {
+	if err := params.Validate(a.apiName, maxTemperature); err != nil {
+		return err
+	}
+	a.params = params
+	return nil
+}
+
 func (a *AIModel) GetAPIName() string {
 	return a.apiName
 }
@@ -164,11 +233,12 @@ func (a *AIModel) GetAPIModelName() string {
 	return a.model
 }
 
-// Initializes a new AIModel instance with the specified model name.
-func CreateAPIClient(model string) models.TextVisionAPI {
-	return &AIModel{apiName: "Ollama", model: model}
+// Initializes a new AIModel instance with the specified model name, using the URL and options in conf.
+func CreateAPIClient(model string, conf *config.AppConfig) models.TextVisionAPI {
+	return &AIModel{apiName: "Ollama", model: model, conf: conf}
 }
 
 func init() {
 	models.RegisterAPI("Ollama", CreateAPIClient)
+	models.RegisterEmbeddingAPI("Ollama", CreateEmbeddingClient)
 }
@@ -1,27 +1,69 @@
 package ollama
 
-type OllamaRequest struct {
-	Model   string    `json:"model"`
-	Prompt  string    `json:"prompt"`
-	Stream  bool      `json:"stream"`
-	Images  *[]string `json:"images,omitempty"`
-	Options Options   `json:"options,omitempty"`
-}
-
-type Options struct {
-	Temperature float32 `json:""`
-}
-
-type OllamaFullResponse struct {
-	Model              string `json:"model"`
-	CreatedAt          string `json:"created_at"`
-	Response           string `json:"response"` // Date field. Parse if needed
-	Done               bool   `json:"done"`
-	Context            []int  `json:"context"`
-	TotalDuration      int64  `json:"total_duration"`
-	LoadDuration       int64  `json:"load_duration"`
-	PromptEvalCount    int    `json:"prompt_eval_count"`
-	PromptEvalDuration int64  `json:"prompt_eval_duration"`
-	EvalCount          int    `json:"eval_count"`
-	EvalDuration       int64  `json:"eval_duration"`
+type OllamaChatMessage struct {
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"images,omitempty"` // Base64 encoded images, without a data URL prefix
+}
+
+type OllamaOptions struct {
+	NumCtx      int      `json:"num_ctx,omitempty"`     // Context window size in tokens. Prompts longer than this are truncated
+	NumPredict  int      `json:"num_predict,omitempty"` // Maximum number of tokens to generate
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+}
+
+type OllamaChatRequest struct {
+	Model     string              `json:"model"`
+	Messages  []OllamaChatMessage `json:"messages"`
+	Stream    bool                `json:"stream"`
+	KeepAlive interface{}         `json:"keep_alive,omitempty"` // How long the model stays loaded after the request, e.g. "5m" or a number of seconds
+	Options   *OllamaOptions      `json:"options,omitempty"`
+}
+
+type OllamaChatResponse struct {
+	Model              string            `json:"model"`
+	CreatedAt          string            `json:"created_at"`
+	Message            OllamaChatMessage `json:"message"`
+	Done               bool              `json:"done"`
+	DoneReason         string            `json:"done_reason"`
+	TotalDuration      int64             `json:"total_duration"`
+	LoadDuration       int64             `json:"load_duration"`
+	PromptEvalCount    int               `json:"prompt_eval_count"`
+	PromptEvalDuration int64             `json:"prompt_eval_duration"`
+	EvalCount          int               `json:"eval_count"`
+	EvalDuration       int64             `json:"eval_duration"`
+}
+
+type OllamaEmbedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type OllamaEmbedResponse struct {
+	Model      string      `json:"model"`
+	Embeddings [][]float32 `json:"embeddings"`
+}
+
+type OllamaTagsModel struct {
+	Name  string `json:"name"`
+	Model string `json:"model"`
+}
+
+type OllamaTagsResponse struct {
+	Models []OllamaTagsModel `json:"models"`
+}
+
+type OllamaShowRequest struct {
+	Model string `json:"model"`
+}
+
+type OllamaShowDetails struct {
+	Family   string   `json:"family"`
+	Families []string `json:"families"`
+}
+
+type OllamaShowResponse struct {
+	Details      OllamaShowDetails `json:"details"`
+	Capabilities []string          `json:"capabilities"`
 }
@@ -0,0 +1,90 @@
+package openai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"recap/internal/config"
+	"recap/internal/models"
+)
+
+type EmbeddingModel struct {
+	ApiName   string
+	ApiKeyPtr *string
+	client    *http.Client
+	Endpoint  string
+	Model     string
+}
+
+// Generates embeddings for the given texts with a single request to the embeddings endpoint.
+// It returns one vector per text, ordered like texts, or an error.
+func (e *EmbeddingModel) Embed(texts []string) ([][]float32, error) {
+	requestBody := OpenAIEmbeddingRequest{
+		Model: e.Model,
+		Input: texts,
+	}
+
+	preparedBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling JSON request body: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", e.Endpoint, bytes.NewBuffer(preparedBody))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request to %s: %w", e.ApiName, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", *e.ApiKeyPtr))
+
+	res, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request to %s: %w", e.ApiName, err)
+	}
+	defer res.Body.Close()
+
+	readRes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response from %s: %w", e.ApiName, err)
+	}
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s API returned non-2xx status: %d - %s", e.ApiName, res.StatusCode, string(readRes))
+	}
+
+	var embeddingResponse OpenAIEmbeddingResponse
+	err = json.Unmarshal(readRes, &embeddingResponse)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding %s response: %w", e.ApiName, err)
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, data := range embeddingResponse.Data {
+		if data.Index < 0 || data.Index >= len(texts) {
+			return nil, fmt.Errorf("%s returned an embedding with invalid index %d", e.ApiName, data.Index)
+		}
+		vectors[data.Index] = data.Embedding
+	}
+
+	for i, vec := range vectors {
+		if vec == nil {
+			return nil, fmt.Errorf("%s did not return an embedding for text %d", e.ApiName, i)
+		}
+	}
+
+	return vectors, nil
+}
+
+func (e *EmbeddingModel) GetAPIName() string {
+	return e.ApiName
+}
+
+func (e *EmbeddingModel) GetAPIModelName() string {
+	return e.Model
+}
+
+// Initializes a new EmbeddingModel instance with the specified model name.
+func CreateEmbeddingClient(model string) models.EmbeddingAPI {
+	return &EmbeddingModel{ApiName: "OpenAI", client: models.NewHTTPClient("OpenAI"), Endpoint: "https://api.openai.com/v1/embeddings", Model: model, ApiKeyPtr: &config.Config.OpenAIAPIKey}
+}
@@ -0,0 +1,130 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"recap/internal/models"
+	"slices"
+	"strings"
+)
+
+// Returns the base URL of the API, e.g. https://api.openai.com/v1 for https://api.openai.com/v1/chat/completions
+func (a *AIModel) baseURL() string {
+	return strings.TrimSuffix(strings.TrimSuffix(a.Endpoint, "/"), "/chat/completions")
+}
+
+// Sends an authenticated GET request to a path under the API's base URL and decodes the JSON response into out
+func (a *AIModel) getJSON(ctx context.Context, client *http.Client, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.baseURL()+path, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request to %s: %w", a.ApiName, err)
+	}
+	a.setHeaders(req)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending request to %s: %w", a.ApiName, err)
+	}
+	defer res.Body.Close()
+
+	readRes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response from %s: %w", a.ApiName, err)
+	}
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return &models.APIError{API: a.ApiName, StatusCode: res.StatusCode, Message: string(readRes)}
+	}
+
+	if err := json.Unmarshal(readRes, out); err != nil {
+		return fmt.Errorf("error decoding %s response: %w", a.ApiName, err)
+	}
+
+	return nil
+}
+
+// Checks that the API is reachable, that the API key is accepted, that the model exists,
+// and whether it supports images if the API reports it
+func (a *AIModel) HealthCheck(ctx context.Context) *models.HealthCheckResult {
+	result := models.NewHealthCheckResult(a.ApiName, a.Model)
+	client := models.NewHTTPClient(a.ApiName)
+
+	var list OpenAIModelsResponse
+	if err := a.getJSON(ctx, client, "/models", &list); err != nil {
+		if models.ClassifyError(err) == models.ErrorClassAuth {
+			result.Add(models.HealthCheckReachable, models.HealthCheckPassed, "%s is reachable", a.ApiName)
+			result.Fail(models.HealthCheckCredentials, models.DescribeHealthCheckError(err), models.HealthCheckModel, models.HealthCheckVision)
+		} else {
+			result.Fail(models.HealthCheckReachable, models.DescribeHealthCheckError(err), models.HealthCheckCredentials, models.HealthCheckModel, models.HealthCheckVision)
+		}
+		return result.Finish()
+	}
+	result.Add(models.HealthCheckReachable, models.HealthCheckPassed, "%s is reachable", a.ApiName)
+
+	// Some APIs list models without authentication, so the key has to be checked separately
+	if a.AuthCheckPath != "" {
+		var keyInfo json.RawMessage
+		if err := a.getJSON(ctx, client, a.AuthCheckPath, &keyInfo); err != nil {
+			result.Fail(models.HealthCheckCredentials, models.DescribeHealthCheckError(err), models.HealthCheckModel, models.HealthCheckVision)
+			return result.Finish()
+		}
+	}
+	if *a.ApiKeyPtr == "" {
+		result.Add(models.HealthCheckCredentials, models.HealthCheckSkipped, "No API key is set")
+	} else {
+		result.Add(models.HealthCheckCredentials, models.HealthCheckPassed, "API key was accepted")
+	}
+
+	idx := slices.IndexFunc(list.Data, func(m OpenAIModel) bool { return m.ID == a.Model })
+	if idx == -1 {
+		result.Fail(models.HealthCheckModel, fmt.Errorf("model %s isn't available on %s", a.Model, a.ApiName), models.HealthCheckVision)
+		return result.Finish()
+	}
+	result.Add(models.HealthCheckModel, models.HealthCheckPassed, "Model %s is available", a.Model)
+
+	switch a.getVisionSupport(list.Data[idx]) {
+	case models.VisionSupported:
+		result.Add(models.HealthCheckVision, models.HealthCheckPassed, "Model supports images")
+	case models.VisionUnsupported:
+		result.Add(models.HealthCheckVision, models.HealthCheckFailed, "Model doesn't support images and can only be used for reports")
+	default:
+		result.Add(models.HealthCheckVision, models.HealthCheckUnknown, "%s doesn't report whether models support images", a.ApiName)
+	}
+
+	return result.Finish()
+}
+
+// Returns whether a model accepts images, going by the Vision field if it's set, or the architecture
+// OpenRouter reports otherwise. OpenAI doesn't report it
+func (a *AIModel) getVisionSupport(m OpenAIModel) models.VisionSupport {
+	arch := m.Architecture
+	switch {
+	case a.Vision != "":
+		return a.Vision
+	case arch == nil:
+		return models.VisionUnknown
+	case slices.Contains(arch.InputModalities, "image"), strings.Contains(strings.SplitN(arch.Modality, "->", 2)[0], "image"):
+		return models.VisionSupported
+	default:
+		return models.VisionUnsupported
+	}
+}
+
+// Lists the models available from the API's /models endpoint
+func (a *AIModel) ListModels(ctx context.Context) ([]models.ModelInfo, error) {
+	var res OpenAIModelsResponse
+	if err := a.getJSON(ctx, models.NewHTTPClient(a.ApiName), "/models", &res); err != nil {
+		return nil, err
+	}
+
+	list := make([]models.ModelInfo, len(res.Data))
+	for i, m := range res.Data {
+		list[i] = models.ModelInfo{Name: m.ID, Vision: a.getVisionSupport(m)}
+	}
+
+	slices.SortFunc(list, func(x, y models.ModelInfo) int { return strings.Compare(x.Name, y.Name) })
+	return list, nil
+}
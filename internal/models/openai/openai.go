@@ -9,19 +9,22 @@ import (
 	"recap/internal/config"
 	"recap/internal/models"
 	"recap/internal/utils"
-	"strings"
 	"sync"
 	"time"
 )
 
 type AIModel struct {
-	ApiName      string
-	ApiKeyPtr    *string
-	client       *http.Client
-	clientTicker *time.Ticker
-	Endpoint     string // Used to set endpoint to OpenAI's API, or OpenRouter's
-	Model        string
-	mu           sync.Mutex // Added mutex for thread-safety
+	ApiName       string
+	ApiKeyPtr     *string
+	AuthCheckPath string // Path under the base URL used to validate the API key, if listing models doesn't require one
+	client        *http.Client
+	clientTicker  *time.Ticker
+	Endpoint      string               // Used to set endpoint to OpenAI's API, or OpenRouter's
+	Headers       map[string]string    // Extra headers sent with every request
+	Vision        models.VisionSupport // Set if it's known whether the model accepts images, otherwise read from the model list
+	Model         string
+	params        models.GenerationParams
+	mu            sync.Mutex // Added mutex for thread-safety
 }
 
 // Starts or resets a timer that closes the HTTP client
@@ -64,7 +67,7 @@ func (a *AIModel) GenerateText(prompt string) (string, error) {
 	}
 	defer a.startClientDeadline()
 
-	return sendToOpenAI(client, a.Model, nil, prompt, a.Endpoint, *a.ApiKeyPtr)
+	return a.sendToOpenAI(client, nil, prompt)
 }
 
 // Generates a description for a screenshot specified by its filename
@@ -76,50 +79,101 @@ func (a *AIModel) DescribeScreenshot(fileName string, prompt string) (string, er
 	}
 	defer a.startClientDeadline()
 
-	return sendToOpenAI(client, a.Model, &fileName, prompt, a.Endpoint, *a.ApiKeyPtr)
+	if err := a.checkVision(); err != nil {
+		return "", err
+	}
+
+	return a.sendToOpenAI(client, []string{fileName}, prompt)
 }
 
-// Generates descriptions for multiple screenshots
-// provided in the fileNames slice. It returns concatenated descriptions or an error.
-func (a *AIModel) DescribeBulkScreenshots(fileNames []string, prompt string) (string, error) {
+// Generates descriptions for multiple screenshots provided in the fileNames slice
+// with a single request. It returns one description per screenshot or an error.
+func (a *AIModel) DescribeBulkScreenshots(fileNames []string, prompt string) ([]string, error) {
 	client := a.generateClient()
 	if client == nil {
-		return "", fmt.Errorf("failed to create client")
+		return nil, fmt.Errorf("failed to create client")
 	}
 	defer a.startClientDeadline()
 
-	var descriptions []string
+	if err := a.checkVision(); err != nil {
+		return nil, err
+	}
+
+	res, err := a.sendToOpenAI(client, fileNames, models.BuildBulkPrompt(prompt, len(fileNames)))
+	if err != nil {
+		return nil, fmt.Errorf("error sending files to %s: %w", a.ApiName, err)
+	}
+
+	return models.ParseBulkResponse(res, len(fileNames))
+}
 
-	for _, fn := range fileNames {
-		res, err := sendToOpenAI(client, a.Model, &fn, prompt, a.Endpoint, *a.ApiKeyPtr)
-		if err != nil {
-			return "", fmt.Errorf("error sending file to OpenAI: %w", err)
+// Builds the content of a chat message from the prompt and the screenshots, attached as data URLs
+func buildContent(fileNames []string, prompt string) ([]ChatContentPart, error) {
+	content := make([]ChatContentPart, 0, len(fileNames)+1)
+	content = append(content, ChatContentPart{Type: "text", Text: prompt})
+
+	for _, fileName := range fileNames {
+		dataURL := utils.ReadImageToBase64(fileName)
+		if dataURL == "" {
+			return nil, fmt.Errorf("failed to read image file %s", fileName)
 		}
-		descriptions = append(descriptions, res)
+		content = append(content, ChatContentPart{Type: "image_url", ImageURL: &ChatImageURL{URL: dataURL}})
+	}
+
+	return content, nil
+}
+
+// Returns an error without sending a request if the model is known not to accept images
+func (a *AIModel) checkVision() error {
+	if a.Vision == models.VisionUnsupported {
+		return &models.APIError{API: a.ApiName, StatusCode: http.StatusBadRequest, Message: fmt.Sprintf("model %s is set as not supporting images", a.Model)}
 	}
+	return nil
+}
 
-	return strings.Join(descriptions, "\n"), nil
+// Sets the authorization and extra headers on a request. Authorization is left out if there's no API key,
+// as local servers usually don't need one
+func (a *AIModel) setHeaders(req *http.Request) {
+	if *a.ApiKeyPtr != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", *a.ApiKeyPtr))
+	}
+	for name, value := range a.Headers {
+		req.Header.Set(name, value)
+	}
 }
 
-// Sends a request to the OpenAI API with the specified client, model,
-// and image data (if applicable). It returns the response from the API or an error.
-func sendToOpenAI(client *http.Client, modelName string, fileName *string, prompt string, endpoint string, apiKey string) (string, error) {
-	var images []string
-	if fileName != nil {
-		imageBase64 := utils.ReadImageToBase64(*fileName)
-		if imageBase64 == "" {
-			return "", fmt.Errorf("failed to read image file")
+// Converts an error response to *models.APIError, using the message and code from the error body if it has one
+func parseErrorResponse(apiName string, statusCode int, body []byte) error {
+	apiErr := &models.APIError{API: apiName, StatusCode: statusCode, Message: string(body)}
+
+	var errRes OpenAIErrorResponse
+	if err := json.Unmarshal(body, &errRes); err == nil && errRes.Error.Message != "" {
+		apiErr.Message = errRes.Error.Message
+		apiErr.Code = errRes.Error.Type
+		if code, ok := errRes.Error.Code.(string); ok && code != "" {
+			apiErr.Code = code
 		}
-		images = append(images, imageBase64)
 	}
 
-	apiBearerAuth := fmt.Sprintf("Bearer %s", apiKey)
+	return apiErr
+}
+
+// Sends a chat completion request to the API with the specified client and screenshots
+// (if any file names are given). It returns the response from the API or an error.
+// Error statuses are returned as *models.APIError
+func (a *AIModel) sendToOpenAI(client *http.Client, fileNames []string, prompt string) (string, error) {
+	content, err := buildContent(fileNames, prompt)
+	if err != nil {
+		return "", err
+	}
 
-	requestBody := OpenAIRequest{
-		Model:  modelName,
-		Prompt: prompt,
-		Stream: false,
-		Images: &images,
+	requestBody := ChatCompletionRequest{
+		Model:       a.Model,
+		Messages:    []ChatMessage{{Role: "user", Content: content}},
+		Stream:      false,
+		Temperature: a.params.Temperature,
+		TopP:        a.params.TopP,
+		MaxTokens:   a.params.MaxTokens,
 	}
 
 	preparedBody, err := json.Marshal(requestBody)
@@ -127,36 +181,43 @@ func sendToOpenAI(client *http.Client, modelName string, fileName *string, promp
 		return "", fmt.Errorf("error marshalling JSON request body: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(preparedBody))
+	req, err := http.NewRequest("POST", a.Endpoint, bytes.NewBuffer(preparedBody))
 	if err != nil {
-		return "", fmt.Errorf("error creating request to OpenAI: %w", err)
+		return "", fmt.Errorf("error creating request to %s: %w", a.ApiName, err)
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", apiBearerAuth) // Replace with your actual API key
+	a.setHeaders(req)
 
 	res, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("error sending request to OpenAI: %w", err)
+		return "", fmt.Errorf("error sending request to %s: %w", a.ApiName, err)
 	}
 	defer res.Body.Close()
 
+	readRes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading response from %s: %w", a.ApiName, err)
+	}
+
 	if res.StatusCode < 200 || res.StatusCode >= 300 {
-		errorMessage, _ := io.ReadAll(res.Body)
-		return "", fmt.Errorf("OpenAI API returned non-2xx status: %d - %s", res.StatusCode, string(errorMessage))
+		return "", parseErrorResponse(a.ApiName, res.StatusCode, readRes)
 	}
 
-	readRes, err := io.ReadAll(res.Body)
+	var chatResponse ChatCompletionResponse
+	err = json.Unmarshal(readRes, &chatResponse)
 	if err != nil {
-		return "", fmt.Errorf("error reading response from OpenAI: %w", err)
+		return "", fmt.Errorf("error decoding %s response: %w", a.ApiName, err)
 	}
 
-	var openaiResponse OpenAIFullResponse
-	err = json.Unmarshal(readRes, &openaiResponse)
-	if err != nil {
-		return "", fmt.Errorf("error decoding OpenAI response: %v", err.Error())
+	if len(chatResponse.Choices) == 0 {
+		return "", fmt.Errorf("%s returned no choices", a.ApiName)
+	}
+
+	if chatResponse.Usage != nil {
+		fmt.Printf("%s %s used %d prompt and %d completion tokens\n", a.ApiName, a.Model, chatResponse.Usage.PromptTokens, chatResponse.Usage.CompletionTokens)
 	}
 
-	return openaiResponse.Response, nil
+	return chatResponse.Choices[0].Message.Content, nil
 }
 
 // Creates and returns a new HTTP client if one does not already exist.
@@ -166,12 +227,21 @@ func (a *AIModel) generateClient() *http.Client {
 	defer a.mu.Unlock()
 
 	if a.client == nil {
-		a.client = &http.Client{}
+		a.client = models.NewHTTPClient(a.ApiName)
 	}
 
 	return a.client
 }
 
+// OpenAI accepts temperatures between 0 and 2, as do OpenRouter and most OpenAI-compatible servers
+func (a *AIModel) SetGenerationParams(params models.GenerationParams) error {
+	if err := params.Validate(a.ApiName, 2); err != nil {
+		return err
+	}
+	a.params = params
+	return nil
+}
+
 func (a *AIModel) GetAPIName() string {
 	return a.ApiName
 }
@@ -180,11 +250,12 @@ func (a *AIModel) GetAPIModelName() string {
 	return a.Model
 }
 
-// Initializes a new AIModel instance with the specified model name.
-func CreateAPIClient(model string) models.TextVisionAPI {
-	return &AIModel{ApiName: "OpenAI", Endpoint: "https://api.openai.com/v1/completions", Model: model, ApiKeyPtr: &config.Config.OpenAIAPIKey}
+// Initializes a new AIModel instance with the specified model name, using the API key in conf.
+func CreateAPIClient(model string, conf *config.AppConfig) models.TextVisionAPI {
+	return &AIModel{ApiName: "OpenAI", Endpoint: "https://api.openai.com/v1/chat/completions", Model: model, ApiKeyPtr: &conf.OpenAIAPIKey}
 }
 
 func init() {
 	models.RegisterAPI("OpenAI", CreateAPIClient)
+	models.RegisterEmbeddingAPI("OpenAI", CreateEmbeddingClient)
 }
@@ -1,27 +1,88 @@
 package openai
 
-type OpenAIRequest struct {
-	Model   string    `json:"model"`
-	Prompt  string    `json:"prompt"`
-	Stream  bool      `json:"stream"`
-	Images  *[]string `json:"images,omitempty"`
-	Options Options   `json:"options,omitempty"`
-}
-
-type Options struct {
-	Temperature float32 `json:"temperature"`
-}
-
-type OpenAIFullResponse struct {
-	Model              string `json:"model"`
-	CreatedAt          string `json:"created_at"`
-	Response           string `json:"response"`
-	Done               bool   `json:"done"`
-	Context            []int  `json:"context"`
-	TotalDuration      int64  `json:"total_duration"`
-	LoadDuration       int64  `json:"load_duration"`
-	PromptEvalCount    int    `json:"prompt_eval_count"`
-	PromptEvalDuration int64  `json:"prompt_eval_duration"`
-	EvalCount          int    `json:"eval_count"`
-	EvalDuration       int64  `json:"eval_duration"`
+type ChatImageURL struct {
+	URL string `json:"url"` // Data URL of the image
+}
+
+// A part of a message's content, either text or an image
+type ChatContentPart struct {
+	Type     string        `json:"type"` // "text" or "image_url"
+	Text     string        `json:"text,omitempty"`
+	ImageURL *ChatImageURL `json:"image_url,omitempty"`
+}
+
+type ChatMessage struct {
+	Role    string            `json:"role"`
+	Content []ChatContentPart `json:"content"`
+}
+
+type ChatCompletionRequest struct {
+	Model       string        `json:"model"`
+	Messages    []ChatMessage `json:"messages"`
+	Stream      bool          `json:"stream"`
+	Temperature *float64      `json:"temperature,omitempty"`
+	TopP        *float64      `json:"top_p,omitempty"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
+}
+
+type ChatResponseMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ChatChoice struct {
+	Index        int                 `json:"index"`
+	Message      ChatResponseMessage `json:"message"`
+	FinishReason string              `json:"finish_reason"`
+}
+
+type ChatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type ChatCompletionResponse struct {
+	ID      string       `json:"id"`
+	Model   string       `json:"model"`
+	Choices []ChatChoice `json:"choices"`
+	Usage   *ChatUsage   `json:"usage,omitempty"`
+}
+
+// Error body returned by OpenAI and OpenRouter along with error statuses
+type OpenAIErrorResponse struct {
+	Error struct {
+		Message string      `json:"message"`
+		Type    string      `json:"type"`
+		Code    interface{} `json:"code"` // A string on OpenAI, a number on OpenRouter
+	} `json:"error"`
+}
+
+type OpenAIEmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type OpenAIEmbeddingData struct {
+	Index     int       `json:"index"`
+	Embedding []float32 `json:"embedding"`
+}
+
+type OpenAIEmbeddingResponse struct {
+	Model string                `json:"model"`
+	Data  []OpenAIEmbeddingData `json:"data"`
+}
+
+type OpenAIModelArchitecture struct {
+	Modality        string   `json:"modality"`
+	InputModalities []string `json:"input_modalities"`
+}
+
+type OpenAIModel struct {
+	ID           string                   `json:"id"`
+	Architecture *OpenAIModelArchitecture `json:"architecture,omitempty"` // Only returned by OpenRouter
+}
+
+type OpenAIModelsResponse struct {
+	Data []OpenAIModel `json:"data"`
 }
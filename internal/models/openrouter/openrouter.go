@@ -12,9 +12,9 @@ This creates an OpenAI API connector instance with a different ApiName and Endpo
 pointing the connector to OpenRouter's server
 */
 
-// Initializes a new AIModel instance with the specified model name.
-func CreateAPIClient(model string) models.TextVisionAPI {
-	return &openai.AIModel{ApiName: "OpenRouter", Endpoint: "https://openrouter.ai/api/v1/chat/completions", Model: model, ApiKeyPtr: &config.Config.OpenRouterAPIKey}
+// Initializes a new AIModel instance with the specified model name, using the API key in conf.
+func CreateAPIClient(model string, conf *config.AppConfig) models.TextVisionAPI {
+	return &openai.AIModel{ApiName: "OpenRouter", Endpoint: "https://openrouter.ai/api/v1/chat/completions", Model: model, ApiKeyPtr: &conf.OpenRouterAPIKey, AuthCheckPath: "/key"}
 }
 
 func init() {
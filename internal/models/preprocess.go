@@ -0,0 +1,24 @@
+package models
+
+import (
+	"log"
+	"recap/internal/preprocess"
+)
+
+// Returns the file names of screenshots prepared with an API's image settings.
+// Screenshots that can't be processed are sent as they were saved
+func preprocessScreenshots(api string, fileNames []string) []string {
+	opts := preprocess.GetOptions(api)
+	processed := make([]string, len(fileNames))
+
+	for i, fileName := range fileNames {
+		name, err := preprocess.Image(fileName, opts)
+		if err != nil {
+			log.Printf("Sending %s without preprocessing: %v", fileName, err)
+			name = fileName
+		}
+		processed[i] = name
+	}
+
+	return processed
+}
@@ -1,5 +1,7 @@
 package models
 
+import "context"
+
 type TextVisionAPI interface {
 	// Get this API's name
 	GetAPIName() string
@@ -15,6 +17,19 @@ type TextVisionAPI interface {
 	// an error if one is received
 	DescribeScreenshot(fileName string, prompt string) (string, error)
 
-	// Helper function to describe screenshots in bulk. Works almost the same as DescribeScreenshot under the hood
-	DescribeBulkScreenshots(fileNames []string, prompt string) (string, error)
+	// Describes multiple screenshots in a single request. Returns one description per file name,
+	// in the same order as fileNames, or an error if the response could not be split per screenshot
+	DescribeBulkScreenshots(fileNames []string, prompt string) ([]string, error)
+
+	// Checks whether the API is reachable, the credentials are valid, the model is available
+	// and whether it supports images. Failures are reported in the result rather than as errors
+	HealthCheck(ctx context.Context) *HealthCheckResult
+
+	// Lists the models this API offers and whether they accept images. The model this instance
+	// was created with doesn't affect the result
+	ListModels(ctx context.Context) ([]ModelInfo, error)
+
+	// Sets the sampling parameters used by later requests. Returns an error, leaving the current
+	// parameters unchanged, if they're outside the ranges the API accepts
+	SetGenerationParams(params GenerationParams) error
 }
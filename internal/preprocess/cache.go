@@ -0,0 +1,77 @@
+package preprocess
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Folder inside ScrPath processed screenshots are cached in
+const cacheDirName = "preprocessed"
+
+// Processed screenshots are kept long enough for failed descriptions to be retried, then deleted
+const cacheMaxAge = 24 * time.Hour
+
+func cacheDir() string {
+	return scrPath(cacheDirName)
+}
+
+// Writes a processed screenshot to the cache. It's written to a temporary file first, so a screenshot that's
+// being processed elsewhere is never read half-written
+func writeCache(cacheName string, data []byte) error {
+	if err := os.MkdirAll(cacheDir(), 0755); err != nil {
+		return err
+	}
+
+	fullPath := scrPath(cacheName)
+	tmp, err := os.CreateTemp(cacheDir(), filepath.Base(cacheName)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), fullPath)
+}
+
+// Deletes the cached versions of a screenshot, for when the screenshot itself is deleted
+func RemoveCached(fileName string) {
+	base := strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+	matches, _ := filepath.Glob(filepath.Join(cacheDir(), base+"_*"))
+
+	for _, match := range matches {
+		os.Remove(match)
+	}
+}
+
+// Deletes processed screenshots that were cached more than cacheMaxAge ago
+func PruneCache() {
+	entries, err := os.ReadDir(cacheDir())
+	if err != nil {
+		return
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < cacheMaxAge {
+			continue
+		}
+		if os.Remove(filepath.Join(cacheDir(), entry.Name())) == nil {
+			removed++
+		}
+	}
+
+	if removed > 0 {
+		log.Printf("Removed %d processed screenshots from the cache", removed)
+	}
+}
@@ -0,0 +1,66 @@
+package preprocess
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+)
+
+// Keyword of the PNG text chunk that holds the bounds of the display that was active when a screenshot was taken
+const displayChunkKeyword = "Recap Active Display"
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// Adds the bounds of the display that was active when a screenshot was taken to its PNG data, so the screenshot
+// can later be cropped to it. The bounds are saved as a tEXt chunk right after the image header
+func AddDisplayBounds(data []byte, bounds image.Rectangle) ([]byte, error) {
+	// The signature is followed by the IHDR chunk: 4 bytes of length, 4 of type, 13 of data and 4 of CRC
+	headerEnd := len(pngSignature) + 25
+	if len(data) < headerEnd || !bytes.HasPrefix(data, pngSignature) {
+		return nil, fmt.Errorf("not a PNG image")
+	}
+
+	text := fmt.Sprintf("%s\x00%d,%d,%d,%d", displayChunkKeyword, bounds.Min.X, bounds.Min.Y, bounds.Max.X, bounds.Max.Y)
+
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(text)))
+	chunk = append(chunk, "tEXt"...)
+	chunk = append(chunk, text...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+	out := make([]byte, 0, len(data)+len(chunk))
+	out = append(out, data[:headerEnd]...)
+	out = append(out, chunk...)
+	return append(out, data[headerEnd:]...), nil
+}
+
+// Reads the bounds saved by AddDisplayBounds, relative to the top left corner of the screenshot.
+// Returns false if the data isn't a PNG image or has no bounds saved
+func readDisplayBounds(data []byte) (image.Rectangle, bool) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return image.Rectangle{}, false
+	}
+
+	// Text chunks saved by AddDisplayBounds come before the image data, so the search stops there
+	for pos := len(pngSignature); pos+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		chunkType := string(data[pos+4 : pos+8])
+		if chunkType == "IDAT" || pos+12+length > len(data) {
+			break
+		}
+
+		if chunkType == "tEXt" {
+			keyword, value, _ := bytes.Cut(data[pos+8:pos+8+length], []byte{0})
+			if string(keyword) == displayChunkKeyword {
+				var r image.Rectangle
+				_, err := fmt.Sscanf(string(value), "%d,%d,%d,%d", &r.Min.X, &r.Min.Y, &r.Max.X, &r.Max.Y)
+				return r, err == nil && !r.Empty()
+			}
+		}
+
+		pos += 12 + length
+	}
+
+	return image.Rectangle{}, false
+}
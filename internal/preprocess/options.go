@@ -0,0 +1,74 @@
+package preprocess
+
+import (
+	"fmt"
+	"log"
+	"recap/internal/config"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Returns the preprocessing options for an API, from the image settings with the API's overrides applied
+func GetOptions(api string) Options {
+	opts := Options{
+		MaxEdge:   max(config.Config.ImageMaxEdge, 0),
+		Format:    config.Config.ImageFormat,
+		Quality:   config.Config.ImageQuality,
+		Grayscale: config.Config.ImageGrayscale == 1,
+		Crop:      config.Config.ImageCropToActiveDisplay == 1,
+	}
+
+	overrides, err := ParseOverrides(config.Config.ImageOverrides)
+	if err != nil {
+		log.Printf("Ignoring image overrides: %v", err)
+		return opts
+	}
+
+	for key, value := range overrides[api] {
+		switch key {
+		case "max_edge":
+			opts.MaxEdge, _ = strconv.Atoi(value)
+			opts.MaxEdge = max(opts.MaxEdge, 0)
+		case "format":
+			opts.Format = value
+		case "quality":
+			opts.Quality, _ = strconv.Atoi(value)
+		case "grayscale":
+			opts.Grayscale = value == "1"
+		case "crop":
+			opts.Crop = value == "1"
+		}
+	}
+
+	return opts
+}
+
+// Parses per-API overrides of the image settings, given as semicolon-separated "API: key=value, key=value" entries,
+// e.g. "Ollama: max_edge=1344, format=jpeg; Gemini: max_edge=3072". Keys are max_edge, format, quality,
+// grayscale and crop, with grayscale and crop set to 1 or 0
+func ParseOverrides(list string) (map[string]map[string]string, error) {
+	return config.ParseOverrides(list, validateOverride)
+}
+
+// Checks one image override
+func validateOverride(api string, key string, value string) error {
+	switch key {
+	case "max_edge", "quality":
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("%s for %s must be a number", key, api)
+		}
+	case "format":
+		if !slices.Contains(Formats, value) {
+			return fmt.Errorf("format for %s must be one of %s", api, strings.Join(Formats, ", "))
+		}
+	case "grayscale", "crop":
+		if value != "0" && value != "1" {
+			return fmt.Errorf("%s for %s must be 1 or 0", key, api)
+		}
+	default:
+		return fmt.Errorf("unknown override %q for %s", key, api)
+	}
+
+	return nil
+}
@@ -0,0 +1,234 @@
+package preprocess
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"recap/internal/config"
+	"strings"
+
+	"github.com/chai2010/webp"
+)
+
+/**
+Screenshots are prepared for each vision API before they're sent: cropped to the display that was active when they
+were taken, downscaled, converted to grayscale, and re-encoded as JPEG or WebP, depending on the settings.
+Processed images are cached on disk, so retries and fallbacks to an API with the same settings don't redo the work
+*/
+
+// Formats screenshots can be converted to. FormatOriginal keeps the format they were saved in
+const (
+	FormatOriginal = "original"
+	FormatJPEG     = "jpeg"
+	FormatWebP     = "webp"
+)
+
+var Formats = []string{FormatOriginal, FormatJPEG, FormatWebP}
+
+// Steps applied to a screenshot before it's sent to a vision API
+type Options struct {
+	MaxEdge   int    // Longest side in pixels the image is downscaled to. 0 keeps its size
+	Format    string // One of Formats
+	Quality   int    // JPEG and WebP quality, from 1 to 100
+	Grayscale bool   // Converts the image to grayscale
+	Crop      bool   // Crops the image to the display that was active when it was taken
+}
+
+// Reports whether the options leave every screenshot as it is
+func (o Options) isNoop() bool {
+	return o.MaxEdge <= 0 && !o.Grayscale && !o.Crop && (o.Format == "" || o.Format == FormatOriginal)
+}
+
+// Returns the name of a screenshot prepared with the given options, relative to ScrPath like the screenshot's own.
+// The screenshot's name is returned as is if none of the steps change it
+func Image(fileName string, opts Options) (string, error) {
+	if opts.isNoop() {
+		return fileName, nil
+	}
+
+	srcPath := scrPath(fileName)
+	info, err := os.Stat(srcPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read image file %s: %w", fileName, err)
+	}
+
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read image file %s: %w", fileName, err)
+	}
+
+	cfg, srcFormat, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode image file %s: %w", fileName, err)
+	}
+
+	format := opts.Format
+	if format == "" || format == FormatOriginal {
+		format = srcFormat
+	}
+
+	bounds := image.Rect(0, 0, cfg.Width, cfg.Height)
+	if opts.Crop {
+		if display, ok := readDisplayBounds(data); ok && !display.Intersect(bounds).Empty() {
+			bounds = display.Intersect(bounds)
+		}
+	}
+
+	width, height := fitWithin(bounds.Dx(), bounds.Dy(), opts.MaxEdge)
+	cropped := bounds != image.Rect(0, 0, cfg.Width, cfg.Height)
+	resized := width != bounds.Dx() || height != bounds.Dy()
+
+	if !cropped && !resized && !opts.Grayscale && format == srcFormat {
+		return fileName, nil
+	}
+
+	cacheName := cachedName(fileName, info, opts, format)
+	if _, err := os.Stat(scrPath(cacheName)); err == nil {
+		return cacheName, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode image file %s: %w", fileName, err)
+	}
+
+	if cropped {
+		img = crop(img, bounds)
+	}
+	if resized {
+		img = Resize(img, width, height)
+	}
+	if opts.Grayscale {
+		img = grayscale(img)
+	}
+
+	var buf bytes.Buffer
+	if err := encode(&buf, img, format, opts.Quality); err != nil {
+		return "", fmt.Errorf("failed to encode processed image %s: %w", fileName, err)
+	}
+
+	if err := writeCache(cacheName, buf.Bytes()); err != nil {
+		return "", fmt.Errorf("failed to cache processed image %s: %w", fileName, err)
+	}
+
+	return cacheName, nil
+}
+
+func scrPath(fileName string) string {
+	return filepath.Join(config.Config.ScrPath, fileName)
+}
+
+// Returns the size of an image of the given size scaled down to fit within maxEdge pixels on its longest side,
+// keeping its aspect ratio. Images that already fit, or a maxEdge of 0, keep their size
+func fitWithin(width int, height int, maxEdge int) (int, int) {
+	if maxEdge <= 0 || max(width, height) <= maxEdge {
+		return width, height
+	}
+
+	if width >= height {
+		return maxEdge, max(1, height*maxEdge/width)
+	}
+	return max(1, width*maxEdge/height), maxEdge
+}
+
+// Returns the part of an image within bounds, sharing its pixels when the image type allows it
+func crop(img image.Image, bounds image.Rectangle) image.Image {
+	if sub, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(bounds)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+	return dst
+}
+
+func grayscale(img image.Image) *image.Gray {
+	bounds := img.Bounds()
+	dst := image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+	return dst
+}
+
+// Resizes an image to the given size. Each pixel of the result is the average of the pixels it covers
+// in the original, which keeps text more legible than sampling single pixels
+func Resize(img image.Image, width int, height int) *image.RGBA {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*bounds.Dy()/height)
+
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(x0+1, bounds.Min.X+(x+1)*bounds.Dx()/width)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n >> 8)
+			dst.Pix[i+1] = uint8(g / n >> 8)
+			dst.Pix[i+2] = uint8(b / n >> 8)
+			dst.Pix[i+3] = uint8(a / n >> 8)
+		}
+	}
+
+	return dst
+}
+
+// Encodes an image in the given format ("png", "jpeg" or "webp")
+func encode(w io.Writer, img image.Image, format string, quality int) error {
+	quality = min(max(quality, 1), 100)
+
+	switch format {
+	case "png":
+		enc := png.Encoder{CompressionLevel: png.BestCompression}
+		return enc.Encode(w, img)
+	case FormatJPEG:
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case FormatWebP:
+		return webp.Encode(w, img, &webp.Options{Quality: float32(quality)})
+	default:
+		return fmt.Errorf("unsupported image format %q", format)
+	}
+}
+
+// Returns the file extension used for images in a format
+func extension(format string) string {
+	switch format {
+	case FormatJPEG:
+		return ".jpg"
+	case FormatWebP:
+		return ".webp"
+	default:
+		return "." + format
+	}
+}
+
+// Returns the name a processed screenshot is cached under. It changes whenever the screenshot or the options do,
+// so a stale image is never reused
+func cachedName(fileName string, info os.FileInfo, opts Options, format string) string {
+	key := fmt.Sprintf("%s|%d|%d|%d|%s|%d|%t|%t", fileName, info.Size(), info.ModTime().UnixNano(),
+		opts.MaxEdge, format, opts.Quality, opts.Grayscale, opts.Crop)
+	sum := sha256.Sum256([]byte(key))
+
+	base := strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+	return filepath.Join(cacheDirName, base+"_"+hex.EncodeToString(sum[:8])+extension(format))
+}
@@ -0,0 +1,283 @@
+package preprocess
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"recap/internal/config"
+	"testing"
+	"time"
+)
+
+// Encodes a PNG image of the given size with a gradient, so resized and cropped versions differ
+func testPNG(t *testing.T, width int, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// Points ScrPath at a temporary folder and saves a screenshot in it. Returns the screenshot's name
+func writeScreenshot(t *testing.T, data []byte) string {
+	t.Helper()
+
+	config.Config.ScrPath = t.TempDir()
+	if err := os.WriteFile(scrPath("capture.png"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return "capture.png"
+}
+
+func decodeFile(t *testing.T, fileName string) (image.Config, string) {
+	t.Helper()
+
+	data, err := os.ReadFile(scrPath(fileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("could not decode %s: %v", fileName, err)
+	}
+	return cfg, format
+}
+
+func TestFitWithin(t *testing.T) {
+	tests := []struct {
+		width, height, maxEdge int
+		wantW, wantH           int
+	}{
+		{1920, 1080, 0, 1920, 1080},
+		{1920, 1080, -1, 1920, 1080},
+		{1920, 1080, 1920, 1920, 1080},
+		{1920, 1080, 4000, 1920, 1080},
+		{1920, 1080, 960, 960, 540},
+		{1080, 1920, 960, 540, 960},
+		{1000, 1000, 500, 500, 500},
+		{3000, 1, 100, 100, 1},
+		{1, 3000, 100, 1, 100},
+	}
+
+	for _, test := range tests {
+		w, h := fitWithin(test.width, test.height, test.maxEdge)
+		if w != test.wantW || h != test.wantH {
+			t.Errorf("fitWithin(%d, %d, %d) = %d, %d, expected %d, %d", test.width, test.height, test.maxEdge, w, h, test.wantW, test.wantH)
+		}
+	}
+}
+
+func TestResizeAveragesPixels(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.RGBA{0, 0, 0, 255})
+	img.Set(1, 0, color.RGBA{200, 0, 0, 255})
+	img.Set(0, 1, color.RGBA{0, 200, 0, 255})
+	img.Set(1, 1, color.RGBA{200, 200, 0, 255})
+
+	resized := Resize(img, 1, 1)
+	if got := resized.RGBAAt(0, 0); got != (color.RGBA{100, 100, 0, 255}) {
+		t.Errorf("expected the average of the four pixels, got %v", got)
+	}
+
+	if b := Resize(img, 5, 3).Bounds(); b.Dx() != 5 || b.Dy() != 3 {
+		t.Errorf("expected a 5x3 image when upscaling, got %v", b)
+	}
+}
+
+func TestCrop(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	img.Set(6, 4, color.RGBA{255, 0, 0, 255})
+
+	cropped := crop(img, image.Rect(5, 3, 9, 8))
+	if b := cropped.Bounds(); b.Dx() != 4 || b.Dy() != 5 {
+		t.Fatalf("expected a 4x5 image, got %v", b)
+	}
+	if r, _, _, _ := cropped.At(6, 4).RGBA(); r>>8 != 255 {
+		t.Error("expected the cropped image to keep the pixels within the bounds")
+	}
+
+	// Images without SubImage are copied, starting at the origin
+	copied := crop(testUniform{image.NewUniform(color.White)}, image.Rect(2, 2, 6, 5))
+	if b := copied.Bounds(); b != image.Rect(0, 0, 4, 3) {
+		t.Errorf("expected a copied 4x3 image at the origin, got %v", b)
+	}
+}
+
+// Image without a SubImage method
+type testUniform struct{ *image.Uniform }
+
+func (u testUniform) Bounds() image.Rectangle { return image.Rect(0, 0, 10, 10) }
+
+func TestDisplayBoundsRoundTrip(t *testing.T) {
+	data := testPNG(t, 40, 20)
+	bounds := image.Rect(10, 0, 30, 20)
+
+	withBounds, err := AddDisplayBounds(data, bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := readDisplayBounds(withBounds)
+	if !ok || got != bounds {
+		t.Errorf("expected %v to be read back, got %v, %v", bounds, got, ok)
+	}
+
+	// The chunk must leave the PNG valid
+	if _, err := png.Decode(bytes.NewReader(withBounds)); err != nil {
+		t.Errorf("PNG with display bounds couldn't be decoded: %v", err)
+	}
+
+	if _, ok := readDisplayBounds(data); ok {
+		t.Error("expected no bounds in a PNG without them")
+	}
+}
+
+func TestDisplayBoundsRejectInvalidData(t *testing.T) {
+	data := testPNG(t, 4, 4)
+
+	if _, err := AddDisplayBounds([]byte("GIF89a not a png"), image.Rect(0, 0, 1, 1)); err == nil {
+		t.Error("expected non-PNG data to be rejected")
+	}
+	if _, err := AddDisplayBounds(data[:len(pngSignature)+10], image.Rect(0, 0, 1, 1)); err == nil {
+		t.Error("expected a truncated header to be rejected")
+	}
+
+	withBounds, err := AddDisplayBounds(data, image.Rect(0, 0, 2, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A chunk length pointing past the end of the data must not be read
+	huge := bytes.Clone(withBounds)
+	binary.BigEndian.PutUint32(huge[len(pngSignature)+25:], 0xFFFFFFF0)
+
+	inputs := map[string][]byte{
+		"empty":             nil,
+		"not a PNG":         []byte("just some text, not an image at all"),
+		"signature only":    pngSignature,
+		"truncated chunk":   withBounds[:len(pngSignature)+30],
+		"huge chunk length": huge,
+	}
+	for name, input := range inputs {
+		if _, ok := readDisplayBounds(input); ok {
+			t.Errorf("%s: expected no bounds", name)
+		}
+	}
+}
+
+func TestImageConvertsAndCaches(t *testing.T) {
+	fileName := writeScreenshot(t, testPNG(t, 200, 100))
+	opts := Options{MaxEdge: 50, Format: FormatJPEG, Quality: 80}
+
+	name, err := Image(fileName, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(name) != cacheDirName || filepath.Ext(name) != ".jpg" {
+		t.Errorf("expected a .jpg in the cache folder, got %s", name)
+	}
+	cfg, format := decodeFile(t, name)
+	if format != "jpeg" || cfg.Width != 50 || cfg.Height != 25 {
+		t.Errorf("expected a 50x25 JPEG, got a %dx%d %s", cfg.Width, cfg.Height, format)
+	}
+
+	// Mark the cached file, so a second call can be told to reuse it rather than write a new one
+	marked := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(scrPath(name), marked, marked); err != nil {
+		t.Fatal(err)
+	}
+	again, err := Image(fileName, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(scrPath(again))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != name || !info.ModTime().Equal(marked) {
+		t.Errorf("expected the second call to be served from the cache, got %s modified at %v", again, info.ModTime())
+	}
+
+	other, err := Image(fileName, Options{MaxEdge: 60, Format: FormatJPEG, Quality: 80})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == name {
+		t.Error("expected different options to be cached under a different name")
+	}
+}
+
+func TestImageCacheNameChangesWithScreenshot(t *testing.T) {
+	fileName := writeScreenshot(t, testPNG(t, 200, 100))
+	opts := Options{MaxEdge: 50, Format: FormatOriginal}
+
+	before, err := Image(fileName, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(scrPath(fileName), testPNG(t, 100, 200), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(scrPath(fileName), later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := Image(fileName, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after == before {
+		t.Fatal("expected a changed screenshot to be cached under a new name")
+	}
+	if cfg, format := decodeFile(t, after); format != "png" || cfg.Width != 25 || cfg.Height != 50 {
+		t.Errorf("expected a 25x50 PNG of the new screenshot, got a %dx%d %s", cfg.Width, cfg.Height, format)
+	}
+
+	RemoveCached(fileName)
+	for _, name := range []string{before, after} {
+		if _, err := os.Stat(scrPath(name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed from the cache", name)
+		}
+	}
+}
+
+func TestImageCropsToDisplayAndSkipsNoops(t *testing.T) {
+	data, err := AddDisplayBounds(testPNG(t, 80, 40), image.Rect(40, 0, 80, 40))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileName := writeScreenshot(t, data)
+
+	name, err := Image(fileName, Options{Crop: true, Format: FormatOriginal})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg, _ := decodeFile(t, name); cfg.Width != 40 || cfg.Height != 40 {
+		t.Errorf("expected the screenshot to be cropped to 40x40, got %dx%d", cfg.Width, cfg.Height)
+	}
+
+	for _, opts := range []Options{{}, {Format: FormatOriginal, MaxEdge: 200}} {
+		name, err := Image(fileName, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != fileName {
+			t.Errorf("%+v: expected the screenshot to be used as it is, got %s", opts, name)
+		}
+	}
+}
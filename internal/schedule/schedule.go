@@ -2,17 +2,20 @@ package schedule
 
 import (
 	"fmt"
-	"log"
 	"recap/internal/app"
 	"recap/internal/config"
 	"recap/internal/db"
 	"recap/internal/llm"
 	"recap/internal/screenshot"
+	"sync"
 	"time"
 )
 
+// Runs a callback at a regular interval. Safe to start, stop and query from several goroutines
 type Timer struct {
+	mu      sync.Mutex
 	ticker  *time.Ticker
+	done    chan struct{}
 	running bool
 }
 
@@ -21,41 +24,84 @@ var (
 	llmTimer        = &Timer{}
 )
 
+// Fires once a day at ReportAutoAt to generate the daily report
+var (
+	autoReportTimer *time.Timer
+	autoReportMu    sync.Mutex
+)
+
+// Database new captures are saved to
+var store db.Store
+
+// Messages sent to the frontend when a timer runs or is switched on or off
+type frontendNotifier interface {
+	SendScreenshotRanMessage(lastId int64)
+	SendLLMRanMessage(lastId int64)
+	SendScreenshotStateMessage(newState bool)
+	SendLLMStateMessage(newState bool)
+}
+
+// Replaced in tests, which can't take real screenshots or reach a frontend
+var (
+	takeScreenshot                       = screenshot.TakeScreenshot
+	generateDailyReport                  = llm.GenerateDailyReport
+	notifier            frontendNotifier = &app.AppInstance
+)
+
 // Initializes and starts the timer with the specified interval and callback function.
 // It stops any existing timer before starting a new one
 func (t *Timer) start(interval time.Duration, callback func()) {
-	t.stop()
-	t.ticker = time.NewTicker(interval)
-	t.running = true
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.stopLocked()
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	t.ticker, t.done, t.running = ticker, done, true
+
+	// The goroutine only uses its own ticker and channel, so restarting the timer doesn't race with it
 	go func() {
 		for {
-			<-t.ticker.C
-			if !t.running {
+			select {
+			case <-done:
 				return
+			case <-ticker.C:
+				callback()
 			}
-			callback()
 		}
 	}()
 }
 
 // Halts the timer if it is currently running and stops the ticker
 func (t *Timer) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopLocked()
+}
+
+// Stops the ticker and its goroutine. The caller must hold t.mu
+func (t *Timer) stopLocked() {
 	if t.ticker != nil {
 		t.ticker.Stop()
-		t.running = false
+		close(t.done)
+		t.ticker, t.done = nil, nil
 	}
+	t.running = false
+}
+
+// Reports whether the timer is running
+func (t *Timer) isRunning() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.running
 }
 
 // Callback function; captures a screenshot, saves it, writes it to the database
 func screenshotCallback() {
 	fmt.Printf("Taking screenshot at %s\n", time.Now())
-	fullFilename, thumbFilename := screenshot.TakeScreenshot()
-	cl, err := db.CreateConnection()
-	if err != nil {
-		log.Fatalf("Could not create database connection! %v\n", err.Error())
-	}
-	lastId := db.InsertCapture(cl, []db.FullThumbScrPair{{Full: fullFilename, Thumb: thumbFilename}})
-	app.AppInstance.SendScreenshotRanMessage(lastId)
+	fullFilename, thumbFilename := takeScreenshot()
+	lastId := store.InsertCapture([]db.FullThumbScrPair{{Full: fullFilename, Thumb: thumbFilename}})
+	notifier.SendScreenshotRanMessage(lastId)
 }
 
 // Sends unprocessed screenshots to the vision model and inserts the descriptions
@@ -65,22 +111,72 @@ func llmCallback() {
 	llm.SendQueue()
 }
 
+// Generates the daily report from today's captures, then schedules the next one
+func autoReportCallback() {
+	fmt.Printf("Generating automatic report at %s\n", time.Now())
+	reportId, err := generateDailyReport()
+	if err != nil {
+		fmt.Printf("Error generating automatic report: %v\n", err)
+	} else if reportId != nil {
+		notifier.SendLLMRanMessage(*reportId)
+	}
+
+	StartAutoReportSchedule()
+}
+
+// Returns the first time after now that the clock reads at, given as HH:MM, in the configured time zone
+func nextAutoReport(now time.Time, at string) (time.Time, error) {
+	hour, minute, err := config.ParseClock(at)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	loc := config.Location()
+	y, m, d := now.In(loc).Date()
+	next := time.Date(y, m, d, hour, minute, 0, 0, loc)
+	if !next.After(now) {
+		next = time.Date(y, m, d+1, hour, minute, 0, 0, loc)
+	}
+	return next, nil
+}
+
+// Schedules the daily report to be generated at ReportAutoAt, replacing the report already scheduled, if any
+func StartAutoReportSchedule() {
+	next, err := nextAutoReport(time.Now(), config.Config.ReportAutoAt)
+	if err != nil {
+		fmt.Printf("Could not schedule automatic report: %v\n", err)
+		return
+	}
+
+	autoReportMu.Lock()
+	defer autoReportMu.Unlock()
+	if autoReportTimer != nil {
+		autoReportTimer.Stop()
+	}
+	fmt.Printf("Next automatic report at %s\n", next)
+	autoReportTimer = time.AfterFunc(time.Until(next), autoReportCallback)
+}
+
+// Cancels the scheduled daily report
+func StopAutoReportSchedule() {
+	autoReportMu.Lock()
+	defer autoReportMu.Unlock()
+	if autoReportTimer != nil {
+		autoReportTimer.Stop()
+		autoReportTimer = nil
+	}
+}
+
 // Initiates the screenshot capturing process at the specified interval.
 // It stops any currently running screenshot timer before starting a new one
 func StartScreenshotSchedule(interval time.Duration) {
 	fmt.Println("Starting screenshot schedule")
-	if screenshotTimer.running {
-		screenshotTimer.ticker.Stop()
-	}
 	screenshotTimer.start(interval, screenshotCallback)
 }
 
 // Initiates the LLM timer process at the specified interval.
 // It stops any currently running LLM timer before starting a new one
 func StartLLMTimer(interval time.Duration) {
-	if llmTimer.running {
-		llmTimer.ticker.Stop()
-	}
 	llmTimer.start(interval, llmCallback)
 }
 
@@ -93,7 +189,7 @@ func SetLLMScheduleState(state bool) {
 		llmTimer.stop()
 	}
 
-	app.AppInstance.SendLLMStateMessage(state)
+	notifier.SendLLMStateMessage(state)
 }
 
 // Enables or disables the screenshot schedule based on the provided state.
@@ -105,17 +201,19 @@ func SetScrScheduleState(state bool) {
 		screenshotTimer.stop()
 	}
 
-	app.AppInstance.SendScreenshotStateMessage(state)
+	notifier.SendScreenshotStateMessage(state)
 }
 
 // Returns the running state of the screenshot and LLM timers. Used by frontend
 func AreTimersRunning() (bool, bool) {
-	return screenshotTimer.running, llmTimer.running
+	return screenshotTimer.isRunning(), llmTimer.isRunning()
 }
 
-// Sets up the timers based on configuration settings for screenshot capturing
-// and LLM generation. It starts the timers if enabled in the config.
-func Initialize() {
+// Sets up the timers based on configuration settings for screenshot capturing,
+// LLM generation and automatic reports. It starts the timers if enabled in the config.
+func Initialize(dbStore db.Store) {
+	store = dbStore
+
 	ssTakeEnabled := config.Config.ScreenshotIntervalEnabled
 	descGenEnabled := config.Config.DescGenIntervalEnabled
 	ssTakeInterval := config.Config.ScreenshotIntervalMins
@@ -128,4 +226,10 @@ func Initialize() {
 	if descGenEnabled == 1 && descGenInterval > 0 {
 		StartLLMTimer(time.Duration(descGenInterval) * time.Minute)
 	}
+
+	if config.Config.ReportAutoEnabled == 1 {
+		StartAutoReportSchedule()
+	} else {
+		StopAutoReportSchedule()
+	}
 }
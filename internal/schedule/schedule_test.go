@@ -0,0 +1,295 @@
+package schedule
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"recap/internal/config"
+	"recap/internal/db"
+	"recap/internal/llm"
+	_ "recap/internal/models/mock"
+	"recap/internal/secrets"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Records the messages that would have been sent to the frontend
+type fakeNotifier struct {
+	mu              sync.Mutex
+	screenshotsRan  []int64
+	reportsRan      []int64
+	screenshotState []bool
+	llmState        []bool
+}
+
+func (n *fakeNotifier) SendScreenshotRanMessage(lastId int64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.screenshotsRan = append(n.screenshotsRan, lastId)
+}
+
+func (n *fakeNotifier) SendLLMRanMessage(lastId int64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.reportsRan = append(n.reportsRan, lastId)
+}
+
+func (n *fakeNotifier) SendScreenshotStateMessage(newState bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.screenshotState = append(n.screenshotState, newState)
+}
+
+func (n *fakeNotifier) SendLLMStateMessage(newState bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.llmState = append(n.llmState, newState)
+}
+
+// Sets the package up with an in-memory store, the Mock API, a fake frontend and a screenshot function that
+// saves a blank image instead of capturing the screen. Timers are stopped when the test ends
+func setupSchedule(t *testing.T) (db.Store, *fakeNotifier) {
+	t.Helper()
+
+	secrets.UseStore(secrets.NewMemoryStore())
+	t.Setenv("RECAP_MOCK_FIXTURES", "")
+
+	testStore, err := db.OpenMemory()
+	if err != nil {
+		t.Fatalf("could not open in-memory store: %v", err)
+	}
+
+	config.Config.ScrPath = t.TempDir()
+	config.Config.DescGenAPI = "Mock"
+	config.Config.DescGenModel = "mock-vision"
+	config.Config.DescGenFallback = ""
+	config.Config.ReportAPI = "Mock"
+	config.Config.ReportModel = "mock-text"
+	config.Config.ReportFallback = ""
+	config.Config.EmbeddingEnabled = 0
+	config.Config.ScreenshotIntervalEnabled = 0
+	config.Config.DescGenIntervalEnabled = 0
+	llm.Initialize(testStore)
+
+	fake := &fakeNotifier{}
+	prevNotifier, prevTakeScreenshot := notifier, takeScreenshot
+	notifier = fake
+
+	count := 0
+	takeScreenshot = func() (string, string) {
+		count++
+		fileName := fmt.Sprintf("capture_%d.png", count)
+		f, err := os.Create(filepath.Join(config.Config.ScrPath, fileName))
+		if err != nil {
+			t.Error(err)
+			return fileName, fileName
+		}
+		defer f.Close()
+		png.Encode(f, image.NewGray(image.Rect(0, 0, 4, 4+count))) // nolint: errcheck
+		return fileName, fileName
+	}
+
+	t.Cleanup(func() {
+		screenshotTimer.stop()
+		llmTimer.stop()
+		StopAutoReportSchedule()
+		notifier, takeScreenshot = prevNotifier, prevTakeScreenshot
+		testStore.Close()
+	})
+
+	store = testStore
+	return testStore, fake
+}
+
+func TestScreenshotCallbackSavesCapture(t *testing.T) {
+	testStore, fake := setupSchedule(t)
+
+	screenshotCallback()
+
+	shots, err := testStore.GetScreenshots(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shots) != 1 {
+		t.Fatalf("expected 1 capture, got %d", len(shots))
+	}
+	if len(fake.screenshotsRan) != 1 || fake.screenshotsRan[0] != int64(shots[0].CaptureID) {
+		t.Errorf("frontend wasn't told about capture %d: %v", shots[0].CaptureID, fake.screenshotsRan)
+	}
+}
+
+func TestLLMCallbackDescribesQueuedCaptures(t *testing.T) {
+	testStore, _ := setupSchedule(t)
+
+	screenshotCallback()
+	screenshotCallback()
+
+	llmCallback()
+
+	queue, err := testStore.GetUnprocessedCaptures()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 0 {
+		t.Errorf("expected every capture to be described, %d are left", len(queue))
+	}
+}
+
+func TestTimerRunsCallbackUntilStopped(t *testing.T) {
+	var timer Timer
+	calls := make(chan struct{}, 10)
+
+	timer.start(5*time.Millisecond, func() { calls <- struct{}{} })
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-calls:
+		case <-time.After(time.Second):
+			t.Fatalf("callback ran %d times, expected at least 3", i)
+		}
+	}
+
+	timer.stop()
+	if timer.isRunning() {
+		t.Error("timer still reports running after being stopped")
+	}
+
+	// A tick may already have been in flight when the timer stopped
+	time.Sleep(20 * time.Millisecond)
+	for len(calls) > 0 {
+		<-calls
+	}
+
+	select {
+	case <-calls:
+		t.Error("callback ran after the timer was stopped")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestTimerCanBeStartedAndStoppedConcurrently(t *testing.T) {
+	var timer Timer
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if (i+j)%2 == 0 {
+					timer.start(time.Millisecond, func() {})
+				} else {
+					timer.stop()
+				}
+				timer.isRunning()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	timer.stop()
+	if timer.isRunning() {
+		t.Error("timer still reports running after being stopped")
+	}
+}
+
+func TestInitializeStartsEnabledTimers(t *testing.T) {
+	testStore, _ := setupSchedule(t)
+
+	config.Config.ScreenshotIntervalEnabled = 1
+	config.Config.ScreenshotIntervalMins = 5
+	config.Config.DescGenIntervalEnabled = 0
+	config.Config.DescGenIntervalMins = 5
+
+	Initialize(testStore)
+
+	scrRunning, llmRunning := AreTimersRunning()
+	if !scrRunning || llmRunning {
+		t.Errorf("expected only the screenshot timer to run, got screenshot %t, LLM %t", scrRunning, llmRunning)
+	}
+}
+
+func TestSetScheduleStateNotifiesFrontend(t *testing.T) {
+	_, fake := setupSchedule(t)
+	config.Config.ScreenshotIntervalMins = 5
+	config.Config.DescGenIntervalMins = 5
+
+	SetScrScheduleState(true)
+	SetLLMScheduleState(true)
+
+	scrRunning, llmRunning := AreTimersRunning()
+	if !scrRunning || !llmRunning {
+		t.Errorf("expected both timers to run, got screenshot %t, LLM %t", scrRunning, llmRunning)
+	}
+
+	SetScrScheduleState(false)
+	SetLLMScheduleState(false)
+
+	scrRunning, llmRunning = AreTimersRunning()
+	if scrRunning || llmRunning {
+		t.Errorf("expected both timers to be stopped, got screenshot %t, LLM %t", scrRunning, llmRunning)
+	}
+
+	if len(fake.screenshotState) != 2 || !fake.screenshotState[0] || fake.screenshotState[1] {
+		t.Errorf("unexpected screenshot state messages: %v", fake.screenshotState)
+	}
+	if len(fake.llmState) != 2 || !fake.llmState[0] || fake.llmState[1] {
+		t.Errorf("unexpected LLM state messages: %v", fake.llmState)
+	}
+}
+
+func TestNextAutoReport(t *testing.T) {
+	setupSchedule(t)
+	config.Config.Timezone = "Asia/Tokyo"
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+
+	// 16:00 in Tokyo
+	now := time.Date(2024, 10, 4, 7, 0, 0, 0, time.UTC)
+
+	next, err := nextAutoReport(now, "17:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 10, 4, 17, 0, 0, 0, tokyo); !next.Equal(want) {
+		t.Errorf("expected the report later today at %s, got %s", want, next)
+	}
+
+	next, err = nextAutoReport(now, "09:30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 10, 5, 9, 30, 0, 0, tokyo); !next.Equal(want) {
+		t.Errorf("expected the report tomorrow at %s, got %s", want, next)
+	}
+
+	if _, err := nextAutoReport(now, "5pm"); err == nil {
+		t.Error("expected an error for a time not in HH:MM format")
+	}
+}
+
+func TestAutoReportCallbackNotifiesFrontend(t *testing.T) {
+	_, fake := setupSchedule(t)
+
+	reportId := int64(7)
+	generateDailyReport = func() (*int64, error) { return &reportId, nil }
+	t.Cleanup(func() { generateDailyReport = llm.GenerateDailyReport })
+
+	autoReportCallback()
+
+	if len(fake.reportsRan) != 1 || fake.reportsRan[0] != reportId {
+		t.Errorf("frontend wasn't told about report %d: %v", reportId, fake.reportsRan)
+	}
+
+	autoReportMu.Lock()
+	scheduled := autoReportTimer != nil
+	autoReportMu.Unlock()
+	if !scheduled {
+		t.Error("the next automatic report wasn't scheduled")
+	}
+}
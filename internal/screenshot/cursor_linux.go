@@ -0,0 +1,27 @@
+//go:build linux
+
+package screenshot
+
+import (
+	"image"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+)
+
+// Returns the position of the mouse pointer on the X server's root window
+func cursorPosition() (image.Point, error) {
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return image.Point{}, err
+	}
+	defer conn.Close()
+
+	root := xproto.Setup(conn).DefaultScreen(conn).Root
+	reply, err := xproto.QueryPointer(conn, root).Reply()
+	if err != nil {
+		return image.Point{}, err
+	}
+
+	return image.Pt(int(reply.RootX), int(reply.RootY)), nil
+}
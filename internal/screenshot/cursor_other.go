@@ -0,0 +1,13 @@
+//go:build !linux && !windows
+
+package screenshot
+
+import (
+	"errors"
+	"image"
+)
+
+// Finding the pointer's position isn't supported on this platform, so screenshots are never cropped
+func cursorPosition() (image.Point, error) {
+	return image.Point{}, errors.New("finding the mouse pointer isn't supported on this platform")
+}
@@ -0,0 +1,23 @@
+//go:build windows
+
+package screenshot
+
+import (
+	"image"
+	"syscall"
+	"unsafe"
+)
+
+var procGetCursorPos = syscall.NewLazyDLL("user32.dll").NewProc("GetCursorPos")
+
+// Returns the position of the mouse pointer on the virtual screen
+func cursorPosition() (image.Point, error) {
+	var pt struct{ X, Y int32 }
+
+	ret, _, err := procGetCursorPos.Call(uintptr(unsafe.Pointer(&pt)))
+	if ret == 0 {
+		return image.Point{}, err
+	}
+
+	return image.Pt(int(pt.X), int(pt.Y)), nil
+}
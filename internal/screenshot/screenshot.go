@@ -1,6 +1,7 @@
 package screenshot
 
 import (
+	"bytes"
 	"fmt"
 	"image"
 	"image/jpeg"
@@ -10,6 +11,7 @@ import (
 	"path"
 
 	"recap/internal/config"
+	"recap/internal/preprocess"
 
 	"github.com/google/uuid"
 	"github.com/kbinani/screenshot"
@@ -33,24 +35,51 @@ func saveScreenshotJPEG(img *image.RGBA, filename string, quality int) error {
 }
 
 // Saves the provided RGBA image as a PNG file in the specified directory. Called by TakeScreenshot.
+// If activeDisplay isn't empty, its bounds are saved in the file so the screenshot can be cropped to it later.
 // Returns error if the operation fails
-func saveScreenshotPNG(img *image.RGBA, filename string) error {
-	file, err := os.Create(path.Join(config.Config.ScrPath, filename))
+func saveScreenshotPNG(img *image.RGBA, filename string, activeDisplay image.Rectangle) error {
+	enc := png.Encoder{
+		CompressionLevel: png.BestCompression,
+	}
+
+	var buf bytes.Buffer
+	err := enc.Encode(&buf, img)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
-	enc := png.Encoder{
-		CompressionLevel: png.BestCompression,
+	data := buf.Bytes()
+	if !activeDisplay.Empty() {
+		data, err = preprocess.AddDisplayBounds(data, activeDisplay)
+		if err != nil {
+			return err
+		}
+	}
+
+	return os.WriteFile(path.Join(config.Config.ScrPath, filename), data, 0644)
+}
+
+// Returns the bounds of the display the mouse pointer is on, relative to the top left corner of screenBounds.
+// Returns an empty rectangle if there's only one display or the pointer's position can't be found
+func activeDisplayBounds(screenBounds image.Rectangle) image.Rectangle {
+	if screenshot.NumActiveDisplays() < 2 {
+		return image.Rectangle{}
 	}
 
-	err = enc.Encode(file, img)
+	pointer, err := cursorPosition()
 	if err != nil {
-		return err
+		log.Printf("Could not find the active display: %v", err)
+		return image.Rectangle{}
 	}
 
-	return nil
+	for idx := range screenshot.NumActiveDisplays() {
+		bounds := screenshot.GetDisplayBounds(idx)
+		if pointer.In(bounds) {
+			return bounds.Intersect(screenBounds).Sub(screenBounds.Min)
+		}
+	}
+
+	return image.Rectangle{}
 }
 
 // Captures the entire screen based on the bounds of active displays. The end result is one image showing all screens.
@@ -78,7 +107,7 @@ func TakeScreenshot() (string, string) {
 	fullFilename := fmt.Sprintf("%s.png", scrUuid)
 	thumbFilename := fmt.Sprintf("%s_thumb.jpg", scrUuid)
 
-	err = saveScreenshotPNG(img, fullFilename)
+	err = saveScreenshotPNG(img, fullFilename, activeDisplayBounds(displayBounds))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,174 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"recap/internal/config"
+	"sync"
+)
+
+const (
+	secretsFileName = "secrets.enc"
+	keyFileName     = "secrets.key"
+)
+
+// Stores secrets in a file next to the database, encrypted with AES-GCM. The key is kept in the user's
+// config directory, so a copy of the app's folder alone doesn't reveal the secrets
+type fileStore struct {
+	path    string
+	keyPath string
+	mu      sync.Mutex
+}
+
+func newFileStore() *fileStore {
+	keyDir := config.GetProjectRoot()
+	if dir, err := os.UserConfigDir(); err == nil {
+		keyDir = filepath.Join(dir, "recap")
+	}
+
+	return &fileStore{
+		path:    filepath.Join(config.GetProjectRoot(), secretsFileName),
+		keyPath: filepath.Join(keyDir, keyFileName),
+	}
+}
+
+func (f *fileStore) Name() string {
+	return "encrypted file " + f.path
+}
+
+// Reads the encryption key, creating one if it doesn't exist yet
+func (f *fileStore) key() ([]byte, error) {
+	key, err := os.ReadFile(f.keyPath)
+	if err == nil {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("secrets key %s is corrupted", f.keyPath)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	// A new key can't decrypt secrets saved with a lost one
+	if _, err := os.Stat(f.path); err == nil {
+		return nil, fmt.Errorf("secrets key %s is missing, so %s can't be decrypted", f.keyPath, f.path)
+	}
+
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.keyPath), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(f.keyPath, key, 0600); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (f *fileStore) cipher() (cipher.AEAD, error) {
+	key, err := f.key()
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Reads and decrypts every secret in the file. Returns an empty map if the file doesn't exist
+func (f *fileStore) load() (map[string]string, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return make(map[string]string), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := f.cipher()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("%s is corrupted", f.path)
+	}
+
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt %s: %w", f.path, err)
+	}
+
+	values := make(map[string]string)
+	if err := json.Unmarshal(plain, &values); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", f.path, err)
+	}
+
+	return values, nil
+}
+
+// Encrypts and writes every secret to the file
+func (f *fileStore) save(values map[string]string) error {
+	plain, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := f.cipher()
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+
+	// Written to a temporary file first, so a failed write doesn't lose the existing secrets
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, gcm.Seal(nonce, nonce, plain, nil), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
+
+func (f *fileStore) Get(key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	values, err := f.load()
+	if err != nil {
+		return "", err
+	}
+	return values[key], nil
+}
+
+func (f *fileStore) Set(key string, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	values, err := f.load()
+	if err != nil {
+		return err
+	}
+
+	if value == "" {
+		delete(values, key)
+	} else {
+		values[key] = value
+	}
+
+	return f.save(values)
+}
@@ -0,0 +1,37 @@
+package secrets
+
+import "sync"
+
+// Keeps secrets in memory only, so they're gone when the application exits
+type memoryStore struct {
+	values map[string]string
+	mu     sync.Mutex
+}
+
+// Returns a store that keeps secrets in memory only. Meant for tests
+func NewMemoryStore() Store {
+	return &memoryStore{values: make(map[string]string)}
+}
+
+func (s *memoryStore) Name() string {
+	return "memory"
+}
+
+func (s *memoryStore) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.values[key], nil
+}
+
+func (s *memoryStore) Set(key string, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if value == "" {
+		delete(s.values, key)
+	} else {
+		s.values[key] = value
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package secrets
+
+import (
+	"log"
+	"slices"
+	"strings"
+	"sync"
+)
+
+/**
+Secrets such as API keys are kept out of the settings table. They're stored in the freedesktop Secret Service
+(GNOME Keyring, KWallet) over D-Bus where it's available, and in an encrypted file otherwise
+*/
+
+// Settings that hold secrets. The custom API's headers are among them, as they often carry credentials
+// such as an Authorization header
+var Keys = []string{"GeminiAPIKey", "OpenAIAPIKey", "OpenRouterAPIKey", "AnthropicAPIKey", "CustomOpenAIAPIKey", "CustomOpenAIHeaders"}
+
+// A place secrets are kept in
+type Store interface {
+	// Name of the store, for logging
+	Name() string
+
+	// Returns the secret saved under key, or an empty string if there's none
+	Get(key string) (string, error)
+
+	// Saves a secret under key, replacing the previous one. An empty value deletes the secret
+	Set(key string, value string) error
+}
+
+var (
+	activeStore Store
+	storeOnce   sync.Once
+)
+
+// Returns the store in use, choosing it on first use: the Secret Service if it can be reached, the encrypted file otherwise
+func store() Store {
+	storeOnce.Do(func() {
+		ss, err := newSecretServiceStore()
+		if err == nil {
+			activeStore = ss
+		} else {
+			log.Printf("Secret Service is not available, storing secrets in an encrypted file: %v", err)
+			activeStore = newFileStore()
+		}
+		log.Printf("Storing secrets in %s", activeStore.Name())
+	})
+
+	return activeStore
+}
+
+// Reports whether a setting holds a secret
+func IsSecret(key string) bool {
+	return slices.Contains(Keys, key)
+}
+
+// Returns the secret saved under key, or an empty string if there's none
+func Get(key string) (string, error) {
+	return store().Get(key)
+}
+
+// Saves a secret under key. An empty value deletes the secret
+func Set(key string, value string) error {
+	return store().Set(key, value)
+}
+
+// Returns a value that shows whether a secret is set without revealing it, keeping its last four characters
+// if it's long enough for them not to give much away. Returns an empty string if the secret is empty
+func Mask(value string) string {
+	switch {
+	case value == "":
+		return ""
+	case len(value) < 12:
+		return strings.Repeat("•", 8)
+	default:
+		return strings.Repeat("•", 8) + value[len(value)-4:]
+	}
+}
+
+// Replaces the store secrets are kept in, instead of choosing one on first use. Tests use it with
+// NewMemoryStore, so they don't read or write the user's keyring
+func UseStore(s Store) {
+	storeOnce.Do(func() {})
+	activeStore = s
+}
@@ -0,0 +1,194 @@
+package secrets
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	ssService           = "org.freedesktop.secrets"
+	ssPath              = dbus.ObjectPath("/org/freedesktop/secrets")
+	ssDefaultCollection = dbus.ObjectPath("/org/freedesktop/secrets/aliases/default")
+	ssServiceIface      = "org.freedesktop.Secret.Service"
+	ssCollectionIface   = "org.freedesktop.Secret.Collection"
+	ssItemIface         = "org.freedesktop.Secret.Item"
+	ssPromptIface       = "org.freedesktop.Secret.Prompt"
+
+	// Attribute secrets are looked up by, along with the setting key
+	ssApplication = "recap"
+
+	// How long to wait for the user to answer an unlock prompt
+	ssPromptTimeout = 2 * time.Minute
+)
+
+// A secret as sent over D-Bus. With a plain session, Value holds the secret itself
+type ssSecret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// Stores secrets in the default collection of the freedesktop Secret Service
+type secretServiceStore struct {
+	conn    *dbus.Conn
+	session dbus.ObjectPath
+	mu      sync.Mutex
+}
+
+// Connects to the Secret Service on the session bus. Returns an error if it's not running or has no default collection
+func newSecretServiceStore() (*secretServiceStore, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, err
+	}
+
+	svc := conn.Object(ssService, ssPath)
+
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	if err := svc.Call(ssServiceIface+".OpenSession", 0, "plain", dbus.MakeVariant("")).Store(&output, &session); err != nil {
+		return nil, fmt.Errorf("could not open session: %w", err)
+	}
+
+	var collection dbus.ObjectPath
+	if err := svc.Call(ssServiceIface+".ReadAlias", 0, "default").Store(&collection); err != nil || collection == "/" {
+		return nil, fmt.Errorf("no default collection")
+	}
+
+	return &secretServiceStore{conn: conn, session: session}, nil
+}
+
+func (s *secretServiceStore) Name() string {
+	return "Secret Service"
+}
+
+func attributes(key string) map[string]string {
+	return map[string]string{"application": ssApplication, "key": key}
+}
+
+// Shows a prompt, such as one asking the user to unlock their keyring, and waits for it to complete
+func (s *secretServiceStore) prompt(path dbus.ObjectPath) error {
+	if path == "/" {
+		return nil
+	}
+
+	signals := make(chan *dbus.Signal, 1)
+	s.conn.Signal(signals)
+	defer s.conn.RemoveSignal(signals)
+
+	match := []dbus.MatchOption{dbus.WithMatchObjectPath(path), dbus.WithMatchInterface(ssPromptIface), dbus.WithMatchMember("Completed")}
+	if err := s.conn.AddMatchSignal(match...); err != nil {
+		return err
+	}
+	defer s.conn.RemoveMatchSignal(match...) // nolint: errcheck
+
+	if err := s.conn.Object(ssService, path).Call(ssPromptIface+".Prompt", 0, "").Err; err != nil {
+		return err
+	}
+
+	timeout := time.After(ssPromptTimeout)
+	for {
+		select {
+		case sig := <-signals:
+			if sig.Path != path || sig.Name != ssPromptIface+".Completed" {
+				continue
+			}
+			if dismissed, _ := sig.Body[0].(bool); dismissed {
+				return fmt.Errorf("prompt was dismissed")
+			}
+			return nil
+		case <-timeout:
+			return fmt.Errorf("timed out waiting for prompt")
+		}
+	}
+}
+
+// Unlocks objects, prompting the user if needed
+func (s *secretServiceStore) unlock(objects []dbus.ObjectPath) error {
+	if len(objects) == 0 {
+		return nil
+	}
+
+	var unlocked []dbus.ObjectPath
+	var promptPath dbus.ObjectPath
+	if err := s.conn.Object(ssService, ssPath).Call(ssServiceIface+".Unlock", 0, objects).Store(&unlocked, &promptPath); err != nil {
+		return fmt.Errorf("could not unlock keyring: %w", err)
+	}
+
+	return s.prompt(promptPath)
+}
+
+// Returns the items holding the secret saved under key, unlocking them if needed
+func (s *secretServiceStore) search(key string) ([]dbus.ObjectPath, error) {
+	var unlocked, locked []dbus.ObjectPath
+	if err := s.conn.Object(ssService, ssPath).Call(ssServiceIface+".SearchItems", 0, attributes(key)).Store(&unlocked, &locked); err != nil {
+		return nil, err
+	}
+
+	if err := s.unlock(locked); err != nil {
+		return nil, err
+	}
+
+	return append(unlocked, locked...), nil
+}
+
+func (s *secretServiceStore) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items, err := s.search(key)
+	if err != nil || len(items) == 0 {
+		return "", err
+	}
+
+	var secret ssSecret
+	if err := s.conn.Object(ssService, items[0]).Call(ssItemIface+".GetSecret", 0, s.session).Store(&secret); err != nil {
+		return "", fmt.Errorf("could not read secret %s: %w", key, err)
+	}
+
+	return string(secret.Value), nil
+}
+
+func (s *secretServiceStore) Set(key string, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if value == "" {
+		items, err := s.search(key)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			var promptPath dbus.ObjectPath
+			if err := s.conn.Object(ssService, item).Call(ssItemIface+".Delete", 0).Store(&promptPath); err != nil {
+				return fmt.Errorf("could not delete secret %s: %w", key, err)
+			}
+			if err := s.prompt(promptPath); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := s.unlock([]dbus.ObjectPath{ssDefaultCollection}); err != nil {
+		return err
+	}
+
+	properties := map[string]dbus.Variant{
+		ssItemIface + ".Label":      dbus.MakeVariant("Recap " + key),
+		ssItemIface + ".Attributes": dbus.MakeVariant(attributes(key)),
+	}
+	secret := ssSecret{Session: s.session, Value: []byte(value), ContentType: "text/plain"}
+
+	var item, promptPath dbus.ObjectPath
+	if err := s.conn.Object(ssService, ssDefaultCollection).Call(ssCollectionIface+".CreateItem", 0, properties, secret, true).Store(&item, &promptPath); err != nil {
+		return fmt.Errorf("could not save secret %s: %w", key, err)
+	}
+
+	return s.prompt(promptPath)
+}
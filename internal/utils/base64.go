@@ -25,6 +25,8 @@ func readImageBytes(filepath string) (*[]byte, error) {
 func formatResponse(bytes *[]byte, filename string) string {
 	if strings.HasSuffix(filename, ".png") {
 		return "data:image/png;base64," + base64.StdEncoding.EncodeToString(*bytes)
+	} else if strings.HasSuffix(filename, ".webp") {
+		return "data:image/webp;base64," + base64.StdEncoding.EncodeToString(*bytes)
 	} else {
 		return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(*bytes)
 	}
@@ -72,7 +74,7 @@ func ReadImageToBase64PreferFull(fullName string, thumbName *string) string {
 	return ""
 }
 
-// Reads a given image filename to Base64. Can be used with PNG and JPEG files
+// Reads a given image filename to Base64. Can be used with PNG, JPEG and WebP files
 func ReadImageToBase64(fileName string) string {
 	fullPath := path.Join(config.Config.ScrPath, fileName)
 
@@ -13,22 +13,24 @@ import (
 //go:embed assets/icon.ico
 var iconBytes []byte
 
-func addDbInitializers() *db.InitializerCallbacks {
+func addDbInitializers(store db.Store) *db.InitializerCallbacks {
 	initializers := db.NewInitializers()
-	initializers.InitSchedule = schedule.Initialize
-	initializers.InitLLM = llm.Initialize
+	initializers.InitSchedule = func() { schedule.Initialize(store) }
+	initializers.InitLLM = func() { llm.Initialize(store) }
 	initializers.FunctionsGiven = true
 	return initializers
 }
 
 func main() {
-	db.Initializers = *addDbInitializers()
-	_, err := db.Initialize(false) // Initialize database, throw away the client afterwards
+	store, err := db.Initialize() // Initialize database, the store is shared by the whole app until it exits
 	if err != nil {
 		log.Fatalf("Could not initialize database: %v\n", err.Error())
 	}
-	llm.Initialize()      // Setup LLM (text, vision) connectors from config
-	schedule.Initialize() // Start the schedule in which timers are configured for automatic screenshots and vision processing
+	defer store.Close()
+
+	db.Initializers = *addDbInitializers(store)
+	llm.Initialize(store)      // Setup LLM (text, vision) connectors from config
+	schedule.Initialize(store) // Start the schedule in which timers are configured for automatic screenshots and vision processing
 	tray.Initialize(&iconBytes)
-	createApp()
+	createApp(store)
 }